@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gocql/gocql"
+)
+
+// newCluster creates the cluster configuration shared by all commands from the
+// global flags. An empty keyspace connects without selecting a keyspace.
+func newCluster(keyspace string) (*gocql.ClusterConfig, error) {
+	tlsConfig, err := newTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cluster := gocql.NewCluster(parseHosts(cfg.hosts)...)
+	cluster.Keyspace = keyspace
+	cluster.Consistency = parseConsistency(cfg.consistency)
+	cluster.Timeout = cfg.timeout
+
+	// The driver timeout must not cut statements short of the statement timeout.
+	if cfg.statementTimeout > cluster.Timeout {
+		cluster.Timeout = cfg.statementTimeout
+	}
+
+	// Configure datacenter-aware routing if datacenter is specified.
+	if cfg.datacenter != "" {
+		cluster.PoolConfig.HostSelectionPolicy = gocql.TokenAwareHostPolicy(
+			gocql.DCAwareRoundRobinPolicy(cfg.datacenter),
+		)
+	}
+
+	// Configure authentication if username and password are provided.
+	if cfg.username != "" && cfg.password != "" {
+		cluster.Authenticator = gocql.PasswordAuthenticator{
+			Username: cfg.username,
+			Password: cfg.password,
+		}
+	}
+
+	// Configure client-to-node encryption if any TLS flag is set.
+	if tlsConfig != nil {
+		cluster.SslOpts = &gocql.SslOptions{
+			Config:                 tlsConfig,
+			EnableHostVerification: !tlsConfig.InsecureSkipVerify,
+		}
+	}
+
+	return cluster, nil
+}
+
+// createSession connects to the cluster configured by the global flags.
+func createSession(keyspace string) (*gocql.Session, error) {
+	cluster, err := newCluster(keyspace)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := cluster.CreateSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ScyllaDB: %w", err)
+	}
+
+	return session, nil
+}
+
+// parseHosts splits a comma-separated list of hosts.
+func parseHosts(s string) []string {
+	hosts := strings.Split(s, ",")
+	for i := range hosts {
+		hosts[i] = strings.TrimSpace(hosts[i])
+	}
+
+	return hosts
+}
+
+// newTLSConfig builds the TLS configuration from the TLS flags.
+// Returns nil if no TLS flag is set. Without a CA file the system roots are used.
+func newTLSConfig() (*tls.Config, error) {
+	if cfg.tlsCA == "" && cfg.tlsCert == "" && cfg.tlsKey == "" &&
+		cfg.tlsServerName == "" && !cfg.tlsInsecureSkipVerify {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         cfg.tlsServerName,
+		InsecureSkipVerify: cfg.tlsInsecureSkipVerify,
+	}
+
+	if cfg.tlsCA != "" {
+		pem, err := os.ReadFile(cfg.tlsCA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read TLS CA file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("failed to parse TLS CA file %s: no PEM certificates found", cfg.tlsCA)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	switch {
+	case cfg.tlsCert != "" && cfg.tlsKey != "":
+		cert, err := tls.LoadX509KeyPair(cfg.tlsCert, cfg.tlsKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS client certificate: %w", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+
+	case cfg.tlsCert != "" || cfg.tlsKey != "":
+		return nil, errors.New("TLS client certificate and key must be set together (use -tls-cert and -tls-key)")
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	td "github.com/maxatome/go-testdeep/td"
+)
+
+// writeTestCertificate writes a self-signed certificate and its key as PEM
+// files into dir and returns their paths.
+func writeTestCertificate(t *testing.T, dir string) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	td.Require(t).CmpNoError(err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "scylla.test"},
+		DNSNames:              []string{"scylla.test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	td.Require(t).CmpNoError(err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	td.Require(t).CmpNoError(err)
+
+	certPath := filepath.Join(dir, "client.crt")
+	keyPath := filepath.Join(dir, "client.key")
+
+	td.Require(t).CmpNoError(os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	td.Require(t).CmpNoError(os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	return certPath, keyPath
+}
+
+// setConfig replaces the global configuration for the duration of the test.
+func setConfig(t *testing.T, c config) {
+	t.Helper()
+
+	saved, savedLoaded := cfg, loadedConfig
+	cfg = c
+
+	t.Cleanup(func() { cfg, loadedConfig = saved, savedLoaded })
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := writeTestCertificate(t, dir)
+
+	garbage := filepath.Join(dir, "garbage.pem")
+	td.Require(t).CmpNoError(os.WriteFile(garbage, []byte("not a certificate"), 0o600))
+
+	type tcase struct {
+		cfg         config
+		expectNil   bool
+		expectError string
+		check       func(t *testing.T, c *tls.Config)
+	}
+
+	tests := map[string]tcase{
+		"disabled": {
+			expectNil: true,
+		},
+		"server name only uses system roots": {
+			cfg: config{tlsServerName: "scylla.test"},
+			check: func(t *testing.T, c *tls.Config) {
+				td.Cmp(t, c.ServerName, "scylla.test")
+				td.CmpNil(t, c.RootCAs)
+				td.CmpFalse(t, c.InsecureSkipVerify)
+			},
+		},
+		"CA file": {
+			cfg: config{tlsCA: certPath},
+			check: func(t *testing.T, c *tls.Config) {
+				td.CmpNotNil(t, c.RootCAs)
+				td.CmpEmpty(t, c.Certificates)
+			},
+		},
+		"client certificate": {
+			cfg: config{tlsCA: certPath, tlsCert: certPath, tlsKey: keyPath},
+			check: func(t *testing.T, c *tls.Config) {
+				td.Cmp(t, c.Certificates, td.Len(1))
+			},
+		},
+		"insecure skip verify": {
+			cfg: config{tlsInsecureSkipVerify: true},
+			check: func(t *testing.T, c *tls.Config) {
+				td.CmpTrue(t, c.InsecureSkipVerify)
+			},
+		},
+		"missing CA file": {
+			cfg:         config{tlsCA: filepath.Join(dir, "missing.pem")},
+			expectError: "failed to read TLS CA file",
+		},
+		"invalid CA file": {
+			cfg:         config{tlsCA: garbage},
+			expectError: "no PEM certificates found",
+		},
+		"certificate without key": {
+			cfg:         config{tlsCert: certPath},
+			expectError: "must be set together",
+		},
+		"key without certificate": {
+			cfg:         config{tlsKey: keyPath},
+			expectError: "must be set together",
+		},
+		"invalid key pair": {
+			cfg:         config{tlsCert: certPath, tlsKey: garbage},
+			expectError: "failed to load TLS client certificate",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			setConfig(t, tc.cfg)
+
+			c, err := newTLSConfig()
+			if tc.expectError != "" {
+				td.Cmp(t, err, td.ErrorIs(td.Contains(tc.expectError)))
+				return
+			}
+
+			td.CmpNoError(t, err)
+
+			if tc.expectNil {
+				td.CmpNil(t, c)
+				return
+			}
+
+			td.Cmp(t, c.MinVersion, uint16(tls.VersionTLS12))
+			tc.check(t, c)
+		})
+	}
+}
+
+func TestNewCluster(t *testing.T) {
+	setConfig(t, config{
+		hosts:            "node1:9042, node2:9042",
+		consistency:      "local_quorum",
+		timeout:          10 * time.Second,
+		statementTimeout: time.Minute,
+		username:         "cassandra",
+		password:         "secret",
+		tlsServerName:    "scylla.test",
+	})
+
+	cluster, err := newCluster("myapp")
+	td.Require(t).CmpNoError(err)
+
+	td.Cmp(t, cluster.Hosts, []string{"node1:9042", "node2:9042"})
+	td.Cmp(t, cluster.Keyspace, "myapp")
+	td.Cmp(t, cluster.Consistency, gocql.LocalQuorum)
+	td.Cmp(t, cluster.Timeout, time.Minute)
+	td.Cmp(t, cluster.Authenticator, gocql.PasswordAuthenticator{Username: "cassandra", Password: "secret"})
+	td.Require(t).NotNil(cluster.SslOpts)
+	td.Cmp(t, cluster.SslOpts.EnableHostVerification, true)
+	td.Cmp(t, cluster.SslOpts.ServerName, "scylla.test")
+
+	t.Run("without TLS", func(t *testing.T) {
+		setConfig(t, config{hosts: "localhost:9042"})
+
+		cluster, err := newCluster("")
+		td.Require(t).CmpNoError(err)
+		td.CmpNil(t, cluster.SslOpts)
+		td.Cmp(t, cluster.Keyspace, "")
+	})
+
+	t.Run("insecure", func(t *testing.T) {
+		setConfig(t, config{hosts: "localhost:9042", tlsInsecureSkipVerify: true})
+
+		cluster, err := newCluster("")
+		td.Require(t).CmpNoError(err)
+		td.Cmp(t, cluster.SslOpts.EnableHostVerification, false)
+	})
+
+	t.Run("invalid TLS flags", func(t *testing.T) {
+		setConfig(t, config{hosts: "localhost:9042", tlsKey: "client.key"})
+
+		_, err := newCluster("")
+		td.CmpError(t, err)
+	})
+}
@@ -0,0 +1,524 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/heartwilltell/scotty"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFile is the config file read from the working directory
+// if -config is not set. It is optional.
+const defaultConfigFile = "scyllamigrate.yaml"
+
+// Keys of the config file with a special meaning.
+const (
+	configKeyEnvironments = "environments"
+	configKeyDefaultEnv   = "default_env"
+)
+
+// Sources of a resolved setting, from the highest to the lowest priority.
+const (
+	sourceFlag    = "flag"
+	sourceEnv     = "env"
+	sourceFile    = "file"
+	sourceDefault = "default"
+)
+
+// maskedSecret replaces the value of secret settings in the output of config show.
+const maskedSecret = "********"
+
+// configSetting maps a key of the config file to a global flag.
+type configSetting struct {
+	key    string
+	flag   string
+	env    string
+	secret bool
+}
+
+// configSettings are the settings of the config file, in the order config show prints them.
+var configSettings = []configSetting{
+	{key: "hosts", flag: "hosts", env: "SCYLLA_HOSTS"},
+	{key: "keyspace", flag: "keyspace", env: "SCYLLA_KEYSPACE"},
+	{key: "datacenter", flag: "datacenter", env: "SCYLLA_DATACENTER"},
+	{key: "consistency", flag: "consistency", env: "SCYLLA_CONSISTENCY"},
+	{key: "username", flag: "username", env: "SCYLLA_USERNAME"},
+	{key: "password", flag: "password", env: "SCYLLA_PASSWORD", secret: true},
+	{key: "tls.ca", flag: "tls-ca", env: "SCYLLA_TLS_CA"},
+	{key: "tls.cert", flag: "tls-cert", env: "SCYLLA_TLS_CERT"},
+	{key: "tls.key", flag: "tls-key", env: "SCYLLA_TLS_KEY"},
+	{key: "tls.server_name", flag: "tls-server-name", env: "SCYLLA_TLS_SERVER_NAME"},
+	{key: "tls.insecure_skip_verify", flag: "tls-insecure-skip-verify", env: "SCYLLA_TLS_INSECURE_SKIP_VERIFY"},
+	{key: "timeout", flag: "timeout", env: "SCYLLA_TIMEOUT"},
+	{key: "run_timeout", flag: "run-timeout", env: "SCYLLA_RUN_TIMEOUT"},
+	{key: "migration_timeout", flag: "migration-timeout", env: "SCYLLA_MIGRATION_TIMEOUT"},
+	{key: "statement_timeout", flag: "statement-timeout", env: "SCYLLA_STATEMENT_TIMEOUT"},
+	{key: "schema_agreement_timeout", flag: "schema-agreement-timeout", env: "SCYLLA_SCHEMA_AGREEMENT_TIMEOUT"},
+	{key: "table", flag: "table", env: "SCYLLA_MIGRATIONS_TABLE"},
+	{key: "dir", flag: "dir", env: "MIGRATIONS_DIR"},
+	{key: "lock_ttl", flag: "lock-ttl", env: "SCYLLA_LOCK_TTL"},
+	{key: "lock_timeout", flag: "lock-timeout", env: "SCYLLA_LOCK_TIMEOUT"},
+	{key: "dirty", flag: "dirty", env: "SCYLLA_DIRTY_POLICY"},
+	{key: "out_of_order", flag: "out-of-order", env: "SCYLLA_OUT_OF_ORDER"},
+	{key: "allow_destructive", flag: "yes", env: "SCYLLA_YES"},
+}
+
+// configFile is a parsed config file.
+type configFile struct {
+	// path is the path the file was read from.
+	path string
+
+	// base holds the settings outside of environments, which apply to every environment.
+	base map[string]string
+
+	// environments holds the settings of every named environment.
+	environments map[string]map[string]string
+
+	// defaultEnv is the environment used if -env is not set.
+	defaultEnv string
+}
+
+// loadedConfig describes the config file applied to the global flags.
+var loadedConfig struct {
+	path string
+	env  string
+
+	// applied holds the flags whose value was taken from the file.
+	applied map[string]bool
+}
+
+// configured makes the commands apply the config file before they run.
+// Their flag errors are returned instead of exiting the process with code 2,
+// which is reserved for -detailed-exitcode.
+func configured(commands ...*scotty.Command) []*scotty.Command {
+	for _, c := range commands {
+		c.Flags().Init(c.Name, flag.ContinueOnError)
+
+		run := c.Run
+		if run == nil {
+			continue
+		}
+
+		c.Run = func(cmd *scotty.Command, args []string) error {
+			if err := applyConfigFile(cmd.TraverseToRoot().Flags().FlagSet); err != nil {
+				return err
+			}
+
+			return run(cmd, args)
+		}
+	}
+
+	return commands
+}
+
+// applyConfigFile reads the config file selected by -config and applies the
+// environment selected by -env to the global flags. A missing default config
+// file is not an error.
+func applyConfigFile(flags *flag.FlagSet) error {
+	explicit := isSet(flags, "config", "SCYLLA_CONFIG")
+
+	path := cfg.configFile
+	if path == "" {
+		path = defaultConfigFile
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !explicit {
+			if cfg.env != "" {
+				return fmt.Errorf("environment %q is selected but there is no config file (use -config or SCYLLA_CONFIG)", cfg.env)
+			}
+
+			return nil
+		}
+
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	file, err := parseConfigFile(path, data)
+	if err != nil {
+		return err
+	}
+
+	env := cfg.env
+	if env == "" {
+		env = file.defaultEnv
+	}
+
+	values, err := file.resolve(env)
+	if err != nil {
+		return err
+	}
+
+	applied, err := applyConfig(flags, values)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	loadedConfig.path = path
+	loadedConfig.env = env
+	loadedConfig.applied = applied
+
+	return nil
+}
+
+// applyConfig sets the flags of the given settings unless they were set on the
+// command line or through their environment variable.
+// Returns the flags that were set.
+func applyConfig(flags *flag.FlagSet, values map[string]string) (map[string]bool, error) {
+	applied := make(map[string]bool, len(values))
+
+	for _, setting := range configSettings {
+		value, ok := values[setting.key]
+		if !ok || isSet(flags, setting.flag, setting.env) {
+			continue
+		}
+
+		if err := flags.Set(setting.flag, value); err != nil {
+			return nil, fmt.Errorf("invalid value %q for %s: %w", value, setting.key, err)
+		}
+
+		applied[setting.flag] = true
+	}
+
+	return applied, nil
+}
+
+// isSet reports whether a flag was set on the command line or through its environment variable.
+func isSet(flags *flag.FlagSet, name, env string) bool {
+	set := os.Getenv(env) != ""
+
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+
+	return set
+}
+
+// parseConfigFile parses the content of a config file read from path.
+func parseConfigFile(path string, data []byte) (*configFile, error) {
+	doc, err := parseYAML(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	file := &configFile{
+		path:         path,
+		base:         make(map[string]string),
+		environments: make(map[string]map[string]string),
+	}
+
+	for key, value := range doc {
+		switch key {
+		case configKeyDefaultEnv:
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: %s must be a string", path, key)
+			}
+
+			file.defaultEnv = s
+
+		case configKeyEnvironments:
+			envs, ok := value.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: %s must be a mapping of environment names to settings", path, key)
+			}
+
+			for name, settings := range envs {
+				m, ok := settings.(map[string]any)
+				if !ok {
+					return nil, fmt.Errorf("%s: environment %q must be a mapping of settings", path, name)
+				}
+
+				values := make(map[string]string)
+				if err := flattenConfig(values, "", m); err != nil {
+					return nil, fmt.Errorf("%s: environment %q: %w", path, name, err)
+				}
+
+				file.environments[name] = values
+			}
+
+		default:
+			if err := flattenConfig(file.base, "", map[string]any{key: value}); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+	}
+
+	if file.defaultEnv != "" {
+		if _, ok := file.environments[file.defaultEnv]; !ok {
+			return nil, fmt.Errorf("%s: %s %q is not defined in %s", path, configKeyDefaultEnv, file.defaultEnv, configKeyEnvironments)
+		}
+	}
+
+	return file, nil
+}
+
+// flattenConfig adds the settings of m to values with dotted keys, e.g. "tls.ca".
+// Lists are joined with commas. Unknown keys are rejected.
+func flattenConfig(values map[string]string, prefix string, m map[string]any) error {
+	for key, value := range m {
+		key = prefix + key
+
+		switch v := value.(type) {
+		case map[string]any:
+			if err := flattenConfig(values, key+".", v); err != nil {
+				return err
+			}
+
+			continue
+		case []string:
+			values[key] = strings.Join(v, ",")
+		case string:
+			values[key] = v
+		}
+
+		if !slices.ContainsFunc(configSettings, func(s configSetting) bool { return s.key == key }) {
+			return fmt.Errorf("unknown setting %q", key)
+		}
+	}
+
+	return nil
+}
+
+// resolve returns the settings of the environment merged over the base settings.
+// An empty env returns the base settings.
+func (f *configFile) resolve(env string) (map[string]string, error) {
+	values := make(map[string]string, len(f.base))
+	for k, v := range f.base {
+		values[k] = v
+	}
+
+	if env == "" {
+		return values, nil
+	}
+
+	settings, ok := f.environments[env]
+	if !ok {
+		names := make([]string, 0, len(f.environments))
+		for name := range f.environments {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		return nil, fmt.Errorf("%s: unknown environment %q (defined: %s)", f.path, env, strings.Join(names, ", "))
+	}
+
+	for k, v := range settings {
+		values[k] = v
+	}
+
+	return values, nil
+}
+
+func configCmd() *scotty.Command {
+	cmd := &scotty.Command{
+		Name:  "config",
+		Short: "Inspect the configuration",
+		Long: `Inspect the configuration resolved from flags, environment variables and the config file.
+
+Examples:
+  # Show the settings of the prod environment
+  scyllamigrate -env prod config show`,
+	}
+
+	cmd.AddSubcommands(configured(configShowCmd())...)
+
+	return cmd
+}
+
+func configShowCmd() *scotty.Command {
+	return &scotty.Command{
+		Name:  "show",
+		Short: "Show the resolved configuration",
+		Long:  "Display every setting with its value and where it comes from. Secrets are masked.",
+		Run: func(cmd *scotty.Command, _ []string) error {
+			format, err := parseOutputFormat(cfg.output)
+			if err != nil {
+				return err
+			}
+
+			out := newConfigOutput(cmd.TraverseToRoot().Flags().FlagSet)
+
+			return writeOutput(os.Stdout, format, out, func(w io.Writer) { printConfig(w, out) })
+		},
+	}
+}
+
+// configOutput is the output of config show.
+type configOutput struct {
+	File     string          `json:"file"`
+	Env      string          `json:"env"`
+	Settings []settingOutput `json:"settings"`
+}
+
+// settingOutput is a resolved setting in the output of config show.
+type settingOutput struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// newConfigOutput returns the resolved settings with secrets masked.
+func newConfigOutput(flags *flag.FlagSet) *configOutput {
+	out := &configOutput{
+		File:     loadedConfig.path,
+		Env:      loadedConfig.env,
+		Settings: make([]settingOutput, 0, len(configSettings)),
+	}
+
+	for _, setting := range configSettings {
+		f := flags.Lookup(setting.flag)
+		if f == nil {
+			continue
+		}
+
+		value := f.Value.String()
+		if setting.secret && value != "" {
+			value = maskedSecret
+		}
+
+		source := sourceDefault
+
+		switch {
+		case loadedConfig.applied[setting.flag]:
+			source = sourceFile
+		case isSet(flags, setting.flag, ""):
+			source = sourceFlag
+		case os.Getenv(setting.env) != "":
+			source = sourceEnv
+		}
+
+		out.Settings = append(out.Settings, settingOutput{Key: setting.key, Value: value, Source: source})
+	}
+
+	return out
+}
+
+// printConfig writes the resolved settings for humans.
+func printConfig(w io.Writer, out *configOutput) {
+	file := out.File
+	if file == "" {
+		file = "(none)"
+	}
+
+	env := out.Env
+	if env == "" {
+		env = "(none)"
+	}
+
+	fmt.Fprintf(w, "Config file: %s\n", file)
+	fmt.Fprintf(w, "Environment: %s\n\n", env)
+
+	width := 0
+	for _, s := range out.Settings {
+		width = max(width, len(s.Key))
+	}
+
+	for _, s := range out.Settings {
+		value := s.Value
+		if value == "" {
+			value = `""`
+		}
+
+		fmt.Fprintf(w, "  %-*s = %s (%s)\n", width, s.Key, value, s.Source)
+	}
+}
+
+// parseYAML parses a config file. Scalars are returned as strings, sequences
+// of scalars as []string and mappings as map[string]any.
+func parseYAML(data []byte) (map[string]any, error) {
+	var doc yaml.Node
+
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	// An empty document has no content.
+	if len(doc.Content) == 0 {
+		return map[string]any{}, nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: expected a mapping", root.Line)
+	}
+
+	value, err := yamlValue(root)
+	if err != nil {
+		return nil, err
+	}
+
+	return value.(map[string]any), nil
+}
+
+// yamlValue converts a node of a config file to a string, []string or map[string]any.
+// Scalars keep their text, so that e.g. "30s" and "true" are parsed like flag values.
+func yamlValue(node *yaml.Node) (any, error) {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return yamlValue(node.Alias)
+
+	case yaml.ScalarNode:
+		if node.Tag == "!!null" {
+			return "", nil
+		}
+
+		return node.Value, nil
+
+	case yaml.SequenceNode:
+		items := make([]string, 0, len(node.Content))
+
+		for _, item := range node.Content {
+			value, err := yamlValue(item)
+			if err != nil {
+				return nil, err
+			}
+
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("line %d: only lists of scalars are supported", item.Line)
+			}
+
+			items = append(items, s)
+		}
+
+		return items, nil
+
+	case yaml.MappingNode:
+		m := make(map[string]any, len(node.Content)/2)
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: keys must be scalars", key.Line)
+			}
+
+			if _, ok := m[key.Value]; ok {
+				return nil, fmt.Errorf("line %d: duplicate key %q", key.Line, key.Value)
+			}
+
+			v, err := yamlValue(value)
+			if err != nil {
+				return nil, err
+			}
+
+			m[key.Value] = v
+		}
+
+		return m, nil
+
+	default:
+		return nil, fmt.Errorf("line %d: unsupported YAML node", node.Line)
+	}
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/heartwilltell/scotty"
+	td "github.com/maxatome/go-testdeep/td"
+)
+
+func TestParseYAML(t *testing.T) {
+	type tcase struct {
+		input       string
+		expected    map[string]any
+		expectError string
+	}
+
+	tests := map[string]tcase{
+		"empty": {
+			input:    "# nothing here\n\n",
+			expected: map[string]any{},
+		},
+		"scalars": {
+			input: `---
+hosts: localhost:9042
+keyspace: "my app" # comment
+password: 'it''s #secret'
+empty:
+nothing: ~
+`,
+			expected: map[string]any{
+				"hosts":    "localhost:9042",
+				"keyspace": "my app",
+				"password": "it's #secret",
+				"empty":    "",
+				"nothing":  "",
+			},
+		},
+		"nested mappings": {
+			input: `environments:
+  dev:
+    keyspace: dev
+    tls:
+      ca: ca.pem
+  prod:
+    keyspace: prod
+dir: ./migrations
+`,
+			expected: map[string]any{
+				"environments": map[string]any{
+					"dev":  map[string]any{"keyspace": "dev", "tls": map[string]any{"ca": "ca.pem"}},
+					"prod": map[string]any{"keyspace": "prod"},
+				},
+				"dir": "./migrations",
+			},
+		},
+		"sequences": {
+			input: `hosts:
+  - node1:9042
+  - "node2:9042"
+same_indent:
+- a
+flow: [a, 'b', "c"]
+empty_flow: []
+`,
+			expected: map[string]any{
+				"hosts":       []string{"node1:9042", "node2:9042"},
+				"same_indent": []string{"a"},
+				"flow":        []string{"a", "b", "c"},
+				"empty_flow":  []string{},
+			},
+		},
+		"quoted strings": {
+			input: `hosts: "node1:9042"
+password: 'a: b # not a comment'
+keyspace: "it's #1" # comment
+`,
+			expected: map[string]any{
+				"hosts":    "node1:9042",
+				"password": "a: b # not a comment",
+				"keyspace": "it's #1",
+			},
+		},
+		"flow lists with commas": {
+			input: `hosts: ["node1:9042, node2:9042", 'node3:9042', node4:9042]
+`,
+			expected: map[string]any{
+				"hosts": []string{"node1:9042, node2:9042", "node3:9042", "node4:9042"},
+			},
+		},
+		"multi-line values": {
+			input: `password: |
+  first line
+  second line
+keyspace: >-
+  folded
+  value
+dir: plain
+  continued
+`,
+			expected: map[string]any{
+				"password": "first line\nsecond line\n",
+				"keyspace": "folded value",
+				"dir":      "plain continued",
+			},
+		},
+		"other scalars": {
+			input: `yes: true
+timeout: 30s
+lock-ttl: 60
+tls: {ca: ca.pem}
+`,
+			expected: map[string]any{
+				"yes":      "true",
+				"timeout":  "30s",
+				"lock-ttl": "60",
+				"tls":      map[string]any{"ca": "ca.pem"},
+			},
+		},
+		"anchors": {
+			input: `dir: &dir ./migrations
+environments:
+  dev:
+    dir: *dir
+`,
+			expected: map[string]any{
+				"dir":          "./migrations",
+				"environments": map[string]any{"dev": map[string]any{"dir": "./migrations"}},
+			},
+		},
+		"tab indentation": {
+			input:       "tls:\n\tca: ca.pem\n",
+			expectError: "line 2: found character that cannot start any token",
+		},
+		"duplicate key": {
+			input:       "dir: a\ndir: b\n",
+			expectError: `line 2: duplicate key "dir"`,
+		},
+		"missing colon": {
+			input:       "dir ./migrations\n",
+			expectError: "line 1: expected a mapping",
+		},
+		"unexpected indentation": {
+			input:       "dir: a\n  table: b\n",
+			expectError: "line 2: mapping values are not allowed in this context",
+		},
+		"list of mappings": {
+			input:       "hosts:\n  - host: node1\n    port: 9042\n",
+			expectError: "line 2: only lists of scalars are supported",
+		},
+		"top-level list": {
+			input:       "- a\n",
+			expectError: "line 1: expected a mapping",
+		},
+		"unterminated quote": {
+			input:       "dir: \"migrations\n",
+			expectError: "line 2: found unexpected end of stream",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			doc, err := parseYAML([]byte(tc.input))
+			if tc.expectError != "" {
+				td.Cmp(t, err, td.ErrorIs(td.Contains(tc.expectError)))
+				return
+			}
+
+			td.CmpNoError(t, err)
+			td.Cmp(t, doc, tc.expected)
+		})
+	}
+}
+
+func TestParseConfigFile(t *testing.T) {
+	const input = `dir: ./migrations
+table: history
+default_env: dev
+
+environments:
+  dev:
+    keyspace: myapp_dev
+  prod:
+    keyspace: myapp
+    table: prod_history
+    hosts: [node1:9042, node2:9042]
+    tls:
+      ca: ca.pem
+      insecure_skip_verify: false
+`
+
+	file, err := parseConfigFile("scyllamigrate.yaml", []byte(input))
+	td.Require(t).CmpNoError(err)
+	td.Cmp(t, file.defaultEnv, "dev")
+
+	base, err := file.resolve("")
+	td.CmpNoError(t, err)
+	td.Cmp(t, base, map[string]string{"dir": "./migrations", "table": "history"})
+
+	prod, err := file.resolve("prod")
+	td.CmpNoError(t, err)
+	td.Cmp(t, prod, map[string]string{
+		"dir":                      "./migrations",
+		"table":                    "prod_history",
+		"keyspace":                 "myapp",
+		"hosts":                    "node1:9042,node2:9042",
+		"tls.ca":                   "ca.pem",
+		"tls.insecure_skip_verify": "false",
+	})
+
+	_, err = file.resolve("staging")
+	td.Cmp(t, err, td.ErrorIs(td.Contains(`unknown environment "staging" (defined: dev, prod)`)))
+
+	t.Run("errors", func(t *testing.T) {
+		tests := map[string]struct {
+			input       string
+			expectError string
+		}{
+			"unknown setting": {
+				input:       "keyspce: myapp\n",
+				expectError: `unknown setting "keyspce"`,
+			},
+			"unknown nested setting": {
+				input:       "environments:\n  dev:\n    tls:\n      crt: client.pem\n",
+				expectError: `environment "dev": unknown setting "tls.crt"`,
+			},
+			"undefined default environment": {
+				input:       "default_env: prod\nenvironments:\n  dev:\n    keyspace: dev\n",
+				expectError: `default_env "prod" is not defined`,
+			},
+			"environments is not a mapping": {
+				input:       "environments: dev\n",
+				expectError: "environments must be a mapping",
+			},
+			"environment is not a mapping": {
+				input:       "environments:\n  dev: myapp\n",
+				expectError: `environment "dev" must be a mapping`,
+			},
+		}
+
+		for name, tc := range tests {
+			t.Run(name, func(t *testing.T) {
+				_, err := parseConfigFile("scyllamigrate.yaml", []byte(tc.input))
+				td.Cmp(t, err, td.ErrorIs(td.Contains(tc.expectError)))
+			})
+		}
+	})
+}
+
+// newTestFlagSet returns a flag set with a few of the global flags.
+func newTestFlagSet(c *config) *flag.FlagSet {
+	fs := flag.NewFlagSet("scyllamigrate", flag.ContinueOnError)
+	fs.StringVar(&c.configFile, "config", "", "")
+	fs.StringVar(&c.env, "env", "", "")
+	fs.StringVar(&c.hosts, "hosts", "localhost:9042", "")
+	fs.StringVar(&c.keyspace, "keyspace", "", "")
+	fs.StringVar(&c.password, "password", "", "")
+	fs.DurationVar(&c.timeout, "timeout", 30*time.Second, "")
+	fs.BoolVar(&c.yes, "yes", false, "")
+
+	return fs
+}
+
+func TestApplyConfig(t *testing.T) {
+	var c config
+
+	fs := newTestFlagSet(&c)
+	td.Require(t).CmpNoError(fs.Parse([]string{"-keyspace", "from_flag"}))
+
+	t.Setenv("SCYLLA_HOSTS", "env:9042")
+
+	applied, err := applyConfig(fs, map[string]string{
+		"hosts":             "file:9042",
+		"keyspace":          "from_file",
+		"timeout":           "1m",
+		"allow_destructive": "true",
+	})
+	td.Require(t).CmpNoError(err)
+
+	td.Cmp(t, applied, map[string]bool{"timeout": true, "yes": true})
+	td.Cmp(t, c.keyspace, "from_flag")
+	td.Cmp(t, c.timeout, time.Minute)
+	td.Cmp(t, c.yes, true)
+
+	// The environment variable wins, scotty uses it as the flag default.
+	td.Cmp(t, c.hosts, "localhost:9042")
+
+	_, err = applyConfig(newTestFlagSet(&c), map[string]string{"allow_destructive": "maybe"})
+	td.Cmp(t, err, td.ErrorIs(td.Contains(`invalid value "maybe" for allow_destructive`)))
+}
+
+func TestApplyConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+
+	td.Require(t).CmpNoError(os.WriteFile(path, []byte(`keyspace: base
+environments:
+  prod:
+    keyspace: prod
+    password: secret
+`), 0o600))
+
+	t.Run("selected environment", func(t *testing.T) {
+		setConfig(t, config{})
+
+		fs := newTestFlagSet(&cfg)
+		td.Require(t).CmpNoError(fs.Parse([]string{"-config", path, "-env", "prod"}))
+
+		td.Require(t).CmpNoError(applyConfigFile(fs))
+		td.Cmp(t, cfg.keyspace, "prod")
+		td.Cmp(t, loadedConfig.env, "prod")
+
+		out := newConfigOutput(fs)
+		td.Cmp(t, out.File, path)
+		td.Cmp(t, out.Settings, td.SuperBagOf(
+			settingOutput{Key: "keyspace", Value: "prod", Source: sourceFile},
+			settingOutput{Key: "password", Value: maskedSecret, Source: sourceFile},
+			settingOutput{Key: "hosts", Value: "localhost:9042", Source: sourceDefault},
+		))
+
+		var b strings.Builder
+
+		printConfig(&b, out)
+		td.Cmp(t, b.String(), td.Re(`password +=  ?\*{8} \(file\)`))
+		td.Cmp(t, b.String(), td.Not(td.Contains("secret")))
+	})
+
+	t.Run("base settings", func(t *testing.T) {
+		setConfig(t, config{})
+
+		fs := newTestFlagSet(&cfg)
+		td.Require(t).CmpNoError(fs.Parse([]string{"-config", path, "-keyspace", "cli"}))
+
+		td.Require(t).CmpNoError(applyConfigFile(fs))
+		td.Cmp(t, cfg.keyspace, "cli")
+	})
+
+	t.Run("missing explicit file", func(t *testing.T) {
+		setConfig(t, config{})
+
+		fs := newTestFlagSet(&cfg)
+		td.Require(t).CmpNoError(fs.Parse([]string{"-config", filepath.Join(dir, "missing.yaml")}))
+
+		td.Cmp(t, applyConfigFile(fs), td.ErrorIs(td.Contains("failed to read config file")))
+	})
+
+	t.Run("missing default file", func(t *testing.T) {
+		t.Chdir(dir)
+		setConfig(t, config{})
+
+		td.CmpNoError(t, applyConfigFile(newTestFlagSet(&cfg)))
+	})
+
+	t.Run("environment without file", func(t *testing.T) {
+		t.Chdir(dir)
+		setConfig(t, config{})
+
+		fs := newTestFlagSet(&cfg)
+		td.Require(t).CmpNoError(fs.Parse([]string{"-env", "prod"}))
+
+		td.Cmp(t, applyConfigFile(fs), td.ErrorIs(td.Contains("there is no config file")))
+	})
+}
+
+func TestConfigured(t *testing.T) {
+	cmd := configured(&scotty.Command{
+		Name:     "test",
+		SetFlags: func(f *scotty.FlagSet) { f.Bool("yes", false, "") },
+		Run:      func(*scotty.Command, []string) error { return nil },
+	})[0]
+
+	cmd.Flags().SetOutput(io.Discard)
+
+	// A flag error is returned instead of exiting the process.
+	td.Cmp(t, cmd.Flags().Parse([]string{"-bogus"}), td.Contains("flag provided but not defined: -bogus"))
+	td.CmpErrorIs(t, cmd.Flags().Parse([]string{"-help"}), flag.ErrHelp)
+}
@@ -1,11 +1,17 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -31,6 +37,28 @@ type config struct {
 	datacenter  string
 	username    string
 	password    string
+	lockTTL     time.Duration
+	lockTimeout time.Duration
+	dirty       string
+	outOfOrder  string
+	yes         bool
+	output      string
+
+	detailedExitCode bool
+
+	configFile string
+	env        string
+
+	tlsCA                 string
+	tlsCert               string
+	tlsKey                string
+	tlsServerName         string
+	tlsInsecureSkipVerify bool
+
+	runTimeout             time.Duration
+	statementTimeout       time.Duration
+	migrationTimeout       time.Duration
+	schemaAgreementTimeout time.Duration
 }
 
 // Global configuration flags.
@@ -53,6 +81,12 @@ func main() {
 		Short: "ScyllaDB schema migration tool",
 		Long:  "A tool for managing ScyllaDB schema migrations with up/down support.",
 		SetFlags: func(f *scotty.FlagSet) {
+			f.StringVarE(&cfg.configFile, "config", "SCYLLA_CONFIG", "",
+				"Config file (default: "+defaultConfigFile+" if it exists)",
+			)
+			f.StringVarE(&cfg.env, "env", "SCYLLA_ENV", "",
+				"Environment of the config file to use (default: default_env of the config file)",
+			)
 			f.StringVarE(&cfg.hosts, "hosts", "SCYLLA_HOSTS", "localhost:9042",
 				"Comma-separated list of ScyllaDB hosts",
 			)
@@ -66,7 +100,19 @@ func main() {
 				"Consistency level (any, one, two, three, quorum, all, local_quorum, each_quorum, local_one)",
 			)
 			f.DurationVarE(&cfg.timeout, "timeout", "SCYLLA_TIMEOUT", 30*time.Second,
-				"Operation timeout",
+				"Query timeout and timeout of commands other than up, down and goto",
+			)
+			f.DurationVarE(&cfg.runTimeout, "run-timeout", "SCYLLA_RUN_TIMEOUT", 0,
+				"Timeout of a whole up, down or goto run (0 = no limit)",
+			)
+			f.DurationVarE(&cfg.migrationTimeout, "migration-timeout", "SCYLLA_MIGRATION_TIMEOUT", 0,
+				"Timeout of a single migration (0 = no limit)",
+			)
+			f.DurationVarE(&cfg.statementTimeout, "statement-timeout", "SCYLLA_STATEMENT_TIMEOUT", 0,
+				"Timeout of a single migration statement (0 = use -timeout)",
+			)
+			f.DurationVarE(&cfg.schemaAgreementTimeout, "schema-agreement-timeout", "SCYLLA_SCHEMA_AGREEMENT_TIMEOUT", 0,
+				"Timeout of a single wait for schema agreement (0 = driver default)",
 			)
 			f.StringVarE(&cfg.table, "table", "SCYLLA_MIGRATIONS_TABLE", "schema_migrations",
 				"Migration history table name",
@@ -80,35 +126,786 @@ func main() {
 			f.StringVarE(&cfg.password, "password", "SCYLLA_PASSWORD", "",
 				"ScyllaDB password for authentication",
 			)
+			f.StringVarE(&cfg.tlsCA, "tls-ca", "SCYLLA_TLS_CA", "",
+				"PEM file with the CA certificates to verify ScyllaDB with (enables TLS, default: system roots)",
+			)
+			f.StringVarE(&cfg.tlsCert, "tls-cert", "SCYLLA_TLS_CERT", "",
+				"PEM file with the client certificate (enables TLS, requires -tls-key)",
+			)
+			f.StringVarE(&cfg.tlsKey, "tls-key", "SCYLLA_TLS_KEY", "",
+				"PEM file with the client private key (enables TLS, requires -tls-cert)",
+			)
+			f.StringVarE(&cfg.tlsServerName, "tls-server-name", "SCYLLA_TLS_SERVER_NAME", "",
+				"Server name to verify the ScyllaDB certificate against (enables TLS)",
+			)
+			f.BoolVarE(&cfg.tlsInsecureSkipVerify, "tls-insecure-skip-verify", "SCYLLA_TLS_INSECURE_SKIP_VERIFY", false,
+				"Skip verification of the ScyllaDB certificate (enables TLS, insecure)",
+			)
+			f.DurationVarE(&cfg.lockTTL, "lock-ttl", "SCYLLA_LOCK_TTL", time.Minute,
+				"Lease duration of the migration lock (renewed while migrations run)",
+			)
+			f.DurationVarE(&cfg.lockTimeout, "lock-timeout", "SCYLLA_LOCK_TIMEOUT", 5*time.Minute,
+				"How long to wait for a migration lock held by another process (0 = fail immediately)",
+			)
+			f.StringVarE(&cfg.dirty, "dirty", "SCYLLA_DIRTY_POLICY", "resume",
+				"How to handle a migration that failed partway through (resume, restart, fail)",
+			)
+			f.StringVarE(&cfg.outOfOrder, "out-of-order", "SCYLLA_OUT_OF_ORDER", "allow",
+				"How to handle pending migrations older than the current version (allow, strict, ignore)",
+			)
+			f.BoolVarE(&cfg.yes, "yes", "SCYLLA_YES", false,
+				"Run destructive migrations (DROP, TRUNCATE) without asking for confirmation",
+			)
+			f.StringVarE(&cfg.output, "output", "SCYLLA_OUTPUT", outputTable,
+				"Output format (table, json, yaml)",
+			)
+			f.BoolVarE(&cfg.detailedExitCode, "detailed-exitcode", "SCYLLA_DETAILED_EXITCODE", false,
+				"Exit with code 2 when there was nothing to do",
+			)
 		},
 	}
 
-	rootCmd.AddSubcommands(
+	rootCmd.AddSubcommands(configured(
 		upCmd(),
 		downCmd(),
+		gotoCmd(),
 		statusCmd(),
 		createCmd(),
+		renumberCmd(),
+		validateCmd(),
+		lintCmd(),
 		versionCmd(),
 		createKeyspaceCmd(),
-	)
+		lockCmd(),
+		forceCmd(),
+		baselineCmd(),
+		configCmd(),
+	)...)
+
+	rootCmd.Flags().Init(rootCmd.Name, flag.ContinueOnError)
 
-	if err := rootCmd.Exec(); err != nil {
+	err := rootCmd.Exec()
+	if err != nil && !errors.Is(err, scyllamigrate.ErrNoChange) && !errors.Is(err, flag.ErrHelp) {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
 	}
+
+	os.Exit(exitCode(err, cfg.detailedExitCode))
+}
+
+func upCmd() *scotty.Command {
+	var (
+		steps  int
+		dryRun bool
+	)
+
+	return &scotty.Command{
+		Name:  "up",
+		Short: "Apply pending migrations",
+		Long:  "Apply all pending migrations or a specific number of migrations.",
+		SetFlags: func(f *scotty.FlagSet) {
+			f.IntVar(&steps, "n", 0, "Number of migrations to apply (0 = all)")
+			f.BoolVar(&dryRun, "dry-run", false, "Print the CQL that would run without executing it")
+		},
+		Run: func(_ *scotty.Command, _ []string) error {
+			format, err := parseOutputFormat(cfg.output)
+			if err != nil {
+				return err
+			}
+
+			migrator, err := createMigrator()
+			if err != nil {
+				return err
+			}
+			defer migrator.Close()
+
+			ctx, cancel := runContext()
+			defer cancel()
+
+			if dryRun {
+				target := scyllamigrate.TargetLatest()
+				if steps > 0 {
+					target = scyllamigrate.TargetSteps(steps)
+				}
+
+				return printPlan(ctx, migrator, format, target, "No migrations to apply")
+			}
+
+			var result *scyllamigrate.Result
+
+			if steps > 0 {
+				result, err = migrator.Steps(ctx, steps)
+			} else {
+				result, err = migrator.Up(ctx)
+			}
+
+			return reportRun(ctx, migrator, format, result, err, func(w io.Writer, executed int) {
+				if executed == 0 {
+					fmt.Fprintln(w, "No migrations to apply")
+					return
+				}
+
+				fmt.Fprintf(w, "Applied %d migration(s)\n", executed)
+			})
+		},
+	}
+}
+
+func downCmd() *scotty.Command {
+	var (
+		steps  int
+		dryRun bool
+	)
+
+	return &scotty.Command{
+		Name:  "down",
+		Short: "Rollback migrations",
+		Long:  "Rollback the last migration or a specific number of migrations.",
+		SetFlags: func(f *scotty.FlagSet) {
+			f.IntVar(&steps, "n", 1, "Number of migrations to rollback")
+			f.BoolVar(&dryRun, "dry-run", false, "Print the CQL that would run without executing it")
+		},
+		Run: func(_ *scotty.Command, _ []string) error {
+			format, err := parseOutputFormat(cfg.output)
+			if err != nil {
+				return err
+			}
+
+			migrator, err := createMigrator()
+			if err != nil {
+				return err
+			}
+			defer migrator.Close()
+
+			ctx, cancel := runContext()
+			defer cancel()
+
+			if dryRun {
+				return printPlan(ctx, migrator, format, scyllamigrate.TargetSteps(-steps), "No migrations to rollback")
+			}
+
+			result, err := migrator.Steps(ctx, -steps)
+
+			return reportRun(ctx, migrator, format, result, err, func(w io.Writer, executed int) {
+				if executed == 0 {
+					fmt.Fprintln(w, "No migrations to rollback")
+					return
+				}
+
+				fmt.Fprintf(w, "Rolled back %d migration(s)\n", executed)
+			})
+		},
+	}
+}
+
+func gotoCmd() *scotty.Command {
+	return &scotty.Command{
+		Name:  "goto",
+		Short: "Migrate to a specific version",
+		Long: `Migrate the schema to the given version in whichever direction is needed.
+
+Applied migrations above the version are rolled back, then pending migrations
+up to and including the version are applied. Version 0 rolls back everything.
+
+Examples:
+  # Move to version 12, whether the keyspace is currently at 8 or at 15
+  scyllamigrate -keyspace myapp goto 12`,
+		Run: func(_ *scotty.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("version is required")
+			}
+
+			version, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid version %q: %w", args[0], err)
+			}
+
+			format, err := parseOutputFormat(cfg.output)
+			if err != nil {
+				return err
+			}
+
+			migrator, err := createMigrator()
+			if err != nil {
+				return err
+			}
+			defer migrator.Close()
+
+			ctx, cancel := runContext()
+			defer cancel()
+
+			result, err := migrator.Goto(ctx, version)
+
+			return reportRun(ctx, migrator, format, result, err, func(w io.Writer, executed int) {
+				if executed == 0 {
+					fmt.Fprintf(w, "Already at version %d\n", version)
+					return
+				}
+
+				fmt.Fprintf(w, "Migrated to version %d (%d migration(s))\n", version, executed)
+			})
+		},
+	}
+}
+
+// runContext returns the context of an up, down or goto run, bounded by -run-timeout if it is set.
+func runContext() (context.Context, context.CancelFunc) {
+	if cfg.runTimeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+
+	return context.WithTimeout(context.Background(), cfg.runTimeout)
+}
+
+// reportRun writes the result of an up, down or goto run and returns the error
+// the command fails with: runErr, or ErrNoChange if nothing was executed.
+// The table output is only written for successful runs.
+func reportRun(
+	ctx context.Context,
+	migrator *managedMigrator,
+	format string,
+	result *scyllamigrate.Result,
+	runErr error,
+	table func(w io.Writer, executed int),
+) error {
+	out := newRunResult(result, runErr)
+
+	if out.Status == runFailed && format == outputTable {
+		return runErr
+	}
+
+	// The version is informational, so a failure to read it does not fail the run.
+	if version, err := migrator.Version(ctx); err == nil {
+		out.CurrentVersion = version
+	}
+
+	if err := writeOutput(os.Stdout, format, out, func(w io.Writer) {
+		table(w, len(out.Migrations))
+
+		if len(out.Skipped) > 0 {
+			fmt.Fprintf(w, "Skipped %d out-of-order migration(s): %s\n", len(out.Skipped), joinVersions(out.Skipped))
+		}
+	}); err != nil {
+		return err
+	}
+
+	switch out.Status {
+	case runFailed:
+		return runErr
+	case runNoChange:
+		return scyllamigrate.ErrNoChange
+	default:
+		return nil
+	}
+}
+
+// newRunResult converts the result of a run that ended with runErr.
+func newRunResult(result *scyllamigrate.Result, runErr error) *runResult {
+	out := &runResult{
+		Status:     runSuccess,
+		Migrations: make([]executedMigration, 0, result.Count()),
+		Skipped:    []uint64{},
+	}
+
+	if result != nil {
+		for _, em := range result.Executed {
+			out.Migrations = append(out.Migrations, executedMigration{
+				Version:     em.Version,
+				Description: em.Description,
+				Direction:   em.Direction.String(),
+				DurationMs:  em.Duration.Milliseconds(),
+			})
+		}
+
+		out.Skipped = append(out.Skipped, result.Skipped...)
+	}
+
+	switch {
+	case runErr != nil && !errors.Is(runErr, scyllamigrate.ErrNoChange):
+		out.Status = runFailed
+		out.Error = runErr.Error()
+	case len(out.Migrations) == 0:
+		out.Status = runNoChange
+	}
+
+	return out
+}
+
+// printPlan prints the CQL a run towards target would execute.
+func printPlan(ctx context.Context, migrator *managedMigrator, format string, target scyllamigrate.Target, empty string) error {
+	plan, err := migrator.Plan(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(os.Stdout, format, newPlanOutput(plan), func(w io.Writer) {
+		if len(plan) == 0 {
+			fmt.Fprintln(w, empty)
+			return
+		}
+
+		fmt.Fprint(w, formatPlan(plan))
+	})
+}
+
+// formatPlan renders a plan as an executable CQL script annotated with comments.
+func formatPlan(plan []*scyllamigrate.PlannedMigration) string {
+	var b strings.Builder
+
+	for i, pm := range plan {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		fmt.Fprintf(&b, "-- [%d] %s (%s)", pm.Version, pm.Description, pm.Direction)
+
+		if pm.File != "" {
+			fmt.Fprintf(&b, " from %s", pm.File)
+		}
+
+		b.WriteString("\n")
+
+		if pm.Go {
+			b.WriteString("-- Go migration, no CQL to show\n")
+			continue
+		}
+
+		if pm.FromStatement > 1 {
+			fmt.Fprintf(&b, "-- resuming at statement %d\n", pm.FromStatement)
+		}
+
+		for _, stmt := range pm.Statements {
+			b.WriteString(stmt.Text)
+			b.WriteString(";\n")
+		}
+	}
+
+	return b.String()
+}
+
+func statusCmd() *scotty.Command {
+	return &scotty.Command{
+		Name:  "status",
+		Short: "Show migration status",
+		Long:  "Display the current migration status including applied and pending migrations.",
+		Run: func(_ *scotty.Command, _ []string) error {
+			format, err := parseOutputFormat(cfg.output)
+			if err != nil {
+				return err
+			}
+
+			migrator, err := createMigrator()
+			if err != nil {
+				return err
+			}
+			defer migrator.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout)
+			defer cancel()
+
+			status, err := migrator.Status(ctx)
+			if err != nil {
+				return err
+			}
+
+			var drift []scyllamigrate.ChecksumMismatch
+
+			if err := migrator.Verify(ctx); err != nil {
+				var checksumErr *scyllamigrate.ChecksumError
+				if !errors.As(err, &checksumErr) {
+					return err
+				}
+
+				drift = checksumErr.Mismatches
+			}
+
+			out := newStatusOutput(cfg.keyspace, status, drift)
+
+			return writeOutput(os.Stdout, format, out, func(w io.Writer) { printStatus(w, out) })
+		},
+	}
+}
+
+// printStatus writes the status for humans.
+func printStatus(w io.Writer, status *statusOutput) {
+	fmt.Fprintf(w, "Current Version: %d\n\n", status.CurrentVersion)
+
+	if status.Dirty != nil {
+		fmt.Fprintf(w, "DIRTY: %s migration %d failed at statement %d (repair the schema and run force)\n\n",
+			status.Dirty.Direction, status.Dirty.Version, status.Dirty.Statement)
+	}
+
+	if len(status.Applied) > 0 {
+		fmt.Fprintln(w, "Applied Migrations:")
+		fmt.Fprintln(w, "-------------------")
+
+		for _, m := range status.Applied {
+			modified := ""
+			if m.Modified {
+				modified = " (modified)"
+			}
+
+			if m.Baselined {
+				fmt.Fprintf(w, "  [%d] %s (baselined at %s)%s\n",
+					m.Version, m.Description, m.AppliedAt.Format(time.RFC3339), modified)
+
+				continue
+			}
+
+			fmt.Fprintf(w, "  [%d] %s (applied at %s, took %dms)%s\n",
+				m.Version, m.Description, m.AppliedAt.Format(time.RFC3339), m.ExecutionMs, modified)
+		}
+
+		fmt.Fprintln(w)
+	}
+
+	if len(status.Pending) == 0 {
+		fmt.Fprintln(w, "No pending migrations")
+		return
+	}
+
+	fmt.Fprintln(w, "Pending Migrations:")
+	fmt.Fprintln(w, "-------------------")
+
+	for _, m := range status.Pending {
+		if m.OutOfOrder {
+			fmt.Fprintf(w, "  [%d] %s (out of order)\n", m.Version, m.Description)
+			continue
+		}
+
+		fmt.Fprintf(w, "  [%d] %s\n", m.Version, m.Description)
+	}
+}
+
+func createCmd() *scotty.Command {
+	var (
+		ext       string
+		format    string
+		seqDigits int
+	)
+
+	return &scotty.Command{
+		Name:  "create",
+		Short: "Create new migration files",
+		Long: `Create a new pair of up/down migration files.
+
+By default the version is the next sequential number. With -format timestamp
+the version is the current UTC time (YYYYMMDDHHMMSS), which avoids version
+conflicts between branches developed in parallel.`,
+		SetFlags: func(f *scotty.FlagSet) {
+			f.StringVar(&ext, "ext", "cql", "File extension (cql or sql)")
+			f.StringVar(&format, "format", versionFormatSequential, "Version format (sequential or timestamp)")
+			f.IntVar(&seqDigits, "seq-digits", defaultSeqDigits, "Number of digits of sequential versions")
+		},
+		Run: func(_ *scotty.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("migration name is required")
+			}
+
+			name := args[0]
+
+			// Validate extension.
+			if ext != "cql" && ext != "sql" {
+				return fmt.Errorf("invalid extension: %s (must be cql or sql)", ext)
+			}
+
+			if seqDigits < 1 {
+				return fmt.Errorf("invalid number of digits: %d (must be at least 1)", seqDigits)
+			}
+
+			// Ensure migrations directory exists.
+			if err := os.MkdirAll(cfg.dir, migrationsDirMode); err != nil {
+				return fmt.Errorf("failed to create migrations directory: %w", err)
+			}
+
+			var (
+				version uint64
+				err     error
+			)
+
+			switch format {
+			case versionFormatSequential:
+				version, err = findNextVersion(cfg.dir)
+			case versionFormatTimestamp:
+				version, err = findNextTimestampVersion(cfg.dir, time.Now())
+			default:
+				return fmt.Errorf("invalid version format: %s (must be sequential or timestamp)", format)
+			}
+
+			if err != nil {
+				return err
+			}
+
+			// Create filenames.
+			upFile := migrationFilename(version, seqDigits, name, scyllamigrate.Up, ext)
+			downFile := migrationFilename(version, seqDigits, name, scyllamigrate.Down, ext)
+
+			upPath := filepath.Join(cfg.dir, upFile)
+			downPath := filepath.Join(cfg.dir, downFile)
+
+			// Create up migration file.
+			if err := os.WriteFile(upPath, []byte("-- Migration: "+name+" (up)\n\n"), migrationFileMode); err != nil {
+				return fmt.Errorf("failed to create up migration: %w", err)
+			}
+
+			// Create down migration file.
+			if err := os.WriteFile(downPath, []byte("-- Migration: "+name+" (down)\n\n"), migrationFileMode); err != nil {
+				return fmt.Errorf("failed to create down migration: %w", err)
+			}
+
+			fmt.Println("Created migration files:")
+			fmt.Printf("  %s\n", upPath)
+			fmt.Printf("  %s\n", downPath)
+
+			return nil
+		},
+	}
+}
+
+func renumberCmd() *scotty.Command {
+	var (
+		seqDigits int
+		dryRun    bool
+	)
+
+	return &scotty.Command{
+		Name:  "renumber",
+		Short: "Move pending sequential migrations onto the tip",
+		Long: `Renumber pending sequential migrations after a rebase.
+
+A pending migration is moved after the highest version in the migrations
+directory if it is older than the current version of the keyspace or shares
+its version with another migration. Moved migrations keep their relative
+order. Timestamp-based migrations are never renumbered.
+
+Examples:
+  # Show what would be renamed
+  scyllamigrate -keyspace myapp renumber -dry-run
+
+  # Rename the files
+  scyllamigrate -keyspace myapp renumber`,
+		SetFlags: func(f *scotty.FlagSet) {
+			f.IntVar(&seqDigits, "seq-digits", defaultSeqDigits, "Number of digits of sequential versions")
+			f.BoolVar(&dryRun, "dry-run", false, "Print the renames without renaming any file")
+		},
+		Run: func(_ *scotty.Command, _ []string) error {
+			files, err := scanMigrationFiles(cfg.dir)
+			if err != nil {
+				return err
+			}
+
+			migrator, err := createMigrator()
+			if err != nil {
+				return err
+			}
+			defer migrator.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout)
+			defer cancel()
+
+			applied, err := migrator.Applied(ctx)
+			if err != nil {
+				return err
+			}
+
+			appliedDescriptions := make(map[uint64]string, len(applied))
+			for _, am := range applied {
+				appliedDescriptions[am.Version] = am.Description
+			}
+
+			renames := planRenumber(files, appliedDescriptions, seqDigits)
+			if len(renames) == 0 {
+				fmt.Println("No migrations to renumber")
+
+				return nil
+			}
+
+			for _, r := range renames {
+				fmt.Printf("  %s -> %s\n", r.from, r.to)
+
+				if dryRun {
+					continue
+				}
+
+				if err := os.Rename(filepath.Join(cfg.dir, r.from), filepath.Join(cfg.dir, r.to)); err != nil {
+					return fmt.Errorf("failed to rename %s: %w", r.from, err)
+				}
+			}
+
+			if !dryRun {
+				fmt.Printf("Renumbered %d file(s)\n", len(renames))
+			}
+
+			return nil
+		},
+	}
+}
+
+func validateCmd() *scotty.Command {
+	return &scotty.Command{
+		Name:  "validate",
+		Short: "Check the migrations directory without a cluster",
+		Long: `Check the migration files for problems without connecting to ScyllaDB.
+
+Reports invalid filenames, duplicate versions, missing up or down files,
+mixed .cql and .sql files, empty migrations, statements that cannot be
+parsed and gaps between sequential versions.
+
+Each issue is printed on its own line as "file:line: severity: message (kind)".
+The command exits with a non-zero status if any issue is an error.
+
+Examples:
+  # Check ./migrations
+  scyllamigrate validate
+
+  # Print the report as JSON
+  scyllamigrate -dir ./migrations -output json validate`,
+		Run: func(_ *scotty.Command, _ []string) error {
+			format, err := parseOutputFormat(cfg.output)
+			if err != nil {
+				return err
+			}
+
+			// The files are checked without creating a source, which fails on duplicate versions.
+			report, err := scyllamigrate.ValidateFS(os.DirFS(cfg.dir))
+			if err != nil {
+				return fmt.Errorf("failed to read migrations directory: %w", err)
+			}
+
+			if err := printValidationReport(os.Stdout, report, format); err != nil {
+				return err
+			}
+
+			if report.HasErrors() {
+				return fmt.Errorf("validation failed with %d error(s)", report.Errors())
+			}
+
+			return nil
+		},
+	}
+}
+
+func lintCmd() *scotty.Command {
+	var (
+		severities string
+		listRules  bool
+	)
+
+	return &scotty.Command{
+		Name:  "lint",
+		Short: "Check migrations for dangerous CQL without a cluster",
+		Long: `Check the statements of the migration files for destructive, expensive and
+non-idempotent operations without connecting to ScyllaDB.
+
+Rule severities can be changed with -severity. A migration file disables rules
+for itself with a line comment:
+
+  -- scyllamigrate:disable drop_table, drop_column
+
+The command exits with a non-zero status if any issue is an error.
+
+Examples:
+  # Check ./migrations
+  scyllamigrate lint
+
+  # Fail on new indexes and ignore missing IF EXISTS
+  scyllamigrate lint -severity create_index=error,missing_if_exists=off
+
+  # List the rules
+  scyllamigrate lint -rules`,
+		SetFlags: func(f *scotty.FlagSet) {
+			f.StringVar(&severities, "severity", "", "Comma-separated rule=severity pairs (error, warning or off)")
+			f.BoolVar(&listRules, "rules", false, "List the rules and their default severities")
+		},
+		Run: func(_ *scotty.Command, _ []string) error {
+			format, err := parseOutputFormat(cfg.output)
+			if err != nil {
+				return err
+			}
+
+			if listRules {
+				for _, rule := range scyllamigrate.LintRules() {
+					fmt.Printf("%-26s %-8s %s\n", rule.Name, rule.Severity, rule.Description)
+				}
+
+				return nil
+			}
+
+			opts, err := parseLintSeverities(severities)
+			if err != nil {
+				return err
+			}
+
+			source, err := scyllamigrate.NewDirSource(cfg.dir)
+			if err != nil {
+				return fmt.Errorf("failed to open migrations directory: %w", err)
+			}
+			defer source.Close()
+
+			report, err := scyllamigrate.Lint(source, opts...)
+			if err != nil {
+				return err
+			}
+
+			if err := printValidationReport(os.Stdout, report, format); err != nil {
+				return err
+			}
+
+			if report.HasErrors() {
+				return fmt.Errorf("lint failed with %d error(s)", report.Errors())
+			}
+
+			return nil
+		},
+	}
+}
+
+// parseLintSeverities parses comma-separated rule=severity pairs into lint options.
+func parseLintSeverities(s string) ([]scyllamigrate.LintOption, error) {
+	var opts []scyllamigrate.LintOption
+
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		rule, severity, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid lint severity %q (expected rule=severity)", pair)
+		}
+
+		opts = append(opts, scyllamigrate.WithLintSeverity(
+			scyllamigrate.IssueKind(strings.TrimSpace(rule)),
+			scyllamigrate.Severity(strings.ToLower(strings.TrimSpace(severity))),
+		))
+	}
+
+	return opts, nil
 }
 
-func upCmd() *scotty.Command {
-	var steps int
+// printValidationReport writes the report in the given output format. The table
+// format has one issue per line followed by a summary.
+func printValidationReport(w io.Writer, report *scyllamigrate.ValidationReport, format string) error {
+	return writeOutput(w, format, report, func(w io.Writer) {
+		for _, issue := range report.Issues {
+			fmt.Fprintln(w, issue.String())
+		}
+
+		fmt.Fprintf(w, "%d error(s), %d warning(s)\n", report.Errors(), report.Warnings())
+	})
+}
 
+func versionCmd() *scotty.Command {
 	return &scotty.Command{
-		Name:  "up",
-		Short: "Apply pending migrations",
-		Long:  "Apply all pending migrations or a specific number of migrations.",
-		SetFlags: func(f *scotty.FlagSet) {
-			f.IntVar(&steps, "n", 0, "Number of migrations to apply (0 = all)")
-		},
+		Name:  "version",
+		Short: "Show current migration version",
+		Long:  "Display the current migration version number.",
 		Run: func(_ *scotty.Command, _ []string) error {
+			format, err := parseOutputFormat(cfg.output)
+			if err != nil {
+				return err
+			}
+
 			migrator, err := createMigrator()
 			if err != nil {
 				return err
@@ -118,47 +915,48 @@ func upCmd() *scotty.Command {
 			ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout)
 			defer cancel()
 
-			var applied int
+			version, err := migrator.Version(ctx)
+			if err != nil {
+				return err
+			}
 
-			switch {
-			case steps > 0:
-				if err := migrator.Steps(ctx, steps); err != nil {
-					return err
+			return writeOutput(os.Stdout, format, versionOutput{CurrentVersion: version}, func(w io.Writer) {
+				if version == 0 {
+					fmt.Fprintln(w, "No migrations applied")
+					return
 				}
 
-				applied = steps
-
-			default:
-				applied, err = migrator.Up(ctx)
-				if err != nil {
-					return err
-				}
-			}
+				fmt.Fprintf(w, "Current version: %d\n", version)
+			})
+		},
+	}
+}
 
-			if applied == 0 {
-				fmt.Println("No migrations to apply")
+func forceCmd() *scotty.Command {
+	return &scotty.Command{
+		Name:  "force",
+		Short: "Clear the dirty state after manual repair",
+		Long: `Clear the dirty state left by a migration that failed partway through.
 
-				return nil
-			}
+Repair the schema manually first. The dirty migration is recorded as applied
+if its version is less than or equal to the given version, and as not applied otherwise.
 
-			fmt.Printf("Applied %d migration(s)\n", applied)
+Examples:
+  # Migration 5 failed, the remaining statements were applied by hand
+  scyllamigrate -keyspace myapp force 5
 
-			return nil
-		},
-	}
-}
+  # Migration 5 failed, the applied statements were reverted by hand
+  scyllamigrate -keyspace myapp force 4`,
+		Run: func(_ *scotty.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("version is required")
+			}
 
-func downCmd() *scotty.Command {
-	var steps int
+			version, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid version %q: %w", args[0], err)
+			}
 
-	return &scotty.Command{
-		Name:  "down",
-		Short: "Rollback migrations",
-		Long:  "Rollback the last migration or a specific number of migrations.",
-		SetFlags: func(f *scotty.FlagSet) {
-			f.IntVar(&steps, "n", 1, "Number of migrations to rollback")
-		},
-		Run: func(_ *scotty.Command, _ []string) error {
 			migrator, err := createMigrator()
 			if err != nil {
 				return err
@@ -168,9 +966,9 @@ func downCmd() *scotty.Command {
 			ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout)
 			defer cancel()
 
-			if err := migrator.Steps(ctx, -steps); err != nil {
+			if err := migrator.Force(ctx, version); err != nil {
 				if errors.Is(err, scyllamigrate.ErrNoChange) {
-					fmt.Println("No migrations to rollback")
+					fmt.Println("Database is not dirty")
 
 					return nil
 				}
@@ -178,19 +976,36 @@ func downCmd() *scotty.Command {
 				return err
 			}
 
-			fmt.Printf("Rolled back %d migration(s)\n", steps)
+			fmt.Printf("Dirty state cleared at version %d\n", version)
 
 			return nil
 		},
 	}
 }
 
-func statusCmd() *scotty.Command {
+func baselineCmd() *scotty.Command {
 	return &scotty.Command{
-		Name:  "status",
-		Short: "Show migration status",
-		Long:  "Display the current migration status including applied and pending migrations.",
-		Run: func(_ *scotty.Command, _ []string) error {
+		Name:  "baseline",
+		Short: "Record existing schema as applied",
+		Long: `Record every migration up to and including the given version as applied
+without executing it.
+
+Use it once to adopt scyllamigrate on a keyspace whose schema was created by
+other means. Later runs of up only apply the migrations after that version.
+
+Examples:
+  # The keyspace already contains the schema of migrations 1 to 12
+  scyllamigrate -keyspace myapp baseline 12`,
+		Run: func(_ *scotty.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("version is required")
+			}
+
+			version, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid version %q: %w", args[0], err)
+			}
+
 			migrator, err := createMigrator()
 			if err != nil {
 				return err
@@ -200,105 +1015,89 @@ func statusCmd() *scotty.Command {
 			ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout)
 			defer cancel()
 
-			status, err := migrator.Status(ctx)
+			baselined, err := migrator.Baseline(ctx, version)
 			if err != nil {
 				return err
 			}
 
-			fmt.Printf("Current Version: %d\n\n", status.CurrentVersion)
-
-			if len(status.Applied) > 0 {
-				fmt.Println("Applied Migrations:")
-				fmt.Println("-------------------")
+			if baselined == 0 {
+				fmt.Printf("All migrations up to version %d are already applied\n", version)
 
-				for _, m := range status.Applied {
-					fmt.Printf("  [%d] %s (applied at %s, took %dms)\n",
-						m.Version, m.Description, m.AppliedAt.Format(time.RFC3339), m.ExecutionMs)
-				}
-
-				fmt.Println()
+				return nil
 			}
 
-			if len(status.Pending) > 0 {
-				fmt.Println("Pending Migrations:")
-				fmt.Println("-------------------")
-
-				for _, m := range status.Pending {
-					fmt.Printf("  [%d] %s\n", m.Version, m.Description)
-				}
-			} else {
-				fmt.Println("No pending migrations")
-			}
+			fmt.Printf("Baselined %d migration(s) up to version %d\n", baselined, version)
 
 			return nil
 		},
 	}
 }
 
-func createCmd() *scotty.Command {
-	var ext string
+func lockCmd() *scotty.Command {
+	cmd := &scotty.Command{
+		Name:  "lock",
+		Short: "Inspect or release the migration lock",
+		Long: `Inspect or release the cluster-wide migration lock.
 
-	return &scotty.Command{
-		Name:  "create",
-		Short: "Create new migration files",
-		Long:  "Create a new pair of up/down migration files with the next sequential version number.",
-		SetFlags: func(f *scotty.FlagSet) {
-			f.StringVar(&ext, "ext", "cql", "File extension (cql or sql)")
-		},
-		Run: func(_ *scotty.Command, args []string) error {
-			if len(args) < 1 {
-				return errors.New("migration name is required")
-			}
+Examples:
+  # Show who holds the lock
+  scyllamigrate -keyspace myapp lock status
 
-			name := args[0]
+  # Remove a lock left behind by a crashed process
+  scyllamigrate -keyspace myapp lock force-release`,
+	}
 
-			// Validate extension.
-			if ext != "cql" && ext != "sql" {
-				return fmt.Errorf("invalid extension: %s (must be cql or sql)", ext)
-			}
+	cmd.AddSubcommands(configured(
+		lockStatusCmd(),
+		lockForceReleaseCmd(),
+	)...)
 
-			// Ensure migrations directory exists.
-			if err := os.MkdirAll(cfg.dir, migrationsDirMode); err != nil {
-				return fmt.Errorf("failed to create migrations directory: %w", err)
-			}
+	return cmd
+}
 
-			// Find the next version number.
-			nextVersion, err := findNextVersion(cfg.dir)
+func lockStatusCmd() *scotty.Command {
+	return &scotty.Command{
+		Name:  "status",
+		Short: "Show the migration lock holder",
+		Long:  "Display the process currently holding the migration lock.",
+		Run: func(_ *scotty.Command, _ []string) error {
+			migrator, err := createMigrator()
 			if err != nil {
 				return err
 			}
+			defer migrator.Close()
 
-			// Create filenames.
-			upFile := fmt.Sprintf("%06d_%s.up.%s", nextVersion, name, ext)
-			downFile := fmt.Sprintf("%06d_%s.down.%s", nextVersion, name, ext)
-
-			upPath := filepath.Join(cfg.dir, upFile)
-			downPath := filepath.Join(cfg.dir, downFile)
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout)
+			defer cancel()
 
-			// Create up migration file.
-			if err := os.WriteFile(upPath, []byte("-- Migration: "+name+" (up)\n\n"), migrationFileMode); err != nil {
-				return fmt.Errorf("failed to create up migration: %w", err)
+			info, err := migrator.LockStatus(ctx)
+			if err != nil {
+				return err
 			}
 
-			// Create down migration file.
-			if err := os.WriteFile(downPath, []byte("-- Migration: "+name+" (down)\n\n"), migrationFileMode); err != nil {
-				return fmt.Errorf("failed to create down migration: %w", err)
+			if info == nil {
+				fmt.Println("Migration lock is free")
+
+				return nil
 			}
 
-			fmt.Println("Created migration files:")
-			fmt.Printf("  %s\n", upPath)
-			fmt.Printf("  %s\n", downPath)
+			fmt.Println("Migration lock is held:")
+			fmt.Printf("  Holder:      %s\n", info.Holder)
+			fmt.Printf("  Host:        %s\n", info.Host)
+			fmt.Printf("  PID:         %d\n", info.PID)
+			fmt.Printf("  Acquired at: %s\n", info.AcquiredAt.Format(time.RFC3339))
+			fmt.Printf("  Expires at:  %s\n", info.ExpiresAt.Format(time.RFC3339))
 
 			return nil
 		},
 	}
 }
 
-func versionCmd() *scotty.Command {
+func lockForceReleaseCmd() *scotty.Command {
 	return &scotty.Command{
-		Name:  "version",
-		Short: "Show current migration version",
-		Long:  "Display the current migration version number.",
+		Name:  "force-release",
+		Short: "Forcibly release the migration lock",
+		Long:  "Remove the migration lock regardless of its holder. Only use it when the holder is known to be dead.",
 		Run: func(_ *scotty.Command, _ []string) error {
 			migrator, err := createMigrator()
 			if err != nil {
@@ -309,70 +1108,95 @@ func versionCmd() *scotty.Command {
 			ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout)
 			defer cancel()
 
-			version, err := migrator.Version(ctx)
-			if err != nil {
+			if err := migrator.ForceReleaseLock(ctx); err != nil {
 				return err
 			}
 
-			if version == 0 {
-				fmt.Println("No migrations applied")
-			} else {
-				fmt.Printf("Current version: %d\n", version)
-			}
+			fmt.Println("Migration lock released")
 
 			return nil
 		},
 	}
 }
 
+// confirmDestructive asks on the terminal whether destructive statements may run.
+// Without a terminal on stdin it refuses, so -yes is required in pipelines.
+func confirmDestructive(_ context.Context, statements []scyllamigrate.DestructiveStatement) (bool, error) {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		fmt.Fprintln(os.Stderr, "Refusing to run destructive statements without a terminal (use -yes to allow them)")
+		return false, nil
+	}
+
+	return promptDestructive(os.Stdin, os.Stderr, statements)
+}
+
+// promptDestructive lists the destructive statements on out and reads the answer from in.
+// Only "y" or "yes" confirms.
+func promptDestructive(in io.Reader, out io.Writer, statements []scyllamigrate.DestructiveStatement) (bool, error) {
+	fmt.Fprintln(out, "The following statements delete schema or data:")
+
+	for _, s := range statements {
+		fmt.Fprintf(out, "  %s:%d (%s): %s\n", s.File, s.Line, s.Direction, s.Text)
+	}
+
+	fmt.Fprint(out, "Run them? [y/N]: ")
+
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, fmt.Errorf("failed to read confirmation: %w", err)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
 // createMigrator creates a new Migrator instance with the configured options.
+// Extra options are applied after the configured ones.
 // Returns a managed migrator with a cleanup hook.
-func createMigrator() (*managedMigrator, error) {
+func createMigrator(opts ...scyllamigrate.Option) (*managedMigrator, error) {
 	if cfg.keyspace == "" {
 		return nil, errors.New("keyspace is required (use -keyspace or SCYLLA_KEYSPACE)")
 	}
 
-	// Parse hosts.
-	hostList := strings.Split(cfg.hosts, ",")
-	for i := range hostList {
-		hostList[i] = strings.TrimSpace(hostList[i])
-	}
-
-	// Create cluster configuration.
-	cluster := gocql.NewCluster(hostList...)
-	cluster.Keyspace = cfg.keyspace
-	cluster.Consistency = parseConsistency(cfg.consistency)
-	cluster.Timeout = cfg.timeout
-
-	// Configure datacenter-aware routing if datacenter is specified.
-	if cfg.datacenter != "" {
-		cluster.PoolConfig.HostSelectionPolicy = gocql.TokenAwareHostPolicy(
-			gocql.DCAwareRoundRobinPolicy(cfg.datacenter),
-		)
+	dirtyPolicy, err := parseDirtyPolicy(cfg.dirty)
+	if err != nil {
+		return nil, err
 	}
 
-	// Configure authentication if username and password are provided.
-	if cfg.username != "" && cfg.password != "" {
-		cluster.Authenticator = gocql.PasswordAuthenticator{
-			Username: cfg.username,
-			Password: cfg.password,
-		}
+	outOfOrderPolicy, err := parseOutOfOrderPolicy(cfg.outOfOrder)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create session.
-	session, err := cluster.CreateSession()
+	session, err := createSession(cfg.keyspace)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to ScyllaDB: %w", err)
+		return nil, err
 	}
 
 	// Create migrator.
-	migrator, err := scyllamigrate.New(session,
+	options := []scyllamigrate.Option{
 		scyllamigrate.WithDir(cfg.dir),
 		scyllamigrate.WithKeyspace(cfg.keyspace),
 		scyllamigrate.WithHistoryTable(cfg.table),
 		scyllamigrate.WithConsistency(parseConsistency(cfg.consistency)),
+		scyllamigrate.WithLockTTL(cfg.lockTTL),
+		scyllamigrate.WithLockTimeout(cfg.lockTimeout),
+		scyllamigrate.WithDirtyPolicy(dirtyPolicy),
+		scyllamigrate.WithOutOfOrderPolicy(outOfOrderPolicy),
+		scyllamigrate.WithStatementTimeout(cfg.statementTimeout),
+		scyllamigrate.WithMigrationTimeout(cfg.migrationTimeout),
+		scyllamigrate.WithSchemaAgreementTimeout(cfg.schemaAgreementTimeout),
+		scyllamigrate.WithAllowDestructive(cfg.yes),
+		scyllamigrate.WithDestructiveConfirmation(confirmDestructive),
 		scyllamigrate.WithStdLogger(nil), // Use default logger.
-	)
+	}
+
+	migrator, err := scyllamigrate.New(session, append(options, opts...)...)
 	if err != nil {
 		session.Close()
 		return nil, err
@@ -413,6 +1237,34 @@ func parseConsistency(s string) gocql.Consistency {
 	return gocql.Quorum
 }
 
+// parseDirtyPolicy converts a string to scyllamigrate.DirtyPolicy.
+func parseDirtyPolicy(s string) (scyllamigrate.DirtyPolicy, error) {
+	switch strings.ToLower(s) {
+	case "", "resume":
+		return scyllamigrate.DirtyResume, nil
+	case "restart":
+		return scyllamigrate.DirtyRestart, nil
+	case "fail":
+		return scyllamigrate.DirtyFail, nil
+	}
+
+	return 0, fmt.Errorf("invalid dirty policy %q (must be resume, restart or fail)", s)
+}
+
+// parseOutOfOrderPolicy converts a string to scyllamigrate.OutOfOrderPolicy.
+func parseOutOfOrderPolicy(s string) (scyllamigrate.OutOfOrderPolicy, error) {
+	switch strings.ToLower(s) {
+	case "", "allow":
+		return scyllamigrate.OutOfOrderAllow, nil
+	case "strict":
+		return scyllamigrate.OutOfOrderStrict, nil
+	case "ignore":
+		return scyllamigrate.OutOfOrderIgnore, nil
+	}
+
+	return 0, fmt.Errorf("invalid out-of-order policy %q (must be allow, strict or ignore)", s)
+}
+
 func createKeyspaceCmd() *scotty.Command {
 	var (
 		replicationFactor int
@@ -447,36 +1299,10 @@ Examples:
 				return errors.New("keyspace is required (use -keyspace or SCYLLA_KEYSPACE)")
 			}
 
-			// Parse hosts.
-			hostList := strings.Split(cfg.hosts, ",")
-			for i := range hostList {
-				hostList[i] = strings.TrimSpace(hostList[i])
-			}
-
-			// Create cluster configuration without keyspace.
-			cluster := gocql.NewCluster(hostList...)
-			cluster.Consistency = parseConsistency(cfg.consistency)
-			cluster.Timeout = cfg.timeout
-
-			// Configure datacenter-aware routing if datacenter is specified.
-			if cfg.datacenter != "" {
-				cluster.PoolConfig.HostSelectionPolicy = gocql.TokenAwareHostPolicy(
-					gocql.DCAwareRoundRobinPolicy(cfg.datacenter),
-				)
-			}
-
-			// Configure authentication if username and password are provided.
-			if cfg.username != "" && cfg.password != "" {
-				cluster.Authenticator = gocql.PasswordAuthenticator{
-					Username: cfg.username,
-					Password: cfg.password,
-				}
-			}
-
-			// Create session.
-			session, err := cluster.CreateSession()
+			// Connect without a keyspace, since it does not exist yet.
+			session, err := createSession("")
 			if err != nil {
-				return fmt.Errorf("failed to connect to ScyllaDB: %w", err)
+				return err
 			}
 			defer session.Close()
 
@@ -552,32 +1378,187 @@ func parseNetworkTopology(s string) (map[string]int, error) {
 	return result, nil
 }
 
-// findNextVersion scans the migrations directory and returns the next version number.
-func findNextVersion(dir string) (uint64, error) {
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return 1, nil
-		}
+// Version formats of the create command.
+const (
+	versionFormatSequential = "sequential"
+	versionFormatTimestamp  = "timestamp"
+
+	// defaultSeqDigits is the default number of digits of sequential versions.
+	defaultSeqDigits = 6
+)
+
+// migrationFilename builds the filename of a migration. Sequential versions are
+// zero-padded to digits, timestamp-based versions are used as is.
+func migrationFilename(version uint64, digits int, name string, direction scyllamigrate.Direction, ext string) string {
+	if scyllamigrate.IsTimestampVersion(version) {
+		return fmt.Sprintf("%d_%s.%s.%s", version, name, direction, ext)
+	}
+
+	return fmt.Sprintf("%0*d_%s.%s.%s", digits, version, name, direction, ext)
+}
 
+// findNextTimestampVersion returns the timestamp-based version of now, moved
+// forward by whole seconds until it is greater than every version in the directory.
+func findNextTimestampVersion(dir string, now time.Time) (uint64, error) {
+	next, err := findNextVersion(dir)
+	if err != nil {
 		return 0, err
 	}
 
-	var maxVersion uint64
+	for scyllamigrate.TimestampVersion(now) < next {
+		now = now.Add(time.Second)
+	}
+
+	return scyllamigrate.TimestampVersion(now), nil
+}
+
+// migrationFile is a migration file found in the migrations directory.
+type migrationFile struct {
+	name      string
+	migration *scyllamigrate.Migration
+}
+
+// scanMigrationFiles returns every migration file of the directory and its nested
+// directories, sorted by version and description. Unlike the migration source it
+// keeps all files sharing a version. File names are relative to dir.
+func scanMigrationFiles(dir string) ([]migrationFile, error) {
+	var files []migrationFile
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
 
-	for _, entry := range entries {
 		if entry.IsDir() {
-			continue
+			return nil
 		}
 
 		m, err := scyllamigrate.ParseMigration(entry.Name())
 		if err != nil {
-			continue
+			return nil
+		}
+
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, migrationFile{name: name, migration: m})
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		a, b := files[i].migration, files[j].migration
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+
+		return a.Description < b.Description
+	})
+
+	return files, nil
+}
+
+// rename is a file rename planned by the renumber command.
+type rename struct {
+	from string
+	to   string
+}
+
+// planRenumber returns the renames that move pending sequential migrations onto the tip.
+// applied maps the applied versions to their descriptions. A migration is pending
+// unless its version was applied with the same description. A pending sequential
+// migration moves if its version is not greater than the current version or is
+// shared with another migration. Moved migrations keep their order.
+func planRenumber(files []migrationFile, applied map[uint64]string, digits int) []rename {
+	type key struct {
+		version     uint64
+		description string
+	}
+
+	var (
+		keys        []key
+		byKey       = make(map[key][]migrationFile)
+		perVersion  = make(map[uint64]int)
+		current     uint64
+		tip         uint64
+		moving      []key
+		movingByKey = make(map[key]bool)
+	)
+
+	for _, f := range files {
+		k := key{version: f.migration.Version, description: f.migration.Description}
+
+		if _, ok := byKey[k]; !ok {
+			keys = append(keys, k)
+			perVersion[k.version]++
+		}
+
+		byKey[k] = append(byKey[k], f)
+	}
+
+	for version := range applied {
+		current = max(current, version)
+	}
+
+	for _, k := range keys {
+		description, isApplied := applied[k.version]
+		pending := !isApplied || description != k.description
+
+		if pending && !scyllamigrate.IsTimestampVersion(k.version) &&
+			(k.version <= current || perVersion[k.version] > 1) {
+			moving = append(moving, k)
+			movingByKey[k] = true
+		}
+	}
+
+	for _, k := range keys {
+		if !movingByKey[k] {
+			tip = max(tip, k.version)
+		}
+	}
+
+	var renames []rename
+
+	for _, k := range moving {
+		tip++
+
+		for _, f := range byKey[k] {
+			m := f.migration
+			ext := strings.TrimPrefix(filepath.Ext(f.name), ".")
+
+			to := filepath.Join(filepath.Dir(f.name), migrationFilename(tip, digits, m.Description, m.Direction, ext))
+			if to == f.name {
+				continue
+			}
+
+			renames = append(renames, rename{from: f.name, to: to})
 		}
+	}
+
+	return renames
+}
 
-		if m.Version > maxVersion {
-			maxVersion = m.Version
+// findNextVersion scans the migrations directory and its nested directories
+// and returns the next version number.
+func findNextVersion(dir string) (uint64, error) {
+	files, err := scanMigrationFiles(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 1, nil
 		}
+
+		return 0, err
+	}
+
+	var maxVersion uint64
+
+	for _, f := range files {
+		maxVersion = max(maxVersion, f.migration.Version)
 	}
 
 	return maxVersion + 1, nil
@@ -1,8 +1,14 @@
 package main
 
 import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/heartwilltell/scyllamigrate"
 	td "github.com/maxatome/go-testdeep/td"
 )
 
@@ -138,3 +144,437 @@ func TestParseConsistency(t *testing.T) {
 		})
 	}
 }
+
+func TestParseDirtyPolicy(t *testing.T) {
+	type tcase struct {
+		input       string
+		expected    scyllamigrate.DirtyPolicy
+		expectError bool
+	}
+
+	tests := map[string]tcase{
+		"resume":        {input: "resume", expected: scyllamigrate.DirtyResume},
+		"empty":         {input: "", expected: scyllamigrate.DirtyResume},
+		"restart":       {input: "restart", expected: scyllamigrate.DirtyRestart},
+		"fail":          {input: "fail", expected: scyllamigrate.DirtyFail},
+		"uppercase":     {input: "FAIL", expected: scyllamigrate.DirtyFail},
+		"invalid value": {input: "skip", expectError: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := parseDirtyPolicy(tc.input)
+
+			if tc.expectError {
+				td.CmpError(t, err)
+				return
+			}
+
+			td.CmpNoError(t, err)
+			td.Cmp(t, result, tc.expected)
+		})
+	}
+}
+
+func TestParseOutOfOrderPolicy(t *testing.T) {
+	type tcase struct {
+		input       string
+		expected    scyllamigrate.OutOfOrderPolicy
+		expectError bool
+	}
+
+	tests := map[string]tcase{
+		"allow":         {input: "allow", expected: scyllamigrate.OutOfOrderAllow},
+		"empty":         {input: "", expected: scyllamigrate.OutOfOrderAllow},
+		"strict":        {input: "strict", expected: scyllamigrate.OutOfOrderStrict},
+		"ignore":        {input: "ignore", expected: scyllamigrate.OutOfOrderIgnore},
+		"uppercase":     {input: "STRICT", expected: scyllamigrate.OutOfOrderStrict},
+		"invalid value": {input: "skip", expectError: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := parseOutOfOrderPolicy(tc.input)
+
+			if tc.expectError {
+				td.CmpError(t, err)
+				return
+			}
+
+			td.CmpNoError(t, err)
+			td.Cmp(t, result, tc.expected)
+		})
+	}
+}
+
+func TestFormatPlan(t *testing.T) {
+	plan := []*scyllamigrate.PlannedMigration{
+		{
+			Version:       1,
+			Description:   "create_users",
+			Direction:     scyllamigrate.Up,
+			File:          "000001_create_users.up.cql",
+			FromStatement: 2,
+			Statements: []scyllamigrate.Statement{
+				{Text: "CREATE INDEX ON users (email)", Line: 4},
+			},
+		},
+		{
+			Version:       2,
+			Description:   "backfill",
+			Direction:     scyllamigrate.Up,
+			Go:            true,
+			FromStatement: 1,
+		},
+	}
+
+	expected := "-- [1] create_users (up) from 000001_create_users.up.cql\n" +
+		"-- resuming at statement 2\n" +
+		"CREATE INDEX ON users (email);\n" +
+		"\n" +
+		"-- [2] backfill (up)\n" +
+		"-- Go migration, no CQL to show\n"
+
+	td.Cmp(t, formatPlan(plan), expected)
+}
+
+func TestMigrationFilename(t *testing.T) {
+	type tcase struct {
+		version  uint64
+		digits   int
+		expected string
+	}
+
+	tests := map[string]tcase{
+		"sequential default digits": {
+			version:  7,
+			digits:   6,
+			expected: "000007_add_users.up.cql",
+		},
+		"sequential custom digits": {
+			version:  7,
+			digits:   3,
+			expected: "007_add_users.up.cql",
+		},
+		"sequential wider than digits": {
+			version:  12345,
+			digits:   3,
+			expected: "12345_add_users.up.cql",
+		},
+		"timestamp": {
+			version:  20260102150405,
+			digits:   6,
+			expected: "20260102150405_add_users.up.cql",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			td.Cmp(t, migrationFilename(tc.version, tc.digits, "add_users", scyllamigrate.Up, "cql"), tc.expected)
+		})
+	}
+}
+
+func TestFindNextTimestampVersion(t *testing.T) {
+	now := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	type tcase struct {
+		files    []string
+		expected uint64
+	}
+
+	tests := map[string]tcase{
+		"empty directory": {
+			expected: 20260102150405,
+		},
+		"older migrations": {
+			files:    []string{"000001_init.up.cql", "20250101000000_users.up.cql"},
+			expected: 20260102150405,
+		},
+		"same second": {
+			files:    []string{"20260102150405_users.up.cql"},
+			expected: 20260102150406,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			for _, f := range tc.files {
+				td.CmpNoError(t, os.WriteFile(filepath.Join(dir, f), nil, migrationFileMode))
+			}
+
+			version, err := findNextTimestampVersion(dir, now)
+			td.CmpNoError(t, err)
+			td.Cmp(t, version, tc.expected)
+		})
+	}
+}
+
+func TestFindNextVersion(t *testing.T) {
+	type tcase struct {
+		files    []string
+		expected uint64
+	}
+
+	tests := map[string]tcase{
+		"empty directory": {
+			expected: 1,
+		},
+		"nested directories": {
+			files:    []string{"000001_init.up.cql", "2025/000004_users.up.cql", "2024/000002_orders.up.cql"},
+			expected: 5,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			for _, f := range tc.files {
+				td.CmpNoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, f)), 0o755))
+				td.CmpNoError(t, os.WriteFile(filepath.Join(dir, f), nil, migrationFileMode))
+			}
+
+			version, err := findNextVersion(dir)
+			td.CmpNoError(t, err)
+			td.Cmp(t, version, tc.expected)
+		})
+	}
+
+	t.Run("missing directory", func(t *testing.T) {
+		version, err := findNextVersion(filepath.Join(t.TempDir(), "missing"))
+		td.CmpNoError(t, err)
+		td.Cmp(t, version, uint64(1))
+	})
+}
+
+func TestPlanRenumber(t *testing.T) {
+	type tcase struct {
+		files    []string
+		applied  map[uint64]string
+		expected []rename
+	}
+
+	tests := map[string]tcase{
+		"nothing pending": {
+			files:   []string{"000001_init.up.cql", "000001_init.down.cql"},
+			applied: map[uint64]string{1: "init"},
+		},
+		"pending on the tip": {
+			files:   []string{"000001_init.up.cql", "000002_users.up.cql"},
+			applied: map[uint64]string{1: "init"},
+		},
+		"conflicting version after rebase": {
+			files: []string{
+				"000001_init.up.cql",
+				"000002_orders.up.cql", "000002_orders.down.cql",
+				"000002_users.up.cql", "000002_users.down.cql",
+			},
+			applied: map[uint64]string{1: "init", 2: "orders"},
+			expected: []rename{
+				{from: "000002_users.down.cql", to: "000003_users.down.cql"},
+				{from: "000002_users.up.cql", to: "000003_users.up.cql"},
+			},
+		},
+		"pending older than current": {
+			files: []string{
+				"000001_init.up.cql",
+				"000002_users.up.cql",
+				"000003_orders.up.cql",
+				"000004_items.up.cql",
+			},
+			applied: map[uint64]string{1: "init", 3: "orders"},
+			expected: []rename{
+				{from: "000002_users.up.cql", to: "000005_users.up.cql"},
+			},
+		},
+		"unapplied conflict keeps order": {
+			files: []string{
+				"000001_init.up.cql",
+				"000002_a.up.cql",
+				"000002_b.up.cql",
+			},
+			applied: map[uint64]string{1: "init"},
+			expected: []rename{
+				{from: "000002_b.up.cql", to: "000003_b.up.cql"},
+			},
+		},
+		"timestamp versions are kept": {
+			files: []string{
+				"20250101000000_init.up.cql",
+				"20240101000000_users.up.cql",
+			},
+			applied: map[uint64]string{20250101000000: "init"},
+		},
+		"nested directories": {
+			files: []string{
+				"2024/000001_init.up.cql",
+				"2025/000002_a.up.cql",
+				"2025/q2/000002_b.up.cql",
+			},
+			applied: map[uint64]string{1: "init"},
+			expected: []rename{
+				{from: "2025/q2/000002_b.up.cql", to: "2025/q2/000003_b.up.cql"},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			for _, f := range tc.files {
+				td.CmpNoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, f)), 0o755))
+				td.CmpNoError(t, os.WriteFile(filepath.Join(dir, f), nil, migrationFileMode))
+			}
+
+			files, err := scanMigrationFiles(dir)
+			td.CmpNoError(t, err)
+
+			td.Cmp(t, planRenumber(files, tc.applied, 6), tc.expected)
+		})
+	}
+}
+
+func TestPrintValidationReport(t *testing.T) {
+	report := &scyllamigrate.ValidationReport{Issues: []scyllamigrate.Issue{
+		{
+			Kind:     scyllamigrate.IssueMissingDown,
+			Severity: scyllamigrate.SeverityWarning,
+			Version:  1,
+			File:     "000001_init.up.cql",
+			Message:  "up migration has no down migration",
+		},
+	}}
+
+	t.Run("text", func(t *testing.T) {
+		var b strings.Builder
+
+		td.CmpNoError(t, printValidationReport(&b, report, outputTable))
+		td.Cmp(t, b.String(), "000001_init.up.cql: warning: up migration has no down migration (missing_down)\n"+
+			"0 error(s), 1 warning(s)\n")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var b strings.Builder
+
+		td.CmpNoError(t, printValidationReport(&b, report, outputJSON))
+		td.Cmp(t, json.RawMessage(b.String()), td.JSON(`{
+			"issues": [{
+				"kind": "missing_down",
+				"severity": "warning",
+				"version": 1,
+				"file": "000001_init.up.cql",
+				"message": "up migration has no down migration"
+			}]
+		}`))
+	})
+}
+
+func TestParseLintSeverities(t *testing.T) {
+	type tcase struct {
+		input    string
+		expected int
+		errorMsg string
+	}
+
+	tests := map[string]tcase{
+		"empty":    {input: "", expected: 0},
+		"single":   {input: "drop_table=off", expected: 1},
+		"multiple": {input: "drop_table=off, create_index = ERROR,", expected: 2},
+		"missing severity": {
+			input:    "drop_table",
+			errorMsg: `invalid lint severity "drop_table" (expected rule=severity)`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts, err := parseLintSeverities(tc.input)
+			if tc.errorMsg != "" {
+				td.CmpString(t, err, tc.errorMsg)
+				return
+			}
+
+			td.CmpNoError(t, err)
+			td.Cmp(t, len(opts), tc.expected)
+		})
+	}
+}
+
+func TestPromptDestructive(t *testing.T) {
+	statements := []scyllamigrate.DestructiveStatement{{
+		Version:   3,
+		Direction: scyllamigrate.Down,
+		File:      "000003_users.down.cql",
+		Line:      1,
+		Text:      "DROP TABLE users",
+	}}
+
+	type tcase struct {
+		input    string
+		expected bool
+	}
+
+	tests := map[string]tcase{
+		"yes":         {input: "yes\n", expected: true},
+		"y uppercase": {input: "Y\n", expected: true},
+		"no":          {input: "n\n", expected: false},
+		"empty":       {input: "\n", expected: false},
+		"eof":         {input: "", expected: false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var out strings.Builder
+
+			confirmed, err := promptDestructive(strings.NewReader(tc.input), &out, statements)
+			td.CmpNoError(t, err)
+			td.Cmp(t, confirmed, tc.expected)
+			td.Cmp(t, out.String(), "The following statements delete schema or data:\n"+
+				"  000003_users.down.cql:1 (down): DROP TABLE users\n"+
+				"Run them? [y/N]: ")
+		})
+	}
+}
+
+func TestValidateCmd(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, content := range map[string]string{
+		"2024/000001_users.up.cql":   "CREATE TABLE users (id int PRIMARY KEY);",
+		"2025/000001_users.down.cql": "DROP TABLE users;",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		td.Require(t).CmpNoError(os.MkdirAll(filepath.Dir(path), 0o755))
+		td.Require(t).CmpNoError(os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	setConfig(t, config{dir: dir, output: outputJSON})
+
+	stdout, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	td.Require(t).CmpNoError(err)
+
+	saved := os.Stdout
+	os.Stdout = stdout
+	t.Cleanup(func() { os.Stdout = saved })
+
+	// Versions in two directories are reported instead of failing to open the directory.
+	cmd := validateCmd()
+	td.Cmp(t, cmd.Run(cmd, nil), td.ErrorIs(td.Contains("validation failed with 1 error(s)")))
+
+	out, err := os.ReadFile(stdout.Name())
+	td.Require(t).CmpNoError(err)
+
+	var report scyllamigrate.ValidationReport
+
+	td.Require(t).CmpNoError(json.Unmarshal(out, &report))
+	td.Cmp(t, report.Issues, []scyllamigrate.Issue{{
+		Kind:     scyllamigrate.IssueDuplicateVersion,
+		Severity: scyllamigrate.SeverityError,
+		Version:  1,
+		Message:  "version is used by several migrations: 2024/000001_users.up.cql, 2025/000001_users.down.cql",
+	}})
+}
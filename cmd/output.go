@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/heartwilltell/scyllamigrate"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats of the -output flag.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// Exit codes of the CLI. Codes other than exitNoChange are stable across releases.
+const (
+	exitOK              = 0
+	exitError           = 1
+	exitNoChange        = 2 // only with -detailed-exitcode.
+	exitLocked          = 3
+	exitDirty           = 4
+	exitRefused         = 5
+	exitMigrationFailed = 6
+)
+
+// Statuses of a run result.
+const (
+	runSuccess  = "success"
+	runNoChange = "no_change"
+	runFailed   = "failed"
+)
+
+// parseOutputFormat validates the value of the -output flag.
+func parseOutputFormat(s string) (string, error) {
+	switch strings.ToLower(s) {
+	case outputTable, "":
+		return outputTable, nil
+	case outputJSON:
+		return outputJSON, nil
+	case outputYAML, "yml":
+		return outputYAML, nil
+	default:
+		return "", fmt.Errorf("invalid output format: %s (must be table, json or yaml)", s)
+	}
+}
+
+// exitCode maps the error returned by a command to the exit code of the process.
+// A run with nothing to do exits with exitNoChange only if detailed is set.
+// Invalid flags exit with exitError.
+func exitCode(err error, detailed bool) int {
+	var (
+		migrationErr *scyllamigrate.MigrationError
+		hookErr      *scyllamigrate.HookError
+	)
+
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, scyllamigrate.ErrNoChange):
+		if detailed {
+			return exitNoChange
+		}
+
+		return exitOK
+	case errors.Is(err, scyllamigrate.ErrLocked), errors.Is(err, scyllamigrate.ErrLockLost):
+		return exitLocked
+	case errors.Is(err, scyllamigrate.ErrDirty):
+		return exitDirty
+	case errors.Is(err, scyllamigrate.ErrChecksumMismatch),
+		errors.Is(err, scyllamigrate.ErrOutOfOrder),
+		errors.Is(err, scyllamigrate.ErrMissingVersion),
+		errors.Is(err, scyllamigrate.ErrDestructive):
+		return exitRefused
+	case errors.As(err, &migrationErr), errors.As(err, &hookErr):
+		return exitMigrationFailed
+	default:
+		return exitError
+	}
+}
+
+// writeOutput writes v as JSON or YAML, or calls table to render it for humans.
+func writeOutput(w io.Writer, format string, v any, table func(w io.Writer)) error {
+	switch format {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(v)
+	case outputYAML:
+		return writeYAML(w, v)
+	default:
+		table(w)
+		return nil
+	}
+}
+
+// joinVersions formats versions as a comma-separated list.
+func joinVersions(versions []uint64) string {
+	s := make([]string, 0, len(versions))
+
+	for _, v := range versions {
+		s = append(s, strconv.FormatUint(v, 10))
+	}
+
+	return strings.Join(s, ", ")
+}
+
+// runResult is the output of up, down and goto.
+type runResult struct {
+	Status         string              `json:"status"`
+	Migrations     []executedMigration `json:"migrations"`
+	Skipped        []uint64            `json:"skipped"`
+	CurrentVersion uint64              `json:"current_version"`
+	Error          string              `json:"error,omitempty"`
+}
+
+// executedMigration is a migration executed by a run.
+type executedMigration struct {
+	Version     uint64 `json:"version"`
+	Description string `json:"description"`
+	Direction   string `json:"direction"`
+	DurationMs  int64  `json:"duration_ms"`
+}
+
+// statusOutput is the output of status.
+type statusOutput struct {
+	Keyspace       string          `json:"keyspace"`
+	CurrentVersion uint64          `json:"current_version"`
+	Dirty          *dirtyOutput    `json:"dirty"`
+	Applied        []appliedOutput `json:"applied"`
+	Pending        []pendingOutput `json:"pending"`
+}
+
+// dirtyOutput describes a migration that failed partway through.
+type dirtyOutput struct {
+	Version   uint64    `json:"version"`
+	Direction string    `json:"direction"`
+	Statement int       `json:"statement"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// appliedOutput is an applied migration in the output of status.
+// Modified reports checksum drift: the file changed after it was applied.
+type appliedOutput struct {
+	Version     uint64    `json:"version"`
+	Description string    `json:"description"`
+	Checksum    string    `json:"checksum"`
+	AppliedAt   time.Time `json:"applied_at"`
+	ExecutionMs int64     `json:"execution_ms"`
+	Baselined   bool      `json:"baselined"`
+	Modified    bool      `json:"modified"`
+}
+
+// pendingOutput is a pending migration in the output of status.
+type pendingOutput struct {
+	Version     uint64 `json:"version"`
+	Description string `json:"description"`
+	OutOfOrder  bool   `json:"out_of_order"`
+}
+
+// versionOutput is the output of version.
+type versionOutput struct {
+	CurrentVersion uint64 `json:"current_version"`
+}
+
+// planOutput is a migration of a dry run.
+type planOutput struct {
+	Version       uint64            `json:"version"`
+	Description   string            `json:"description"`
+	Direction     string            `json:"direction"`
+	File          string            `json:"file,omitempty"`
+	Go            bool              `json:"go"`
+	FromStatement int               `json:"from_statement"`
+	Statements    []statementOutput `json:"statements"`
+}
+
+// statementOutput is a statement of a dry run.
+type statementOutput struct {
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+// newStatusOutput converts a status and the checksum mismatches found by Verify.
+func newStatusOutput(keyspace string, status *scyllamigrate.Status, drift []scyllamigrate.ChecksumMismatch) *statusOutput {
+	out := &statusOutput{
+		Keyspace:       keyspace,
+		CurrentVersion: status.CurrentVersion,
+		Applied:        make([]appliedOutput, 0, len(status.Applied)),
+		Pending:        make([]pendingOutput, 0, len(status.Pending)),
+	}
+
+	if d := status.Dirty; d != nil {
+		out.Dirty = &dirtyOutput{
+			Version:   d.Version,
+			Direction: d.Direction.String(),
+			Statement: d.Statement,
+			StartedAt: d.StartedAt,
+		}
+	}
+
+	modified := make(map[uint64]bool, len(drift))
+	for _, mm := range drift {
+		modified[mm.Version] = true
+	}
+
+	for _, am := range status.Applied {
+		out.Applied = append(out.Applied, appliedOutput{
+			Version:     am.Version,
+			Description: am.Description,
+			Checksum:    am.Checksum,
+			AppliedAt:   am.AppliedAt,
+			ExecutionMs: am.ExecutionMs,
+			Baselined:   am.Baselined,
+			Modified:    modified[am.Version],
+		})
+	}
+
+	outOfOrder := make(map[uint64]bool, len(status.OutOfOrder))
+	for _, v := range status.OutOfOrder {
+		outOfOrder[v] = true
+	}
+
+	for _, pair := range status.Pending {
+		out.Pending = append(out.Pending, pendingOutput{
+			Version:     pair.Version,
+			Description: pair.Description,
+			OutOfOrder:  outOfOrder[pair.Version],
+		})
+	}
+
+	return out
+}
+
+// newPlanOutput converts a plan.
+func newPlanOutput(plan []*scyllamigrate.PlannedMigration) []planOutput {
+	out := make([]planOutput, 0, len(plan))
+
+	for _, pm := range plan {
+		po := planOutput{
+			Version:       pm.Version,
+			Description:   pm.Description,
+			Direction:     pm.Direction.String(),
+			File:          pm.File,
+			Go:            pm.Go,
+			FromStatement: pm.FromStatement,
+			Statements:    make([]statementOutput, 0, len(pm.Statements)),
+		}
+
+		for _, stmt := range pm.Statements {
+			po.Statements = append(po.Statements, statementOutput{Line: stmt.Line, Text: stmt.Text})
+		}
+
+		out = append(out, po)
+	}
+
+	return out
+}
+
+// writeYAML writes v as YAML. The value is encoded with encoding/json first,
+// so that the YAML output has the same keys, in the same order, as the JSON output.
+func writeYAML(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	// JSON is YAML in flow style, which is rewritten in block style.
+	var doc yaml.Node
+
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	blockStyle(&doc)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
+
+// blockStyle clears the styles of node and its children, so that the encoder
+// writes collections in block style and quotes only the strings that need it.
+// Strings that YAML 1.1 parsers read as booleans are quoted as well.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!str" {
+		switch strings.ToLower(node.Value) {
+		case "yes", "no", "on", "off", "y", "n":
+			node.Style = yaml.DoubleQuotedStyle
+		}
+	}
+
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
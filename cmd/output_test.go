@@ -0,0 +1,269 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/heartwilltell/scyllamigrate"
+	td "github.com/maxatome/go-testdeep/td"
+)
+
+func TestParseOutputFormat(t *testing.T) {
+	type tcase struct {
+		input       string
+		expected    string
+		expectError bool
+	}
+
+	tests := map[string]tcase{
+		"table":      {input: "table", expected: outputTable},
+		"empty":      {input: "", expected: outputTable},
+		"json":       {input: "JSON", expected: outputJSON},
+		"yaml":       {input: "yaml", expected: outputYAML},
+		"yml":        {input: "yml", expected: outputYAML},
+		"unknown":    {input: "xml", expectError: true},
+		"misspelled": {input: "jsn", expectError: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			format, err := parseOutputFormat(tc.input)
+			if tc.expectError {
+				td.CmpError(t, err)
+				return
+			}
+
+			td.CmpNoError(t, err)
+			td.Cmp(t, format, tc.expected)
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	type tcase struct {
+		err      error
+		detailed bool
+		expected int
+	}
+
+	tests := map[string]tcase{
+		"success":                 {err: nil, expected: exitOK},
+		"no change":               {err: scyllamigrate.ErrNoChange, expected: exitOK},
+		"no change detailed":      {err: scyllamigrate.ErrNoChange, detailed: true, expected: exitNoChange},
+		"locked":                  {err: &scyllamigrate.LockError{Err: scyllamigrate.ErrLocked}, expected: exitLocked},
+		"dirty":                   {err: &scyllamigrate.DirtyError{Version: 3}, expected: exitDirty},
+		"checksum":                {err: &scyllamigrate.ChecksumError{}, expected: exitRefused},
+		"out of order":            {err: &scyllamigrate.OutOfOrderError{Current: 2, Versions: []uint64{1}}, expected: exitRefused},
+		"destructive":             {err: &scyllamigrate.DestructiveError{}, expected: exitRefused},
+		"missing":                 {err: &scyllamigrate.MissingMigrationError{Versions: []uint64{4}}, expected: exitRefused},
+		"migration failed":        {err: &scyllamigrate.MigrationError{Version: 3, Err: errors.New("boom")}, expected: exitMigrationFailed},
+		"hook failed":             {err: &scyllamigrate.HookError{Hook: "BeforeMigration", Err: errors.New("no")}, expected: exitMigrationFailed},
+		"wrapped migration error": {err: fmt.Errorf("run: %w", &scyllamigrate.MigrationError{Version: 3}), expected: exitMigrationFailed},
+		"help":                    {err: fmt.Errorf("command failed: %w", flag.ErrHelp), detailed: true, expected: exitOK},
+		"invalid flag":            {err: errors.New("command failed: flag provided but not defined: -bogus"), detailed: true, expected: exitError},
+		"other":                   {err: errors.New("connection refused"), expected: exitError},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			td.Cmp(t, exitCode(tc.err, tc.detailed), tc.expected)
+		})
+	}
+}
+
+func TestNewRunResult(t *testing.T) {
+	result := &scyllamigrate.Result{
+		Executed: []scyllamigrate.ExecutedMigration{
+			{Version: 1, Description: "init", Direction: scyllamigrate.Up, Duration: 12 * time.Millisecond},
+		},
+		Skipped: []uint64{3},
+	}
+
+	executed := []executedMigration{{Version: 1, Description: "init", Direction: "up", DurationMs: 12}}
+
+	type tcase struct {
+		result   *scyllamigrate.Result
+		err      error
+		expected *runResult
+	}
+
+	tests := map[string]tcase{
+		"success": {
+			result:   result,
+			expected: &runResult{Status: runSuccess, Migrations: executed, Skipped: []uint64{3}},
+		},
+		"no change": {
+			result:   &scyllamigrate.Result{},
+			expected: &runResult{Status: runNoChange, Migrations: []executedMigration{}, Skipped: []uint64{}},
+		},
+		"no change error": {
+			result:   &scyllamigrate.Result{},
+			err:      scyllamigrate.ErrNoChange,
+			expected: &runResult{Status: runNoChange, Migrations: []executedMigration{}, Skipped: []uint64{}},
+		},
+		"nil result": {
+			err:      errors.New("connection refused"),
+			expected: &runResult{Status: runFailed, Migrations: []executedMigration{}, Skipped: []uint64{}, Error: "connection refused"},
+		},
+		"failed after one migration": {
+			result:   result,
+			err:      errors.New("boom"),
+			expected: &runResult{Status: runFailed, Migrations: executed, Skipped: []uint64{3}, Error: "boom"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			td.Cmp(t, newRunResult(tc.result, tc.err), tc.expected)
+		})
+	}
+}
+
+func TestNewStatusOutput(t *testing.T) {
+	appliedAt := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	status := &scyllamigrate.Status{
+		CurrentVersion: 2,
+		Applied: []*scyllamigrate.AppliedMigration{
+			{Version: 1, Description: "init", Checksum: "aaa", AppliedAt: appliedAt, ExecutionMs: 5},
+			{Version: 2, Description: "users", Checksum: "bbb", AppliedAt: appliedAt, Baselined: true},
+		},
+		Pending: []*scyllamigrate.MigrationPair{
+			{Version: 1, Description: "late"},
+			{Version: 3, Description: "posts"},
+		},
+		OutOfOrder: []uint64{1},
+	}
+
+	out := newStatusOutput("myapp", status, []scyllamigrate.ChecksumMismatch{{Version: 2}})
+
+	td.Cmp(t, out, &statusOutput{
+		Keyspace:       "myapp",
+		CurrentVersion: 2,
+		Applied: []appliedOutput{
+			{Version: 1, Description: "init", Checksum: "aaa", AppliedAt: appliedAt, ExecutionMs: 5},
+			{Version: 2, Description: "users", Checksum: "bbb", AppliedAt: appliedAt, Baselined: true, Modified: true},
+		},
+		Pending: []pendingOutput{
+			{Version: 1, Description: "late", OutOfOrder: true},
+			{Version: 3, Description: "posts"},
+		},
+	})
+}
+
+func TestWriteOutput(t *testing.T) {
+	v := versionOutput{CurrentVersion: 7}
+
+	type tcase struct {
+		format   string
+		expected string
+	}
+
+	tests := map[string]tcase{
+		"table": {format: outputTable, expected: "table\n"},
+		"json":  {format: outputJSON, expected: "{\n  \"current_version\": 7\n}\n"},
+		"yaml":  {format: outputYAML, expected: "current_version: 7\n"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var b strings.Builder
+
+			err := writeOutput(&b, tc.format, v, func(w io.Writer) { fmt.Fprintln(w, "table") })
+			td.CmpNoError(t, err)
+			td.Cmp(t, b.String(), tc.expected)
+		})
+	}
+}
+
+func TestWriteYAML(t *testing.T) {
+	type tcase struct {
+		input    any
+		expected string
+	}
+
+	tests := map[string]tcase{
+		"run result": {
+			input: &runResult{
+				Status: runSuccess,
+				Migrations: []executedMigration{
+					{Version: 1, Description: "create_users", Direction: "up", DurationMs: 12},
+					{Version: 2, Description: "add index", Direction: "up"},
+				},
+				Skipped:        []uint64{},
+				CurrentVersion: 2,
+			},
+			expected: `status: success
+migrations:
+  - version: 1
+    description: create_users
+    direction: up
+    duration_ms: 12
+  - version: 2
+    description: add index
+    direction: up
+    duration_ms: 0
+skipped: []
+current_version: 2
+`,
+		},
+		"empty collections and null": {
+			input: map[string]any{"a": []int{}, "b": map[string]int{}, "c": nil},
+			expected: `a: []
+b: {}
+c: null
+`,
+		},
+		"quoted strings": {
+			input: []string{"yes", "Off", "", "12", "2024-01-15T10:30:00Z", "key: value", "# comment", "DROP TABLE users", " padded"},
+			expected: `- "yes"
+- "Off"
+- ""
+- "12"
+- "2024-01-15T10:30:00Z"
+- 'key: value'
+- '# comment'
+- DROP TABLE users
+- ' padded'
+`,
+		},
+		"multi-line strings": {
+			input: map[string]string{"statement": "CREATE TABLE users (\n  id int PRIMARY KEY\n);"},
+			expected: `statement: |-
+  CREATE TABLE users (
+    id int PRIMARY KEY
+  );
+`,
+		},
+		"nested arrays": {
+			input: [][]int{{1, 2}, {}},
+			expected: `- - 1
+  - 2
+- []
+`,
+		},
+		"scalar": {
+			input:    42,
+			expected: "42\n",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var b strings.Builder
+
+			td.CmpNoError(t, writeYAML(&b, tc.input))
+			td.Cmp(t, b.String(), tc.expected)
+		})
+	}
+}
+
+func TestJoinVersions(t *testing.T) {
+	td.Cmp(t, joinVersions(nil), "")
+	td.Cmp(t, joinVersions([]uint64{3, 20240115103000}), "3, 20240115103000")
+}
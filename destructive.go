@@ -0,0 +1,171 @@
+package scyllamigrate
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// allowDestructiveDirective allows a migration file to run destructive statements
+// when it starts a line comment, e.g. "-- scyllamigrate:allow-destructive".
+const allowDestructiveDirective = "scyllamigrate:allow-destructive"
+
+// DestructiveStatement is a statement that deletes schema or data,
+// such as DROP, TRUNCATE or ALTER TABLE ... DROP.
+type DestructiveStatement struct {
+	// Version is the version of the migration the statement belongs to.
+	Version uint64
+
+	// Direction is the direction the migration would run in.
+	Direction Direction
+
+	// File is the migration filename.
+	File string
+
+	// Line is the 1-based line where the statement starts in File.
+	Line int
+
+	// Text is the CQL statement.
+	Text string
+}
+
+// ConfirmFunc asks whether the destructive statements of a run may be executed.
+// Returning false refuses the run.
+type ConfirmFunc func(ctx context.Context, statements []DestructiveStatement) (bool, error)
+
+// checkDestructive fails with a *DestructiveError if the selected migrations
+// contain destructive statements that are neither allowed by WithAllowDestructive
+// nor by a directive in their file, unless the confirmation function accepts them.
+// Go migrations cannot be inspected and are not checked.
+func (m *Migrator) checkDestructive(ctx context.Context, selected []*MigrationPair, direction Direction) error {
+	if m.allowDestructive {
+		return nil
+	}
+
+	var statements []DestructiveStatement
+
+	for _, pair := range selected {
+		found, err := m.destructiveStatements(pair, direction)
+		if err != nil {
+			return err
+		}
+
+		statements = append(statements, found...)
+	}
+
+	if len(statements) == 0 {
+		return nil
+	}
+
+	if m.confirmDestructive != nil {
+		confirmed, err := m.confirmDestructive(ctx, statements)
+		if err != nil {
+			return err
+		}
+
+		if confirmed {
+			return nil
+		}
+	}
+
+	return &DestructiveError{Statements: statements}
+}
+
+// destructiveStatements returns the destructive statements of a migration.
+// Migrations that are missing or cannot be split are skipped, since running
+// them fails before any statement is executed.
+func (m *Migrator) destructiveStatements(pair *MigrationPair, direction Direction) ([]DestructiveStatement, error) {
+	migration := pair.Up
+	if direction == Down {
+		migration = pair.Down
+	}
+
+	if migration == nil {
+		return nil, nil
+	}
+
+	if _, ok := m.goMigrations[pair.Version]; ok {
+		return nil, nil
+	}
+
+	content, err := m.readMigrationContent(pair.Version, direction)
+	if err != nil {
+		return nil, err
+	}
+
+	if hasDirective(string(content), allowDestructiveDirective) {
+		return nil, nil
+	}
+
+	statements, err := SplitStatements(string(content))
+	if err != nil {
+		var syntaxErr *SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var found []DestructiveStatement
+
+	for _, stmt := range statements {
+		if !isDestructive(statementTokens(stmt.Text)) {
+			continue
+		}
+
+		found = append(found, DestructiveStatement{
+			Version:   pair.Version,
+			Direction: direction,
+			File:      migration.Raw,
+			Line:      stmt.Line,
+			Text:      stmt.Text,
+		})
+	}
+
+	return found, nil
+}
+
+// isDestructive reports whether a statement deletes schema or data.
+func isDestructive(tokens []string) bool {
+	switch {
+	case hasTokens(tokens, "DROP"), hasTokens(tokens, "TRUNCATE"):
+		return true
+	case hasTokens(tokens, "ALTER", "TABLE"), hasTokens(tokens, "ALTER", "COLUMNFAMILY"):
+		return indexToken(tokens, 2, "DROP") >= 0
+	default:
+		return false
+	}
+}
+
+// directives returns the arguments of every line comment in content that starts with directive.
+func directives(content, directive string) []string {
+	var args []string
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(line, "--"):
+			line = strings.TrimPrefix(line, "--")
+		case strings.HasPrefix(line, "//"):
+			line = strings.TrimPrefix(line, "//")
+		default:
+			continue
+		}
+
+		rest, ok := strings.CutPrefix(strings.TrimSpace(line), directive)
+		if !ok || (rest != "" && !isSpaceByte(rest[0])) {
+			continue
+		}
+
+		args = append(args, strings.TrimSpace(rest))
+	}
+
+	return args
+}
+
+// hasDirective reports whether content has a line comment starting with directive.
+func hasDirective(content, directive string) bool {
+	return len(directives(content, directive)) > 0
+}
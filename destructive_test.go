@@ -0,0 +1,145 @@
+package scyllamigrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	td "github.com/maxatome/go-testdeep/td"
+)
+
+func TestIsDestructive(t *testing.T) {
+	tests := map[string]bool{
+		"DROP TABLE IF EXISTS users":                   true,
+		"drop keyspace legacy":                         true,
+		"DROP INDEX users_email_idx":                   true,
+		"TRUNCATE sessions":                            true,
+		"ALTER TABLE users DROP legacy":                true,
+		"ALTER TABLE users ADD email text":             false,
+		`ALTER TABLE users ADD "drop" text`:            false,
+		"CREATE TABLE users (id int PRIMARY KEY)":      false,
+		"INSERT INTO notes (body) VALUES ('DROP ALL')": false,
+	}
+
+	for stmt, expected := range tests {
+		t.Run(stmt, func(t *testing.T) {
+			td.Cmp(t, isDestructive(statementTokens(stmt)), expected)
+		})
+	}
+}
+
+func TestDirectives(t *testing.T) {
+	type tcase struct {
+		content  string
+		expected []string
+	}
+
+	tests := map[string]tcase{
+		"none": {
+			content: "DROP TABLE users;",
+		},
+		"dash comment": {
+			content:  "-- scyllamigrate:allow-destructive\nDROP TABLE users;",
+			expected: []string{""},
+		},
+		"slash comment with arguments": {
+			content:  "  //scyllamigrate:allow-destructive  reviewed by ops\nDROP TABLE users;",
+			expected: []string{"reviewed by ops"},
+		},
+		"longer directive name": {
+			content: "-- scyllamigrate:allow-destructive-later\nDROP TABLE users;",
+		},
+		"not a comment": {
+			content: "SELECT 'scyllamigrate:allow-destructive' FROM system.local;",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			td.Cmp(t, directives(tc.content, allowDestructiveDirective), tc.expected)
+			td.Cmp(t, hasDirective(tc.content, allowDestructiveDirective), tc.expected != nil)
+		})
+	}
+}
+
+func TestMigrator_checkDestructive(t *testing.T) {
+	fsys := fstest.MapFS{
+		"000001_users.up.cql":    {Data: []byte("CREATE TABLE IF NOT EXISTS users (id int PRIMARY KEY);")},
+		"000001_users.down.cql":  {Data: []byte("\nDROP TABLE IF EXISTS users;")},
+		"000002_legacy.up.cql":   {Data: []byte("-- scyllamigrate:allow-destructive\nDROP TABLE IF EXISTS legacy;")},
+		"000002_legacy.down.cql": {Data: []byte("CREATE TABLE IF NOT EXISTS legacy (id int PRIMARY KEY);")},
+	}
+
+	newMigrator := func(t *testing.T, opts ...Option) *Migrator {
+		t.Helper()
+
+		m := &Migrator{}
+		td.Require(t).CmpNoError(WithFS(fsys)(m))
+
+		for _, opt := range opts {
+			td.Require(t).CmpNoError(opt(m))
+		}
+
+		return m
+	}
+
+	source, err := NewFSSource(fsys)
+	td.Require(t).CmpNoError(err)
+
+	pairs, err := source.List()
+	td.Require(t).CmpNoError(err)
+
+	ctx := context.Background()
+
+	t.Run("up with allow directive", func(t *testing.T) {
+		td.CmpNoError(t, newMigrator(t).checkDestructive(ctx, pairs, Up))
+	})
+
+	t.Run("down", func(t *testing.T) {
+		err := newMigrator(t).checkDestructive(ctx, pairs, Down)
+		td.CmpErrorIs(t, err, ErrDestructive)
+
+		var destructiveErr *DestructiveError
+		td.Require(t).Cmp(errors.As(err, &destructiveErr), true)
+		td.Cmp(t, destructiveErr.Statements, []DestructiveStatement{{
+			Version:   1,
+			Direction: Down,
+			File:      "000001_users.down.cql",
+			Line:      2,
+			Text:      "DROP TABLE IF EXISTS users",
+		}})
+	})
+
+	t.Run("allowed", func(t *testing.T) {
+		td.CmpNoError(t, newMigrator(t, WithAllowDestructive(true)).checkDestructive(ctx, pairs, Down))
+	})
+
+	t.Run("confirmed", func(t *testing.T) {
+		var asked []DestructiveStatement
+
+		m := newMigrator(t, WithDestructiveConfirmation(func(_ context.Context, statements []DestructiveStatement) (bool, error) {
+			asked = statements
+			return true, nil
+		}))
+
+		td.CmpNoError(t, m.checkDestructive(ctx, pairs, Down))
+		td.Cmp(t, len(asked), 1)
+	})
+
+	t.Run("refused", func(t *testing.T) {
+		m := newMigrator(t, WithDestructiveConfirmation(func(context.Context, []DestructiveStatement) (bool, error) {
+			return false, nil
+		}))
+
+		td.CmpErrorIs(t, m.checkDestructive(ctx, pairs, Down), ErrDestructive)
+	})
+
+	t.Run("confirmation error", func(t *testing.T) {
+		m := newMigrator(t, WithDestructiveConfirmation(func(context.Context, []DestructiveStatement) (bool, error) {
+			return false, errors.New("no terminal")
+		}))
+
+		td.CmpString(t, m.checkDestructive(ctx, pairs, Down), "no terminal")
+	})
+}
@@ -2,6 +2,9 @@ package scyllamigrate
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Error represents a custom error type.
@@ -38,6 +41,31 @@ const (
 
 	// ErrNoSession indicates no database session was provided.
 	ErrNoSession Error = "scyllamigrate: no database session provided"
+
+	// ErrNoGocqlSession indicates a Go migration uses the gocql session but the
+	// Migrator's Session is not backed by one.
+	ErrNoGocqlSession Error = "scyllamigrate: go migration requires a gocql session"
+
+	// ErrDuplicateVersion indicates two migrations share the same version.
+	ErrDuplicateVersion Error = "scyllamigrate: duplicate migration version"
+
+	// ErrDirty indicates a migration failed partway through and the schema needs manual repair.
+	ErrDirty Error = "scyllamigrate: database is dirty"
+
+	// ErrLocked indicates the migration lock is held by another process.
+	ErrLocked Error = "scyllamigrate: migration lock is held by another process"
+
+	// ErrLockLost indicates the migration lock was lost while it was held.
+	ErrLockLost Error = "scyllamigrate: migration lock was lost"
+
+	// ErrOutOfOrder indicates pending migrations are older than the current version.
+	ErrOutOfOrder Error = "scyllamigrate: pending migrations are older than the current version"
+
+	// ErrDestructive indicates a run would execute destructive statements without being allowed to.
+	ErrDestructive Error = "scyllamigrate: destructive statements were not allowed"
+
+	// ErrManifestMismatch indicates a migration file of a remote source does not match its checksum manifest.
+	ErrManifestMismatch Error = "scyllamigrate: migration file does not match the manifest"
 )
 
 // ParseError indicates a migration filename could not be parsed.
@@ -63,17 +91,36 @@ type MigrationError struct {
 	Version   uint64
 	Direction Direction
 	Statement int
-	Err       error
+
+	// File is the name of the migration file (may be empty).
+	File string
+
+	// Line is the 1-based line in File where the failing statement starts (0 if unknown).
+	Line int
+
+	Err error
 }
 
 // Error implements the error interface.
 func (e *MigrationError) Error() string {
+	if e.Statement > 0 && e.Line > 0 {
+		return fmt.Sprintf("scyllamigrate: failed to execute %s migration %d (statement %d at %s): %v",
+			e.Direction, e.Version, e.Statement, e.location(), e.Err,
+		)
+	}
+
 	if e.Statement > 0 {
 		return fmt.Sprintf("scyllamigrate: failed to execute %s migration %d (statement %d): %v",
 			e.Direction, e.Version, e.Statement, e.Err,
 		)
 	}
 
+	if e.Line > 0 {
+		return fmt.Sprintf("scyllamigrate: failed to execute %s migration %d (at %s): %v",
+			e.Direction, e.Version, e.location(), e.Err,
+		)
+	}
+
 	return fmt.Sprintf("scyllamigrate: failed to execute %s migration %d: %v",
 		e.Direction, e.Version, e.Err,
 	)
@@ -82,6 +129,29 @@ func (e *MigrationError) Error() string {
 // Unwrap returns the underlying error.
 func (e *MigrationError) Unwrap() error { return e.Err }
 
+// location formats the file and line of the failing statement.
+func (e *MigrationError) location() string {
+	if e.File == "" {
+		return fmt.Sprintf("line %d", e.Line)
+	}
+
+	return fmt.Sprintf("%s:%d", e.File, e.Line)
+}
+
+// SyntaxError indicates migration content could not be split into statements.
+type SyntaxError struct {
+	// Line is the 1-based line where the offending token starts.
+	Line int
+
+	// Msg describes the problem.
+	Msg string
+}
+
+// Error implements the error interface.
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("scyllamigrate: syntax error at line %d: %s", e.Line, e.Msg)
+}
+
 // SourceError wraps an error that occurred while reading from a migration source.
 type SourceError struct {
 	Version uint64
@@ -111,3 +181,210 @@ func (e *KeyspaceError) Error() string {
 
 // Unwrap returns the underlying error.
 func (e *KeyspaceError) Unwrap() error { return e.Err }
+
+// ChecksumMismatch describes an applied migration whose source content no longer
+// matches the checksum recorded in the history table.
+type ChecksumMismatch struct {
+	// Version is the migration version.
+	Version uint64
+
+	// Recorded is the checksum stored in the history table.
+	Recorded string
+
+	// Actual is the checksum computed from the current source content.
+	Actual string
+}
+
+// ChecksumError indicates that one or more applied migrations were modified
+// after being applied.
+type ChecksumError struct {
+	Mismatches []ChecksumMismatch
+}
+
+// Error implements the error interface.
+func (e *ChecksumError) Error() string {
+	versions := make([]string, 0, len(e.Mismatches))
+	for _, mm := range e.Mismatches {
+		versions = append(versions, strconv.FormatUint(mm.Version, 10))
+	}
+
+	return fmt.Sprintf("%s: %s", ErrChecksumMismatch, strings.Join(versions, ", "))
+}
+
+// Unwrap returns ErrChecksumMismatch so callers can use errors.Is.
+func (*ChecksumError) Unwrap() error { return ErrChecksumMismatch }
+
+// LockError wraps an error that occurred while acquiring, renewing or releasing the migration lock.
+type LockError struct {
+	// Holder is the current lock holder if the lock is held by another process.
+	Holder *LockInfo
+	Err    error
+}
+
+// Error implements the error interface.
+func (e *LockError) Error() string {
+	if e.Holder != nil && e.Holder.Holder != "" {
+		return fmt.Sprintf("scyllamigrate: lock error (held by %s since %s): %v",
+			e.Holder.Holder, e.Holder.AcquiredAt.Format(time.RFC3339), e.Err,
+		)
+	}
+
+	return fmt.Sprintf("scyllamigrate: lock error: %v", e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *LockError) Unwrap() error { return e.Err }
+
+// DirtyError indicates that a previous migration failed partway through.
+// Repair the schema manually and call Migrator.Force to clear the state.
+type DirtyError struct {
+	Version   uint64
+	Direction Direction
+	Statement int
+
+	// ChecksumChanged is true if the migration could not be resumed because
+	// its content changed after it failed.
+	ChecksumChanged bool
+}
+
+// Error implements the error interface.
+func (e *DirtyError) Error() string {
+	if e.ChecksumChanged {
+		return fmt.Sprintf("%s: %s migration %d failed at statement %d and was modified since, it cannot be resumed",
+			ErrDirty, e.Direction, e.Version, e.Statement,
+		)
+	}
+
+	return fmt.Sprintf("%s: %s migration %d failed at statement %d (repair the schema and force a version)",
+		ErrDirty, e.Direction, e.Version, e.Statement,
+	)
+}
+
+// Unwrap returns ErrDirty so callers can use errors.Is.
+func (*DirtyError) Unwrap() error { return ErrDirty }
+
+// HookError indicates that a hook aborted the migration run.
+type HookError struct {
+	// Hook is the name of the callback that failed, e.g. "BeforeMigration".
+	Hook string
+
+	// Version is the migration the hook was called for (0 for the internal tables).
+	Version uint64
+
+	Err error
+}
+
+// Error implements the error interface.
+func (e *HookError) Error() string {
+	return fmt.Sprintf("scyllamigrate: %s hook aborted migration %d: %v", e.Hook, e.Version, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *HookError) Unwrap() error { return e.Err }
+
+// MissingMigrationError indicates that applied migrations are missing from the
+// source, e.g. after switching to a branch that does not contain them yet.
+type MissingMigrationError struct {
+	Versions []uint64
+}
+
+// Error implements the error interface.
+func (e *MissingMigrationError) Error() string {
+	versions := make([]string, 0, len(e.Versions))
+	for _, v := range e.Versions {
+		versions = append(versions, strconv.FormatUint(v, 10))
+	}
+
+	return fmt.Sprintf("%s: applied migrations missing from the source: %s", ErrMissingVersion, strings.Join(versions, ", "))
+}
+
+// Unwrap returns ErrMissingVersion so callers can use errors.Is.
+func (*MissingMigrationError) Unwrap() error { return ErrMissingVersion }
+
+// OutOfOrderError indicates that pending migrations are older than the current
+// version and the out-of-order policy is OutOfOrderStrict.
+type OutOfOrderError struct {
+	// Current is the current version.
+	Current uint64
+
+	// Versions are the out-of-order pending versions.
+	Versions []uint64
+}
+
+// Error implements the error interface.
+func (e *OutOfOrderError) Error() string {
+	versions := make([]string, 0, len(e.Versions))
+	for _, v := range e.Versions {
+		versions = append(versions, strconv.FormatUint(v, 10))
+	}
+
+	return fmt.Sprintf("%s %d: %s", ErrOutOfOrder, e.Current, strings.Join(versions, ", "))
+}
+
+// Unwrap returns ErrOutOfOrder so callers can use errors.Is.
+func (*OutOfOrderError) Unwrap() error { return ErrOutOfOrder }
+
+// DestructiveError indicates that a run would execute destructive statements
+// that were neither allowed nor confirmed. Nothing was executed.
+type DestructiveError struct {
+	Statements []DestructiveStatement
+}
+
+// Error implements the error interface.
+func (e *DestructiveError) Error() string {
+	locations := make([]string, 0, len(e.Statements))
+	for _, s := range e.Statements {
+		locations = append(locations, fmt.Sprintf("%s:%d", s.File, s.Line))
+	}
+
+	return fmt.Sprintf("%s: %s", ErrDestructive, strings.Join(locations, ", "))
+}
+
+// Unwrap returns ErrDestructive so callers can use errors.Is.
+func (*DestructiveError) Unwrap() error { return ErrDestructive }
+
+// ConflictError indicates that files in different directories or sources define
+// the same migration version, or that several files define the same direction of a version.
+type ConflictError struct {
+	// Version is the conflicting version.
+	Version uint64
+
+	// Files are the paths of the first file found for the version and of the conflicting file.
+	Files [2]string
+}
+
+// Error implements the error interface.
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s %d: %s and %s", ErrDuplicateVersion, e.Version, e.Files[0], e.Files[1])
+}
+
+// Unwrap returns ErrDuplicateVersion so callers can use errors.Is.
+func (*ConflictError) Unwrap() error { return ErrDuplicateVersion }
+
+// ManifestError indicates that a migration file of a remote source does not
+// match the checksum manifest of the source.
+type ManifestError struct {
+	// File is the path of the file relative to the source root.
+	File string
+
+	// Expected is the checksum listed in the manifest, empty if the file is not listed.
+	Expected string
+
+	// Actual is the checksum of the file, empty if the file is listed but missing.
+	Actual string
+}
+
+// Error implements the error interface.
+func (e *ManifestError) Error() string {
+	switch {
+	case e.Expected == "":
+		return fmt.Sprintf("%s: %s is not listed", ErrManifestMismatch, e.File)
+	case e.Actual == "":
+		return fmt.Sprintf("%s: %s is missing", ErrManifestMismatch, e.File)
+	default:
+		return fmt.Sprintf("%s: %s has checksum %s, expected %s", ErrManifestMismatch, e.File, e.Actual, e.Expected)
+	}
+}
+
+// Unwrap returns ErrManifestMismatch so callers can use errors.Is.
+func (*ManifestError) Unwrap() error { return ErrManifestMismatch }
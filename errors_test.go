@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	td "github.com/maxatome/go-testdeep/td"
 )
@@ -191,6 +192,37 @@ func TestMigrationError_Error(t *testing.T) {
 			},
 			expected: "scyllamigrate: failed to execute down migration 5 (statement 3): table not found",
 		},
+		"with file and line": {
+			err: &MigrationError{
+				Version:   2,
+				Direction: Up,
+				Statement: 3,
+				File:      "000002_create_posts.up.cql",
+				Line:      14,
+				Err:       fmt.Errorf("syntax error"),
+			},
+			expected: "scyllamigrate: failed to execute up migration 2 (statement 3 at 000002_create_posts.up.cql:14): syntax error",
+		},
+		"with line but no file": {
+			err: &MigrationError{
+				Version:   2,
+				Direction: Up,
+				Statement: 1,
+				Line:      4,
+				Err:       fmt.Errorf("syntax error"),
+			},
+			expected: "scyllamigrate: failed to execute up migration 2 (statement 1 at line 4): syntax error",
+		},
+		"with line but no statement": {
+			err: &MigrationError{
+				Version:   2,
+				Direction: Up,
+				File:      "000002_create_posts.up.cql",
+				Line:      7,
+				Err:       fmt.Errorf("unterminated string literal"),
+			},
+			expected: "scyllamigrate: failed to execute up migration 2 (at 000002_create_posts.up.cql:7): unterminated string literal",
+		},
 	}
 
 	for name, tt := range tests {
@@ -313,3 +345,198 @@ func TestNestedErrorUnwrapping(t *testing.T) {
 
 	td.CmpErrorIs(t, migrationErr, sourceErr)
 }
+
+func TestChecksumError_Error(t *testing.T) {
+	type tcase struct {
+		err      *ChecksumError
+		expected string
+	}
+	tests := map[string]tcase{
+		"single version": {
+			err: &ChecksumError{Mismatches: []ChecksumMismatch{
+				{Version: 3, Recorded: "aaa", Actual: "bbb"},
+			}},
+			expected: "scyllamigrate: migration file was modified after being applied: 3",
+		},
+		"multiple versions": {
+			err: &ChecksumError{Mismatches: []ChecksumMismatch{
+				{Version: 1, Recorded: "aaa", Actual: "bbb"},
+				{Version: 4, Recorded: "ccc", Actual: "ddd"},
+			}},
+			expected: "scyllamigrate: migration file was modified after being applied: 1, 4",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			td.Cmp(t, tt.err.Error(), tt.expected)
+		})
+	}
+}
+
+func TestChecksumError_Is(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", &ChecksumError{Mismatches: []ChecksumMismatch{{Version: 1}}})
+
+	td.CmpErrorIs(t, err, ErrChecksumMismatch)
+
+	var ce *ChecksumError
+	td.Cmp(t, errors.As(err, &ce), true)
+	td.Cmp(t, ce.Mismatches[0].Version, uint64(1))
+}
+
+func TestLockError_Error(t *testing.T) {
+	type tcase struct {
+		err      *LockError
+		expected string
+	}
+	tests := map[string]tcase{
+		"with holder": {
+			err: &LockError{
+				Holder: &LockInfo{
+					Holder:     "host-a:42:abcd",
+					AcquiredAt: time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC),
+				},
+				Err: ErrLocked,
+			},
+			expected: "scyllamigrate: lock error (held by host-a:42:abcd since 2024-01-15T10:30:00Z): " +
+				"scyllamigrate: migration lock is held by another process",
+		},
+		"without holder": {
+			err:      &LockError{Err: ErrLockLost},
+			expected: "scyllamigrate: lock error: scyllamigrate: migration lock was lost",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			td.Cmp(t, tt.err.Error(), tt.expected)
+		})
+	}
+}
+
+func TestLockError_Is(t *testing.T) {
+	err := &LockError{Err: ErrLocked}
+
+	td.CmpErrorIs(t, err, ErrLocked)
+	td.Cmp(t, errors.Is(err, ErrLockLost), false)
+}
+
+func TestDirtyError_Error(t *testing.T) {
+	err := &DirtyError{Version: 3, Direction: Up, Statement: 2}
+
+	td.Cmp(t, err.Error(),
+		"scyllamigrate: database is dirty: up migration 3 failed at statement 2 (repair the schema and force a version)")
+}
+
+func TestDirtyError_Error_ChecksumChanged(t *testing.T) {
+	err := &DirtyError{Version: 3, Direction: Up, Statement: 2, ChecksumChanged: true}
+
+	td.Cmp(t, err.Error(),
+		"scyllamigrate: database is dirty: up migration 3 failed at statement 2 and was modified since, it cannot be resumed")
+}
+
+func TestDirtyError_Is(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", &DirtyError{Version: 3, Direction: Down, Statement: 1})
+
+	td.CmpErrorIs(t, err, ErrDirty)
+
+	var de *DirtyError
+	td.Cmp(t, errors.As(err, &de), true)
+	td.Cmp(t, de.Version, uint64(3))
+	td.Cmp(t, de.Direction, Down)
+	td.Cmp(t, de.Statement, 1)
+}
+
+func TestSyntaxError_Error(t *testing.T) {
+	err := &SyntaxError{Line: 12, Msg: "unterminated string literal"}
+	td.Cmp(t, err.Error(), "scyllamigrate: syntax error at line 12: unterminated string literal")
+}
+
+func TestHookError_Error(t *testing.T) {
+	err := &HookError{Hook: "BeforeMigration", Version: 3, Err: errors.New("deploy freeze")}
+
+	td.Cmp(t, err.Error(), "scyllamigrate: BeforeMigration hook aborted migration 3: deploy freeze")
+}
+
+func TestHookError_Is(t *testing.T) {
+	cause := errors.New("deploy freeze")
+	err := fmt.Errorf("wrapped: %w", &HookError{Hook: "AfterStatement", Version: 2, Err: cause})
+
+	td.CmpErrorIs(t, err, cause)
+
+	var he *HookError
+	td.Cmp(t, errors.As(err, &he), true)
+	td.Cmp(t, he.Hook, "AfterStatement")
+}
+
+func TestMissingMigrationError_Error(t *testing.T) {
+	err := &MissingMigrationError{Versions: []uint64{8, 7}}
+
+	td.Cmp(t, err.Error(),
+		"scyllamigrate: migration version not found: applied migrations missing from the source: 8, 7")
+}
+
+func TestMissingMigrationError_Is(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", &MissingMigrationError{Versions: []uint64{8}})
+
+	td.CmpErrorIs(t, err, ErrMissingVersion)
+
+	var me *MissingMigrationError
+	td.Cmp(t, errors.As(err, &me), true)
+	td.Cmp(t, me.Versions, []uint64{8})
+}
+
+func TestOutOfOrderError_Error(t *testing.T) {
+	err := &OutOfOrderError{Current: 7, Versions: []uint64{5, 6}}
+
+	td.Cmp(t, err.Error(), "scyllamigrate: pending migrations are older than the current version 7: 5, 6")
+	td.CmpErrorIs(t, err, ErrOutOfOrder)
+}
+
+func TestDestructiveError_Error(t *testing.T) {
+	err := &DestructiveError{Statements: []DestructiveStatement{
+		{Version: 3, Direction: Down, File: "000003_users.down.cql", Line: 2, Text: "DROP TABLE users"},
+		{Version: 3, Direction: Down, File: "000003_users.down.cql", Line: 4, Text: "DROP TYPE address"},
+	}}
+
+	td.Cmp(t, err.Error(), "scyllamigrate: destructive statements were not allowed: "+
+		"000003_users.down.cql:2, 000003_users.down.cql:4")
+	td.CmpErrorIs(t, err, ErrDestructive)
+}
+
+func TestConflictError_Error(t *testing.T) {
+	err := &ConflictError{Version: 3, Files: [2]string{"shared/000003_users.up.cql", "app/000003_posts.up.cql"}}
+
+	td.Cmp(t, err.Error(), "scyllamigrate: duplicate migration version 3: "+
+		"shared/000003_users.up.cql and app/000003_posts.up.cql")
+	td.CmpErrorIs(t, err, ErrDuplicateVersion)
+}
+
+func TestManifestError_Error(t *testing.T) {
+	type tcase struct {
+		err      *ManifestError
+		expected string
+	}
+
+	tests := map[string]tcase{
+		"not listed": {
+			err:      &ManifestError{File: "000001_init.up.cql", Actual: "bbb"},
+			expected: "scyllamigrate: migration file does not match the manifest: 000001_init.up.cql is not listed",
+		},
+		"missing": {
+			err:      &ManifestError{File: "000001_init.up.cql", Expected: "aaa"},
+			expected: "scyllamigrate: migration file does not match the manifest: 000001_init.up.cql is missing",
+		},
+		"checksum": {
+			err:      &ManifestError{File: "000001_init.up.cql", Expected: "aaa", Actual: "bbb"},
+			expected: "scyllamigrate: migration file does not match the manifest: 000001_init.up.cql has checksum bbb, expected aaa",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			td.Cmp(t, tt.err.Error(), tt.expected)
+			td.CmpErrorIs(t, tt.err, ErrManifestMismatch)
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package scyllamigrate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// GitConfig configures a source reading migration files from a git repository on disk.
+type GitConfig struct {
+	// Repository is the path of the repository, a working tree or a bare repository.
+	Repository string
+
+	// Ref is the branch, tag or commit to read, "HEAD" if empty.
+	Ref string
+
+	// Dir is the directory of the migration files within the repository, the root if empty.
+	Dir string
+}
+
+// NewGitSource creates a Source from the migration files committed at a ref
+// of a git repository, regardless of the state of its working tree.
+// It runs the git command, which must be installed.
+//
+// If WithManifest is given, the manifest is read from the same commit relative
+// to Dir and the source fails with a *ManifestError if a file is not listed or
+// its checksum does not match. The files are held in memory.
+func NewGitSource(ctx context.Context, cfg GitConfig, opts ...RemoteOption) (*FSSource, error) {
+	remote := newRemoteConfig(opts)
+
+	if cfg.Ref == "" {
+		cfg.Ref = "HEAD"
+	}
+
+	dir := path.Clean("/" + cfg.Dir)[1:]
+
+	out, err := runGit(ctx, cfg.Repository, nil, "rev-parse", "--verify", "--end-of-options", cfg.Ref+"^{commit}")
+	if err != nil {
+		return nil, &SourceError{Op: "resolve ref", Err: err}
+	}
+
+	commit := strings.TrimSpace(string(out))
+
+	args := []string{"ls-tree", "-r", "-z", "--full-tree", commit}
+	if dir != "" {
+		args = append(args, "--", dir)
+	}
+
+	if out, err = runGit(ctx, cfg.Repository, nil, args...); err != nil {
+		return nil, &SourceError{Op: "list", Err: err}
+	}
+
+	// Each entry is "<mode> <type> <object>\t<path>".
+	var names, objects []string
+
+	for _, entry := range strings.Split(string(out), "\x00") {
+		info, name, ok := strings.Cut(entry, "\t")
+		fields := strings.Fields(info)
+
+		if !ok || len(fields) != 3 || fields[1] != "blob" {
+			continue
+		}
+
+		if dir != "" {
+			if name, ok = strings.CutPrefix(name, dir+"/"); !ok {
+				continue
+			}
+		}
+
+		if isRemoteFile(name) || name == remote.manifest {
+			names = append(names, name)
+			objects = append(objects, fields[2])
+		}
+	}
+
+	blobs, err := readBlobs(ctx, cfg.Repository, objects)
+	if err != nil {
+		return nil, &SourceError{Op: "fetch", Err: err}
+	}
+
+	location := func(name string) string {
+		return cfg.Repository + "@" + cfg.Ref + ":" + path.Join(dir, name)
+	}
+
+	files := make(memFS)
+
+	var manifest Manifest
+
+	for i, name := range names {
+		if name == remote.manifest {
+			if manifest, err = ParseManifest(bytes.NewReader(blobs[i])); err != nil {
+				return nil, &SourceError{Op: "fetch manifest", Err: err}
+			}
+
+			continue
+		}
+
+		files[name] = blobs[i]
+	}
+
+	if remote.manifest != "" && manifest == nil {
+		return nil, &SourceError{Op: "fetch manifest", Err: fmt.Errorf("%s does not exist", location(remote.manifest))}
+	}
+
+	return newMemorySource(files, manifest, location)
+}
+
+// readBlobs returns the contents of the blob objects with a single git cat-file process.
+func readBlobs(ctx context.Context, repo string, objects []string) ([][]byte, error) {
+	if len(objects) == 0 {
+		return nil, nil
+	}
+
+	out, err := runGit(ctx, repo, strings.NewReader(strings.Join(objects, "\n")+"\n"), "cat-file", "--batch")
+	if err != nil {
+		return nil, err
+	}
+
+	// Each object is "<object> <type> <size>\n<content>\n".
+	blobs := make([][]byte, 0, len(objects))
+
+	for _, object := range objects {
+		header, rest, ok := bytes.Cut(out, []byte("\n"))
+		fields := strings.Fields(string(header))
+
+		if !ok || len(fields) != 3 {
+			return nil, fmt.Errorf("git cat-file: unexpected output for %s: %q", object, header)
+		}
+
+		size, err := strconv.Atoi(fields[2])
+		if err != nil || size+1 > len(rest) {
+			return nil, fmt.Errorf("git cat-file: unexpected output for %s: %q", object, header)
+		}
+
+		blobs = append(blobs, rest[:size])
+		out = rest[size+1:]
+	}
+
+	return blobs, nil
+}
+
+// runGit runs git in repo with the given stdin and returns its standard output.
+func runGit(ctx context.Context, repo string, stdin io.Reader, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", repo}, args...)...)
+	cmd.Stdin = stdin
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %w: %s", args[0], err, msg)
+		}
+
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,114 @@
+package scyllamigrate
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	td "github.com/maxatome/go-testdeep/td"
+)
+
+// newGitRepo creates a repository whose "v1" tag has the files of remoteMigrations
+// and a manifest in "db/", and whose HEAD also has a third migration.
+func newGitRepo(t *testing.T) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repo := t.TempDir()
+
+	git := func(args ...string) {
+		t.Helper()
+
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		td.Require(t).CmpNoError(err, string(out))
+	}
+
+	write := func(fsys fstest.MapFS) {
+		t.Helper()
+
+		for name, file := range fsys {
+			path := filepath.Join(repo, "db", filepath.FromSlash(name))
+			td.Require(t).CmpNoError(os.MkdirAll(filepath.Dir(path), 0o755))
+			td.Require(t).CmpNoError(os.WriteFile(path, file.Data, 0o644))
+		}
+	}
+
+	git("init", "--quiet")
+	write(remoteMigrations())
+	write(fstest.MapFS{DefaultManifest: {Data: testManifest(t, remoteMigrations())}})
+	git("add", ".")
+	git("commit", "--quiet", "-m", "v1")
+	git("tag", "v1")
+
+	write(fstest.MapFS{"2025/000003_posts.up.cql": {Data: []byte("CREATE TABLE posts (id int PRIMARY KEY);")}})
+	git("add", ".")
+	git("commit", "--quiet", "-m", "posts")
+
+	// Uncommitted changes are not read.
+	write(fstest.MapFS{"2025/000004_drafts.up.cql": {Data: []byte("CREATE TABLE drafts (id int PRIMARY KEY);")}})
+
+	return repo
+}
+
+func TestNewGitSource(t *testing.T) {
+	repo := newGitRepo(t)
+	ctx := context.Background()
+
+	t.Run("head", func(t *testing.T) {
+		source, err := NewGitSource(ctx, GitConfig{Repository: repo, Dir: "db"})
+		td.Require(t).CmpNoError(err)
+		td.Cmp(t, source.Versions(), []uint64{1, 2, 3})
+	})
+
+	t.Run("tag with manifest", func(t *testing.T) {
+		source, err := NewGitSource(ctx, GitConfig{Repository: repo, Ref: "v1", Dir: "/db/"}, WithManifest(DefaultManifest))
+		td.Require(t).CmpNoError(err)
+		td.Cmp(t, source.Versions(), []uint64{1, 2})
+
+		pair, ok := source.Get(1)
+		td.Require(t).True(ok)
+		td.Cmp(t, sourceFilePath(source, pair.Down.Raw), repo+"@v1:db/2024/000001_init.down.cql")
+
+		rc, err := source.ReadDown(1)
+		td.Require(t).CmpNoError(err)
+
+		content, err := io.ReadAll(rc)
+		td.CmpNoError(t, err)
+		td.CmpNoError(t, rc.Close())
+		td.Cmp(t, string(content), "DROP TABLE init;")
+	})
+
+	t.Run("file not in manifest", func(t *testing.T) {
+		_, err := NewGitSource(ctx, GitConfig{Repository: repo, Dir: "db"}, WithManifest(DefaultManifest))
+
+		var me *ManifestError
+		td.Require(t).True(errors.As(err, &me))
+		td.Cmp(t, me.File, "2025/000003_posts.up.cql")
+		td.Cmp(t, me.Expected, "")
+	})
+
+	t.Run("missing manifest", func(t *testing.T) {
+		_, err := NewGitSource(ctx, GitConfig{Repository: repo}, WithManifest(DefaultManifest))
+		td.Cmp(t, err, td.ErrorIs(td.Contains(repo+"@HEAD:SHA256SUMS does not exist")))
+	})
+
+	t.Run("unknown ref", func(t *testing.T) {
+		_, err := NewGitSource(ctx, GitConfig{Repository: repo, Ref: "v2", Dir: "db"})
+		td.Cmp(t, err, td.ErrorIs(td.Contains("(resolve ref): git rev-parse")))
+	})
+
+	t.Run("empty directory", func(t *testing.T) {
+		source, err := NewGitSource(ctx, GitConfig{Repository: repo, Dir: "migrations"})
+		td.Require(t).CmpNoError(err)
+		td.CmpEmpty(t, source.Versions())
+	})
+}
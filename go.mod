@@ -8,6 +8,7 @@ require (
 	github.com/gocql/gocql v1.7.0
 	github.com/heartwilltell/scotty v0.2.1
 	github.com/maxatome/go-testdeep v1.14.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,21 @@
+module github.com/heartwilltell/scyllamigrate/gocqlxsession
+
+go 1.25
+
+// Like the root module, use the scylladb fork of gocql under its original import path.
+replace github.com/gocql/gocql => github.com/scylladb/gocql v1.14.5
+
+require (
+	github.com/heartwilltell/scyllamigrate v0.2.0
+	github.com/maxatome/go-testdeep v1.14.0
+	github.com/scylladb/gocqlx/v2 v2.8.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/gocql/gocql v1.7.0 // indirect
+	github.com/hailocab/go-hostpool v0.0.0-20160125115350-e80d13ce29ed // indirect
+	github.com/klauspost/compress v1.18.2 // indirect
+	github.com/scylladb/go-reflectx v1.0.1 // indirect
+	gopkg.in/inf.v0 v0.9.1 // indirect
+)
@@ -0,0 +1,25 @@
+// Package gocqlxsession adapts gocqlx sessions to scyllamigrate.
+//
+// It is a separate module so that only users of gocqlx depend on it.
+package gocqlxsession
+
+import (
+	"github.com/scylladb/gocqlx/v2"
+
+	"github.com/heartwilltell/scyllamigrate"
+)
+
+// New adapts a gocqlx session to the scyllamigrate.Session interface.
+// The Migrator shares the connection pool of the session.
+func New(session gocqlx.Session) scyllamigrate.Session {
+	return scyllamigrate.NewGocqlSession(session.Session)
+}
+
+// NewMigrator creates a Migrator that talks to the database through a gocqlx session.
+func NewMigrator(session gocqlx.Session, opts ...scyllamigrate.Option) (*scyllamigrate.Migrator, error) {
+	if session.Session == nil {
+		return nil, scyllamigrate.ErrNoSession
+	}
+
+	return scyllamigrate.NewWithSession(New(session), opts...)
+}
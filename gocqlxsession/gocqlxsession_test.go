@@ -0,0 +1,15 @@
+package gocqlxsession
+
+import (
+	"testing"
+
+	td "github.com/maxatome/go-testdeep/td"
+	"github.com/scylladb/gocqlx/v2"
+
+	"github.com/heartwilltell/scyllamigrate"
+)
+
+func TestNewMigrator(t *testing.T) {
+	_, err := NewMigrator(gocqlx.Session{}, scyllamigrate.WithKeyspace("app"))
+	td.CmpErrorIs(t, err, scyllamigrate.ErrNoSession)
+}
@@ -0,0 +1,194 @@
+package scyllamigrate
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// goChecksumPrefix distinguishes checksums of Go migrations from SHA-256
+// checksums of file based migrations in the history table.
+const goChecksumPrefix = "go:"
+
+// GoMigrationFunc is a migration step implemented in Go that uses the gocql session.
+// It requires a Migrator whose Session is backed by a *gocql.Session, as created
+// by New or with NewGocqlSession.
+type GoMigrationFunc func(ctx context.Context, session *gocql.Session) error
+
+// GoMigrationSessionFunc is a migration step implemented in Go that uses the Session
+// the Migrator talks to, whichever driver or adapter backs it.
+type GoMigrationSessionFunc func(ctx context.Context, session Session) error
+
+// GoMigration is a migration implemented as Go functions, for changes that
+// cannot be expressed in CQL such as backfills or data transformations.
+// Go migrations are merged with the migrations of the Source and are treated
+// the same way by Pending, Status, Up and Down.
+type GoMigration struct {
+	// Version is the migration version. It must not clash with a source migration.
+	Version uint64
+
+	// Description is the human-readable description.
+	Description string
+
+	// Up applies the migration. Exactly one of Up and UpSession is required.
+	Up GoMigrationFunc
+
+	// Down rolls back the migration (may be nil).
+	Down GoMigrationFunc
+
+	// UpSession applies the migration through the Session, as an alternative to Up.
+	UpSession GoMigrationSessionFunc
+
+	// DownSession rolls back the migration through the Session, as an alternative to Down.
+	DownSession GoMigrationSessionFunc
+
+	// Checksum is a user-supplied identifier of the migration implementation, e.g. "v1".
+	// It is recorded with a "go:" prefix in the history table and compared during
+	// checksum verification, so change it whenever the implementation changes.
+	Checksum string
+}
+
+// checksum returns the value recorded in the history table for the migration.
+func (g *GoMigration) checksum() string {
+	return goChecksumPrefix + g.Checksum
+}
+
+// needsGocql reports whether the migration uses the gocql session.
+func (g *GoMigration) needsGocql() bool {
+	return g.Up != nil || g.Down != nil
+}
+
+// hasDown reports whether the migration can be rolled back.
+func (g *GoMigration) hasDown() bool {
+	return g.Down != nil || g.DownSession != nil
+}
+
+// pair returns the migration pair representing the Go migration.
+func (g *GoMigration) pair() *MigrationPair {
+	pair := &MigrationPair{
+		Version:     g.Version,
+		Description: g.Description,
+		Up: &Migration{
+			Version:     g.Version,
+			Description: g.Description,
+			Direction:   Up,
+		},
+	}
+
+	if g.hasDown() {
+		pair.Down = &Migration{
+			Version:     g.Version,
+			Description: g.Description,
+			Direction:   Down,
+		}
+	}
+
+	return pair
+}
+
+// migrations returns the source migrations merged with the registered Go
+// migrations, sorted by version.
+func (m *Migrator) migrations() ([]*MigrationPair, error) {
+	pairs, err := m.source.List()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(m.goMigrations) == 0 {
+		return pairs, nil
+	}
+
+	merged := make([]*MigrationPair, 0, len(pairs)+len(m.goMigrations))
+	merged = append(merged, pairs...)
+
+	for _, gm := range m.goMigrations {
+		merged = append(merged, gm.pair())
+	}
+
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Version < merged[j].Version
+	})
+
+	return merged, nil
+}
+
+// checkGoMigrations ensures no Go migration clashes with a source migration and
+// that migrations using the gocql session have one.
+func (m *Migrator) checkGoMigrations() error {
+	if len(m.goMigrations) == 0 {
+		return nil
+	}
+
+	if gocqlSessionOf(m.session) == nil {
+		for _, gm := range m.goMigrations {
+			if gm.needsGocql() {
+				return &SourceError{
+					Version: gm.Version,
+					Op:      "register go migration",
+					Err:     ErrNoGocqlSession,
+				}
+			}
+		}
+	}
+
+	pairs, err := m.source.List()
+	if err != nil {
+		return err
+	}
+
+	for _, pair := range pairs {
+		if _, ok := m.goMigrations[pair.Version]; ok {
+			return &SourceError{
+				Version: pair.Version,
+				Op:      "register go migration",
+				Err:     ErrDuplicateVersion,
+			}
+		}
+	}
+
+	return nil
+}
+
+// executeGo runs a Go migration in the given direction. It is tracked in the
+// dirty state as a migration with a single statement.
+func (m *Migrator) executeGo(ctx context.Context, gm *GoMigration, direction Direction) error {
+	if err := m.markDirty(ctx, DirtyState{
+		Version:   gm.Version,
+		Direction: direction,
+		Statement: 1,
+		Checksum:  gm.checksum(),
+		StartedAt: time.Now(),
+	}); err != nil {
+		return err
+	}
+
+	if err := m.callGo(ctx, gm, direction); err != nil {
+		return &MigrationError{
+			Version:   gm.Version,
+			Direction: direction,
+			Statement: 1,
+			Err:       err,
+		}
+	}
+
+	return m.awaitSchemaAgreement(ctx, gm.Version, direction)
+}
+
+// callGo calls the function of a Go migration for the given direction.
+func (m *Migrator) callGo(ctx context.Context, gm *GoMigration, direction Direction) error {
+	if direction == Down {
+		if gm.DownSession != nil {
+			return gm.DownSession(ctx, m.session)
+		}
+
+		return gm.Down(ctx, gocqlSessionOf(m.session))
+	}
+
+	if gm.UpSession != nil {
+		return gm.UpSession(ctx, m.session)
+	}
+
+	return gm.Up(ctx, gocqlSessionOf(m.session))
+}
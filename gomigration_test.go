@@ -0,0 +1,116 @@
+package scyllamigrate
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gocql/gocql"
+	td "github.com/maxatome/go-testdeep/td"
+)
+
+func noopGoMigration(context.Context, *gocql.Session) error { return nil }
+
+func noopGoSessionMigration(context.Context, Session) error { return nil }
+
+func TestGoMigration_pair(t *testing.T) {
+	type tcase struct {
+		migration *GoMigration
+		hasDown   bool
+	}
+	tests := map[string]tcase{
+		"up and down": {
+			migration: &GoMigration{Version: 5, Description: "backfill", Up: noopGoMigration, Down: noopGoMigration},
+			hasDown:   true,
+		},
+		"up only": {
+			migration: &GoMigration{Version: 5, Description: "backfill", Up: noopGoMigration},
+			hasDown:   false,
+		},
+		"session up and down": {
+			migration: &GoMigration{Version: 5, Description: "backfill", UpSession: noopGoSessionMigration, DownSession: noopGoSessionMigration},
+			hasDown:   true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			pair := tt.migration.pair()
+
+			td.Cmp(t, pair.Version, uint64(5))
+			td.Cmp(t, pair.Description, "backfill")
+			td.Cmp(t, pair.HasUp(), true)
+			td.Cmp(t, pair.Up.Direction, Up)
+			td.Cmp(t, pair.HasDown(), tt.hasDown)
+		})
+	}
+}
+
+func TestGoMigration_checksum(t *testing.T) {
+	td.Cmp(t, (&GoMigration{Checksum: "v2"}).checksum(), "go:v2")
+	td.Cmp(t, (&GoMigration{}).checksum(), "go:")
+}
+
+func TestMigrator_migrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"000001_create_users.up.cql": {Data: []byte("CREATE TABLE users;")},
+		"000003_create_posts.up.cql": {Data: []byte("CREATE TABLE posts;")},
+	}
+	source, err := NewFSSource(fsys)
+	td.CmpNoError(t, err)
+
+	m := &Migrator{source: source}
+
+	// Without Go migrations the source list is returned as is.
+	pairs, err := m.migrations()
+	td.CmpNoError(t, err)
+	td.Cmp(t, len(pairs), 2)
+
+	td.CmpNoError(t, WithGoMigrations(
+		&GoMigration{Version: 4, Description: "reindex", Up: noopGoMigration},
+		&GoMigration{Version: 2, Description: "backfill", Up: noopGoMigration},
+	)(m))
+
+	pairs, err = m.migrations()
+	td.CmpNoError(t, err)
+
+	versions := make([]uint64, 0, len(pairs))
+	for _, pair := range pairs {
+		versions = append(versions, pair.Version)
+	}
+
+	td.Cmp(t, versions, []uint64{1, 2, 3, 4})
+	td.Cmp(t, pairs[1].Description, "backfill")
+}
+
+func TestMigrator_checkGoMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"000001_create_users.up.cql": {Data: []byte("CREATE TABLE users;")},
+	}
+	source, err := NewFSSource(fsys)
+	td.CmpNoError(t, err)
+
+	_, err = New(&gocql.Session{},
+		WithSource(source),
+		WithKeyspace("test"),
+		WithGoMigrations(&GoMigration{Version: 1, Up: noopGoMigration}),
+	)
+	td.CmpErrorIs(t, err, ErrDuplicateVersion)
+
+	_, err = New(&gocql.Session{},
+		WithSource(source),
+		WithKeyspace("test"),
+		WithGoMigrations(&GoMigration{Version: 2, Up: noopGoMigration}),
+	)
+	td.CmpNoError(t, err)
+}
+
+func TestMigrator_compareChecksums_GoMigrations(t *testing.T) {
+	m := &Migrator{source: &mockSource{}}
+	td.CmpNoError(t, WithGoMigrations(&GoMigration{Version: 1, Up: noopGoMigration, Checksum: "v2"})(m))
+
+	td.CmpNoError(t, m.compareChecksums([]*AppliedMigration{{Version: 1, Checksum: "go:v2"}}))
+
+	err := m.compareChecksums([]*AppliedMigration{{Version: 1, Checksum: "go:v1"}})
+	td.CmpErrorIs(t, err, ErrChecksumMismatch)
+}
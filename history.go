@@ -2,11 +2,9 @@ package scyllamigrate
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"sort"
 	"time"
-
-	"github.com/gocql/gocql"
 )
 
 const historySchemaTemplate = `
@@ -16,6 +14,26 @@ CREATE TABLE IF NOT EXISTS %s.%s (
     checksum text,
     applied_at timestamp,
     execution_ms bigint,
+    baselined boolean,
+    PRIMARY KEY (version)
+)`
+
+// historyColumns are columns added to the history table after its first release.
+// They are added to existing history tables by ensureHistoryTable.
+var historyColumns = map[string]string{
+	"baselined": "boolean",
+}
+
+// dirtyTableSuffix is appended to the history table name to build the dirty state table name.
+const dirtyTableSuffix = "_dirty"
+
+const dirtySchemaTemplate = `
+CREATE TABLE IF NOT EXISTS %s.%s (
+    version bigint,
+    direction text,
+    statement int,
+    checksum text,
+    started_at timestamp,
     PRIMARY KEY (version)
 )`
 
@@ -24,39 +42,94 @@ type migrationRecord struct {
 	description string
 	checksum    string
 	duration    time.Duration
+	baselined   bool
 }
 
-// ensureHistoryTable creates the migration history table if it doesn't exist.
+// ensureHistoryTable creates the migration history table and its companion
+// dirty state table if they don't exist.
 func (m *Migrator) ensureHistoryTable(ctx context.Context) error {
 	query := fmt.Sprintf(historySchemaTemplate, m.keyspace, m.historyTable)
 
-	if err := m.session.Query(query).WithContext(ctx).Consistency(m.consistency).Exec(); err != nil {
+	if err := m.exec(ctx, query); err != nil {
 		return fmt.Errorf("failed to create history table: %w", err)
 	}
 
-	if m.waitForSchemaAgreement {
-		if err := m.session.AwaitSchemaAgreement(ctx); err != nil {
-			return fmt.Errorf("failed to wait for schema agreement: %w", err)
+	if err := m.ensureHistoryColumns(ctx); err != nil {
+		return err
+	}
+
+	query = fmt.Sprintf(dirtySchemaTemplate, m.keyspace, m.dirtyTable())
+
+	if err := m.exec(ctx, query); err != nil {
+		return fmt.Errorf("failed to create dirty state table: %w", err)
+	}
+
+	return m.awaitSchemaAgreement(ctx, 0, "")
+}
+
+// ensureHistoryColumns adds the columns missing from a history table created by an older release.
+func (m *Migrator) ensureHistoryColumns(ctx context.Context) error {
+	query := `
+		SELECT column_name
+		FROM system_schema.columns
+		WHERE keyspace_name = ? AND table_name = ?
+	`
+
+	iter := m.iter(ctx, query, m.keyspace, m.historyTable)
+
+	existing := make(map[string]bool)
+
+	var column string
+
+	for iter.Scan(&column) {
+		existing[column] = true
+	}
+
+	if err := iter.Close(); err != nil {
+		return fmt.Errorf("failed to read history table columns: %w", err)
+	}
+
+	for _, column := range missingColumns(existing, historyColumns) {
+		query := fmt.Sprintf("ALTER TABLE %s.%s ADD %s %s", m.keyspace, m.historyTable, column, historyColumns[column])
+
+		if err := m.exec(ctx, query); err != nil {
+			return fmt.Errorf("failed to add column %s to history table: %w", column, err)
 		}
 	}
 
 	return nil
 }
 
+// missingColumns returns the names of the wanted columns that do not exist, sorted.
+func missingColumns(existing map[string]bool, wanted map[string]string) []string {
+	var missing []string
+
+	for column := range wanted {
+		if !existing[column] {
+			missing = append(missing, column)
+		}
+	}
+
+	sort.Strings(missing)
+
+	return missing
+}
+
 // recordMigration records a successfully applied migration to the history table.
 func (m *Migrator) recordMigration(ctx context.Context, record migrationRecord) error {
 	query := fmt.Sprintf(
-		"INSERT INTO %s.%s (version, description, checksum, applied_at, execution_ms) VALUES (?, ?, ?, ?, ?)",
+		"INSERT INTO %s.%s (version, description, checksum, applied_at, execution_ms, baselined) VALUES (?, ?, ?, ?, ?, ?)",
 		m.keyspace, m.historyTable,
 	)
 
-	if err := m.session.Query(query,
+	if err := m.exec(ctx, query,
 		record.version,
 		record.description,
 		record.checksum,
 		time.Now(),
 		record.duration.Milliseconds(),
-	).WithContext(ctx).Consistency(m.consistency).Exec(); err != nil {
+		record.baselined,
+	); err != nil {
 		return fmt.Errorf("failed to record migration %d: %w", record.version, err)
 	}
 
@@ -70,24 +143,104 @@ func (m *Migrator) removeMigration(ctx context.Context, version uint64) error {
 		m.keyspace, m.historyTable,
 	)
 
-	if err := m.session.Query(query, version).WithContext(ctx).Consistency(m.consistency).Exec(); err != nil {
+	if err := m.exec(ctx, query, version); err != nil {
 		return fmt.Errorf("failed to remove migration record %d: %w", version, err)
 	}
 
 	return nil
 }
 
+// markDirty records that a migration is being executed.
+// The record is updated before every statement, so after a failure it points
+// at the statement that failed.
+func (m *Migrator) markDirty(ctx context.Context, state DirtyState) error {
+	query := fmt.Sprintf(
+		"INSERT INTO %s.%s (version, direction, statement, checksum, started_at) VALUES (?, ?, ?, ?, ?)",
+		m.keyspace, m.dirtyTable(),
+	)
+
+	if err := m.exec(ctx, query,
+		state.Version,
+		state.Direction.String(),
+		state.Statement,
+		state.Checksum,
+		state.StartedAt,
+	); err != nil {
+		return fmt.Errorf("failed to mark migration %d as dirty: %w", state.Version, err)
+	}
+
+	return nil
+}
+
+// clearDirty removes the dirty state record of a migration.
+func (m *Migrator) clearDirty(ctx context.Context, version uint64) error {
+	query := fmt.Sprintf(
+		"DELETE FROM %s.%s WHERE version = ?",
+		m.keyspace, m.dirtyTable(),
+	)
+
+	if err := m.exec(ctx, query, version); err != nil {
+		return fmt.Errorf("failed to clear dirty state of migration %d: %w", version, err)
+	}
+
+	return nil
+}
+
+// getDirty returns the dirty state of the migration that failed partway through.
+// Returns nil if no migration is dirty.
+func (m *Migrator) getDirty(ctx context.Context) (*DirtyState, error) {
+	query := fmt.Sprintf(
+		"SELECT version, direction, statement, checksum, started_at FROM %s.%s",
+		m.keyspace, m.dirtyTable(),
+	)
+
+	iter := m.iter(ctx, query)
+
+	var (
+		dirty     *DirtyState
+		version   uint64
+		direction string
+		statement int
+		checksum  string
+		startedAt time.Time
+	)
+
+	for iter.Scan(&version, &direction, &statement, &checksum, &startedAt) {
+		// Only one migration can be dirty at a time, but report the lowest
+		// version should several records ever exist.
+		if dirty != nil && dirty.Version < version {
+			continue
+		}
+
+		dirty = &DirtyState{
+			Version:   version,
+			Direction: Direction(direction),
+			Statement: statement,
+			Checksum:  checksum,
+			StartedAt: startedAt,
+		}
+	}
+
+	if err := iter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to read dirty state: %w", err)
+	}
+
+	return dirty, nil
+}
+
+// dirtyTable returns the name of the dirty state table.
+func (m *Migrator) dirtyTable() string {
+	return m.historyTable + dirtyTableSuffix
+}
+
 // getAppliedMigrations returns all applied migrations from the history table.
 func (m *Migrator) getAppliedMigrations(ctx context.Context) ([]*AppliedMigration, error) {
 	query := fmt.Sprintf(
-		"SELECT version, description, checksum, applied_at, execution_ms FROM %s.%s",
+		"SELECT version, description, checksum, applied_at, execution_ms, baselined FROM %s.%s",
 		m.keyspace, m.historyTable,
 	)
 
-	iter := m.session.Query(query).
-		WithContext(ctx).
-		Consistency(m.consistency).
-		Iter()
+	iter := m.iter(ctx, query)
 
 	var (
 		migrations            []*AppliedMigration
@@ -95,15 +248,17 @@ func (m *Migrator) getAppliedMigrations(ctx context.Context) ([]*AppliedMigratio
 		description, checksum string
 		appliedAt             time.Time
 		executionMs           int64
+		baselined             bool
 	)
 
-	for iter.Scan(&version, &description, &checksum, &appliedAt, &executionMs) {
+	for iter.Scan(&version, &description, &checksum, &appliedAt, &executionMs, &baselined) {
 		migrations = append(migrations, &AppliedMigration{
 			Version:     version,
 			Description: description,
 			Checksum:    checksum,
 			AppliedAt:   appliedAt,
 			ExecutionMs: executionMs,
+			Baselined:   baselined,
 		})
 	}
 
@@ -151,6 +306,12 @@ func (m *Migrator) getAppliedVersions(ctx context.Context) (map[uint64]bool, err
 
 // historyTableExists checks if the history table exists.
 func (m *Migrator) historyTableExists(ctx context.Context) bool {
+	return m.tableExists(ctx, m.historyTable)
+}
+
+// tableExists checks if the given table exists in the migrator keyspace.
+// A table whose schema cannot be read is reported as missing.
+func (m *Migrator) tableExists(ctx context.Context, table string) bool {
 	query := `
 		SELECT table_name
 		FROM system_schema.tables
@@ -159,16 +320,5 @@ func (m *Migrator) historyTableExists(ctx context.Context) bool {
 
 	var tableName string
 
-	if err := m.session.Query(query, m.keyspace, m.historyTable).
-		WithContext(ctx).
-		Consistency(m.consistency).
-		Scan(&tableName); err != nil {
-		if errors.Is(err, gocql.ErrNotFound) {
-			return false
-		}
-
-		return false
-	}
-
-	return true
+	return scanRow(m.iter(ctx, query, m.keyspace, table), &tableName) == nil
 }
@@ -141,12 +141,43 @@ func TestHistorySchemaTemplate(t *testing.T) {
 	td.Cmp(t, formatted, td.Contains(table))
 
 	// Verify it contains expected CQL keywords
-	expectedKeywords := []string{"CREATE TABLE", "IF NOT EXISTS", "version", "description", "checksum", "applied_at", "execution_ms", "PRIMARY KEY"}
+	expectedKeywords := []string{"CREATE TABLE", "IF NOT EXISTS", "version", "description", "checksum", "applied_at", "execution_ms", "baselined", "PRIMARY KEY"}
 	for _, keyword := range expectedKeywords {
 		td.Cmp(t, formatted, td.Contains(keyword))
 	}
 }
 
+func TestMissingColumns(t *testing.T) {
+	wanted := map[string]string{"baselined": "boolean", "tag": "text"}
+
+	td.Cmp(t, missingColumns(map[string]bool{"version": true}, wanted), []string{"baselined", "tag"})
+	td.Cmp(t, missingColumns(map[string]bool{"baselined": true}, wanted), []string{"tag"})
+	td.CmpNil(t, missingColumns(map[string]bool{"baselined": true, "tag": true}, wanted))
+}
+
+func TestHistoryColumns(t *testing.T) {
+	// Every column added later must also be part of the create template.
+	for column, typ := range historyColumns {
+		td.Cmp(t, historySchemaTemplate, td.Contains(column+" "+typ))
+	}
+}
+
+func TestDirtySchemaTemplate(t *testing.T) {
+	formatted := formatHistoryQuery(dirtySchemaTemplate, "test_keyspace", "schema_migrations_dirty")
+
+	td.Cmp(t, formatted, td.Contains("test_keyspace.schema_migrations_dirty"))
+
+	expectedKeywords := []string{"CREATE TABLE", "IF NOT EXISTS", "version", "direction", "statement", "checksum", "started_at", "PRIMARY KEY"}
+	for _, keyword := range expectedKeywords {
+		td.Cmp(t, formatted, td.Contains(keyword))
+	}
+}
+
+func TestMigrator_dirtyTable(t *testing.T) {
+	m := &Migrator{historyTable: "schema_migrations"}
+	td.Cmp(t, m.dirtyTable(), "schema_migrations_dirty")
+}
+
 func TestMigrationRecord(t *testing.T) {
 	// Test migrationRecord structure
 	record := migrationRecord{
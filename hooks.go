@@ -0,0 +1,220 @@
+package scyllamigrate
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Hooks are callbacks invoked at the lifecycle points of a migration run.
+// Every callback is optional. A callback returning an error aborts the run,
+// and the error is returned to the caller wrapped in a *HookError.
+//
+// The After callbacks are also invoked when the step failed, with the event's
+// Err set. In that case the step's error is returned and the error returned
+// by the callback is ignored.
+type Hooks struct {
+	// BeforeMigration is called before a migration starts.
+	BeforeMigration func(ctx context.Context, event MigrationEvent) error
+
+	// AfterMigration is called after a migration finished and was recorded in the history table.
+	AfterMigration func(ctx context.Context, event MigrationEvent) error
+
+	// BeforeStatement is called before a CQL statement of a migration file is executed.
+	BeforeStatement func(ctx context.Context, event StatementEvent) error
+
+	// AfterStatement is called after a CQL statement of a migration file was executed.
+	AfterStatement func(ctx context.Context, event StatementEvent) error
+
+	// BeforeSchemaAgreement is called before waiting for schema agreement.
+	BeforeSchemaAgreement func(ctx context.Context, event SchemaAgreementEvent) error
+
+	// AfterSchemaAgreement is called after waiting for schema agreement.
+	AfterSchemaAgreement func(ctx context.Context, event SchemaAgreementEvent) error
+}
+
+// MigrationEvent describes a migration passed to the migration hooks.
+type MigrationEvent struct {
+	// Version is the migration version.
+	Version uint64
+
+	// Description is the human-readable description.
+	Description string
+
+	// Direction is the direction the migration runs in.
+	Direction Direction
+
+	// Duration is how long the migration took. Zero before the migration.
+	Duration time.Duration
+
+	// Err is the error the migration failed with. Nil before the migration and on success.
+	Err error
+}
+
+// StatementEvent describes a statement passed to the statement hooks.
+type StatementEvent struct {
+	// Version is the version of the migration the statement belongs to.
+	Version uint64
+
+	// Direction is the direction the migration runs in.
+	Direction Direction
+
+	// Index is the 1-based index of the statement within the migration.
+	Index int
+
+	// Text is the CQL statement.
+	Text string
+
+	// Line is the 1-based line where the statement starts in the migration file.
+	Line int
+
+	// Duration is how long the statement took to execute. Zero before the statement.
+	Duration time.Duration
+
+	// Err is the error the statement failed with. Nil before the statement and on success.
+	Err error
+}
+
+// SchemaAgreementEvent describes a schema agreement wait passed to the schema agreement hooks.
+type SchemaAgreementEvent struct {
+	// Version is the migration whose changes are awaited.
+	// It is 0 when waiting after creating the internal tables.
+	Version uint64
+
+	// Direction is the direction the migration runs in. Empty for the internal tables.
+	Direction Direction
+
+	// Duration is how long the wait took. Zero before the wait.
+	Duration time.Duration
+
+	// Err is the error the wait failed with. Nil before the wait and on success.
+	Err error
+}
+
+// Hook names reported by HookError.
+const (
+	hookBeforeMigration       = "BeforeMigration"
+	hookAfterMigration        = "AfterMigration"
+	hookBeforeStatement       = "BeforeStatement"
+	hookAfterStatement        = "AfterStatement"
+	hookBeforeSchemaAgreement = "BeforeSchemaAgreement"
+	hookAfterSchemaAgreement  = "AfterSchemaAgreement"
+)
+
+// runHooks calls the callback selected by pick on every registered Hooks in
+// registration order, and stops at the first error.
+func runHooks[E any](
+	ctx context.Context,
+	hooks []*Hooks,
+	name string,
+	version uint64,
+	pick func(*Hooks) func(context.Context, E) error,
+	event E,
+) error {
+	for _, h := range hooks {
+		fn := pick(h)
+		if fn == nil {
+			continue
+		}
+
+		if err := fn(ctx, event); err != nil {
+			return &HookError{Hook: name, Version: version, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// beforeMigration runs the BeforeMigration hooks.
+func (m *Migrator) beforeMigration(ctx context.Context, event MigrationEvent) error {
+	return runHooks(ctx, m.hooks, hookBeforeMigration, event.Version,
+		func(h *Hooks) func(context.Context, MigrationEvent) error { return h.BeforeMigration },
+		event,
+	)
+}
+
+// afterMigration runs the AfterMigration hooks for a migration that finished
+// with err, and returns the error the migration should fail with.
+func (m *Migrator) afterMigration(ctx context.Context, event MigrationEvent, err error) error {
+	event.Err = err
+
+	hookErr := runHooks(ctx, m.hooks, hookAfterMigration, event.Version,
+		func(h *Hooks) func(context.Context, MigrationEvent) error { return h.AfterMigration },
+		event,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return hookErr
+}
+
+// beforeStatement runs the BeforeStatement hooks.
+func (m *Migrator) beforeStatement(ctx context.Context, event StatementEvent) error {
+	return runHooks(ctx, m.hooks, hookBeforeStatement, event.Version,
+		func(h *Hooks) func(context.Context, StatementEvent) error { return h.BeforeStatement },
+		event,
+	)
+}
+
+// afterStatement runs the AfterStatement hooks for a statement that finished
+// with err, and returns the error the statement should fail with.
+func (m *Migrator) afterStatement(ctx context.Context, event StatementEvent, err error) error {
+	event.Err = err
+
+	hookErr := runHooks(ctx, m.hooks, hookAfterStatement, event.Version,
+		func(h *Hooks) func(context.Context, StatementEvent) error { return h.AfterStatement },
+		event,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return hookErr
+}
+
+// awaitSchemaAgreement waits for schema agreement after the changes of the
+// given migration (version 0 for the internal tables) within the schema
+// agreement timeout, unless waiting has been disabled.
+// The wait is surrounded by the schema agreement hooks.
+func (m *Migrator) awaitSchemaAgreement(ctx context.Context, version uint64, direction Direction) error {
+	if !m.waitForSchemaAgreement {
+		return nil
+	}
+
+	event := SchemaAgreementEvent{Version: version, Direction: direction}
+
+	if err := runHooks(ctx, m.hooks, hookBeforeSchemaAgreement, version,
+		func(h *Hooks) func(context.Context, SchemaAgreementEvent) error { return h.BeforeSchemaAgreement },
+		event,
+	); err != nil {
+		return err
+	}
+
+	waitCtx, cancel := withTimeout(ctx, m.schemaAgreementTimeout)
+	defer cancel()
+
+	start := time.Now()
+
+	var err error
+
+	if err = m.session.AwaitSchemaAgreement(waitCtx); err != nil {
+		err = fmt.Errorf("failed to wait for schema agreement: %w", err)
+	}
+
+	event.Duration = time.Since(start)
+	event.Err = err
+
+	hookErr := runHooks(ctx, m.hooks, hookAfterSchemaAgreement, version,
+		func(h *Hooks) func(context.Context, SchemaAgreementEvent) error { return h.AfterSchemaAgreement },
+		event,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return hookErr
+}
@@ -0,0 +1,110 @@
+package scyllamigrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	td "github.com/maxatome/go-testdeep/td"
+)
+
+func TestMigrator_beforeMigration(t *testing.T) {
+	var calls []string
+
+	record := func(name string, err error) func(context.Context, MigrationEvent) error {
+		return func(_ context.Context, event MigrationEvent) error {
+			calls = append(calls, name)
+			td.Cmp(t, event.Version, uint64(3))
+
+			return err
+		}
+	}
+
+	abort := errors.New("deploy freeze")
+
+	m := &Migrator{}
+	td.CmpNoError(t, WithHooks(
+		&Hooks{BeforeMigration: record("first", nil)},
+		&Hooks{},
+		&Hooks{BeforeMigration: record("second", abort)},
+		&Hooks{BeforeMigration: record("third", nil)},
+	)(m))
+
+	err := m.beforeMigration(context.Background(), MigrationEvent{Version: 3, Direction: Up})
+
+	var he *HookError
+	td.Cmp(t, errors.As(err, &he), true)
+	td.Cmp(t, he.Hook, "BeforeMigration")
+	td.Cmp(t, he.Version, uint64(3))
+	td.CmpErrorIs(t, err, abort)
+
+	// Hooks after the failing one are not called.
+	td.Cmp(t, calls, []string{"first", "second"})
+}
+
+func TestMigrator_afterMigration(t *testing.T) {
+	abort := errors.New("notification failed")
+
+	var received MigrationEvent
+
+	m := &Migrator{}
+	td.CmpNoError(t, WithHooks(&Hooks{
+		AfterMigration: func(_ context.Context, event MigrationEvent) error {
+			received = event
+			return abort
+		},
+	})(m))
+
+	t.Run("success", func(t *testing.T) {
+		err := m.afterMigration(context.Background(), MigrationEvent{Version: 1, Direction: Up}, nil)
+		td.CmpErrorIs(t, err, abort)
+		td.CmpNil(t, received.Err)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		failure := errors.New("statement failed")
+
+		// The migration error takes precedence over the hook error.
+		err := m.afterMigration(context.Background(), MigrationEvent{Version: 1, Direction: Up}, failure)
+		td.Cmp(t, err, failure)
+		td.Cmp(t, received.Err, failure)
+	})
+}
+
+func TestMigrator_statementHooks(t *testing.T) {
+	var events []StatementEvent
+
+	m := &Migrator{}
+	td.CmpNoError(t, WithHooks(&Hooks{
+		BeforeStatement: func(_ context.Context, event StatementEvent) error {
+			events = append(events, event)
+			return nil
+		},
+		AfterStatement: func(_ context.Context, event StatementEvent) error {
+			events = append(events, event)
+			return nil
+		},
+	})(m))
+
+	event := StatementEvent{Version: 2, Direction: Down, Index: 1, Text: "DROP TABLE users", Line: 1}
+
+	td.CmpNoError(t, m.beforeStatement(context.Background(), event))
+	td.CmpNoError(t, m.afterStatement(context.Background(), event, nil))
+	td.Cmp(t, events, []StatementEvent{event, event})
+}
+
+func TestMigrator_awaitSchemaAgreement_Disabled(t *testing.T) {
+	called := false
+
+	m := &Migrator{}
+	td.CmpNoError(t, WithHooks(&Hooks{
+		BeforeSchemaAgreement: func(context.Context, SchemaAgreementEvent) error {
+			called = true
+			return nil
+		},
+	})(m))
+
+	// Without schema agreement there is nothing to wait for and no session is used.
+	td.CmpNoError(t, m.awaitSchemaAgreement(context.Background(), 1, Up))
+	td.Cmp(t, called, false)
+}
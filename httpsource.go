@@ -0,0 +1,108 @@
+package scyllamigrate
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// NewHTTPSource creates a Source from migration files published over HTTP,
+// e.g. as release artifacts.
+//
+// It downloads the manifest at baseURL/DefaultManifest, or at the path set by
+// WithManifest, then every migration file the manifest lists relative to
+// baseURL, and fails with a *ManifestError if a checksum does not match.
+// The files are held in memory; the source does not access the network afterwards.
+func NewHTTPSource(ctx context.Context, baseURL string, opts ...RemoteOption) (*FSSource, error) {
+	cfg := newRemoteConfig(opts)
+	if cfg.manifest == "" {
+		cfg.manifest = DefaultManifest
+	}
+
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, &SourceError{Op: "fetch", Err: err}
+	}
+
+	location := func(name string) string {
+		return base.JoinPath(name).String()
+	}
+
+	data, err := httpGet(ctx, cfg.client, location(cfg.manifest), nil)
+	if err != nil {
+		return nil, &SourceError{Op: "fetch manifest", Err: err}
+	}
+
+	manifest, err := ParseManifest(bytes.NewReader(data))
+	if err != nil {
+		return nil, &SourceError{Op: "fetch manifest", Err: err}
+	}
+
+	files := make(memFS)
+
+	for _, name := range manifest.paths() {
+		if !isRemoteFile(name) {
+			continue
+		}
+
+		data, err := httpGet(ctx, cfg.client, location(name), nil)
+		if err != nil {
+			return nil, &SourceError{Op: "fetch", Err: err}
+		}
+
+		files[name] = data
+	}
+
+	return newMemorySource(files, manifest, location)
+}
+
+// httpGet downloads the body of rawURL. The request is signed by sign unless it is nil.
+func httpGet(ctx context.Context, client *http.Client, rawURL string, sign func(*http.Request)) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if sign != nil {
+		sign(req)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s%s", rawURL, resp.Status, errorDetail(resp.Body))
+	}
+
+	data, err := readRemoteFile(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: %w", rawURL, err)
+	}
+
+	return data, nil
+}
+
+// errorDetail returns ": code: message" for an S3 error response body, empty otherwise.
+func errorDetail(body io.Reader) string {
+	var s3Err struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+
+	if err := xml.NewDecoder(io.LimitReader(body, 64<<10)).Decode(&s3Err); err != nil || s3Err.Code == "" {
+		return ""
+	}
+
+	if s3Err.Message == "" {
+		return ": " + s3Err.Code
+	}
+
+	return ": " + s3Err.Code + ": " + s3Err.Message
+}
@@ -0,0 +1,93 @@
+package scyllamigrate
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	td "github.com/maxatome/go-testdeep/td"
+)
+
+func TestNewHTTPSource(t *testing.T) {
+	published := fstest.MapFS{"releases/v1/" + DefaultManifest: {Data: testManifest(t, remoteMigrations())}}
+
+	for name, file := range remoteMigrations() {
+		published["releases/v1/"+name] = file
+	}
+
+	server := httptest.NewServer(http.FileServerFS(published))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+
+	t.Run("verified files", func(t *testing.T) {
+		source, err := NewHTTPSource(ctx, server.URL+"/releases/v1", WithHTTPClient(server.Client()))
+		td.Require(t).CmpNoError(err)
+
+		td.Cmp(t, source.Versions(), []uint64{1, 2})
+
+		pairs, err := source.List()
+		td.Require(t).CmpNoError(err)
+		td.Cmp(t, pairs[1].Up.Raw, "2025/000002_users.up.cql")
+
+		rc, err := source.ReadUp(1)
+		td.Require(t).CmpNoError(err)
+
+		content, err := io.ReadAll(rc)
+		td.CmpNoError(t, err)
+		td.CmpNoError(t, rc.Close())
+		td.Cmp(t, string(content), "CREATE TABLE init (id int PRIMARY KEY);")
+
+		files, err := source.Files()
+		td.CmpNoError(t, err)
+		td.Cmp(t, files, []string{"2024/000001_init.down.cql", "2024/000001_init.up.cql", "2025/000002_users.up.cql"})
+		td.Cmp(t, sourceFilePath(source, files[0]), server.URL+"/releases/v1/2024/000001_init.down.cql")
+	})
+
+	t.Run("manifest path", func(t *testing.T) {
+		published := remoteMigrations()
+		published["checksums.txt"] = &fstest.MapFile{Data: testManifest(t, remoteMigrations())}
+
+		server := httptest.NewServer(http.FileServerFS(published))
+		t.Cleanup(server.Close)
+
+		source, err := NewHTTPSource(ctx, server.URL, WithManifest("checksums.txt"))
+		td.Require(t).CmpNoError(err)
+		td.Cmp(t, source.Versions(), []uint64{1, 2})
+	})
+
+	t.Run("modified file", func(t *testing.T) {
+		published := remoteMigrations()
+		published[DefaultManifest] = &fstest.MapFile{Data: testManifest(t, remoteMigrations())}
+		published["2025/000002_users.up.cql"] = &fstest.MapFile{Data: []byte("DROP TABLE users;")}
+
+		server := httptest.NewServer(http.FileServerFS(published))
+		t.Cleanup(server.Close)
+
+		_, err := NewHTTPSource(ctx, server.URL)
+
+		var me *ManifestError
+		td.Require(t).True(errors.As(err, &me))
+		td.Cmp(t, me.File, "2025/000002_users.up.cql")
+		td.CmpErrorIs(t, err, ErrManifestMismatch)
+	})
+
+	t.Run("missing manifest", func(t *testing.T) {
+		_, err := NewHTTPSource(ctx, server.URL+"/releases/v2")
+		td.Cmp(t, err, td.ErrorIs(td.Contains("(fetch manifest): GET "+server.URL+"/releases/v2/SHA256SUMS: 404 Not Found")))
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		published := fstest.MapFS{DefaultManifest: {Data: testManifest(t, remoteMigrations())}}
+
+		server := httptest.NewServer(http.FileServerFS(published))
+		t.Cleanup(server.Close)
+
+		_, err := NewHTTPSource(ctx, server.URL)
+		td.Cmp(t, err, td.ErrorIs(td.Contains("(fetch): GET "+server.URL+"/2024/000001_init.down.cql: 404 Not Found")))
+	})
+}
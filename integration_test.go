@@ -2,6 +2,7 @@ package scyllamigrate
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -146,6 +147,7 @@ CREATE INDEX IF NOT EXISTS users_email_idx ON users (email);
 
 	migration1Down := filepath.Join(tmpDir, "000001_create_users.down.cql")
 	err = os.WriteFile(migration1Down, []byte(`
+-- scyllamigrate:allow-destructive
 DROP INDEX IF EXISTS users_email_idx;
 DROP TABLE IF EXISTS users;
 `), 0644)
@@ -168,6 +170,7 @@ CREATE INDEX IF NOT EXISTS posts_user_id_idx ON posts (user_id);
 
 	migration2Down := filepath.Join(tmpDir, "000002_create_posts.down.cql")
 	err = os.WriteFile(migration2Down, []byte(`
+-- scyllamigrate:allow-destructive
 DROP INDEX IF EXISTS posts_user_id_idx;
 DROP TABLE IF EXISTS posts;
 `), 0644)
@@ -195,9 +198,9 @@ func TestIntegration_Up(t *testing.T) {
 	ctx := context.Background()
 
 	// Apply all migrations
-	applied, err := migrator.Up(ctx)
+	result, err := migrator.Up(ctx)
 	td.CmpNoError(t, err)
-	td.Cmp(t, applied, 2)
+	td.Cmp(t, result.Count(), 2)
 
 	// Verify tables exist
 	var tableName string
@@ -212,9 +215,9 @@ func TestIntegration_Up(t *testing.T) {
 	td.Cmp(t, tableName, "posts")
 
 	// Running Up again should not apply anything
-	applied, err = migrator.Up(ctx)
+	result, err = migrator.Up(ctx)
 	td.CmpNoError(t, err)
-	td.Cmp(t, applied, 0)
+	td.Cmp(t, result.Count(), 0)
 }
 
 func TestIntegration_Down(t *testing.T) {
@@ -236,12 +239,12 @@ func TestIntegration_Down(t *testing.T) {
 	ctx := context.Background()
 
 	// Apply migrations first
-	applied, err := migrator.Up(ctx)
+	result, err := migrator.Up(ctx)
 	td.CmpNoError(t, err)
-	td.Cmp(t, applied, 2)
+	td.Cmp(t, result.Count(), 2)
 
 	// Rollback last migration
-	err = migrator.Down(ctx)
+	_, err = migrator.Down(ctx)
 	td.CmpNoError(t, err)
 
 	// Verify posts table is gone
@@ -284,9 +287,9 @@ func TestIntegration_Status(t *testing.T) {
 	td.Cmp(t, len(status.Pending), 2)
 
 	// Apply migrations
-	applied, err := migrator.Up(ctx)
+	result, err := migrator.Up(ctx)
 	td.CmpNoError(t, err)
-	td.Cmp(t, applied, 2)
+	td.Cmp(t, result.Count(), 2)
 
 	// Check status after applying
 	status, err = migrator.Status(ctx)
@@ -315,9 +318,9 @@ func TestIntegration_UpTo(t *testing.T) {
 	ctx := context.Background()
 
 	// Apply up to version 1
-	applied, err := migrator.UpTo(ctx, 1)
+	result, err := migrator.UpTo(ctx, 1)
 	td.CmpNoError(t, err)
-	td.Cmp(t, applied, 1)
+	td.Cmp(t, result.Count(), 1)
 
 	// Verify only users table exists
 	var count int
@@ -332,9 +335,9 @@ func TestIntegration_UpTo(t *testing.T) {
 	td.Cmp(t, count, 0)
 
 	// Apply remaining migrations
-	applied, err = migrator.UpTo(ctx, 2)
+	result, err = migrator.UpTo(ctx, 2)
 	td.CmpNoError(t, err)
-	td.Cmp(t, applied, 1)
+	td.Cmp(t, result.Count(), 1)
 
 	// Verify both tables exist now
 	err = session.Query("SELECT COUNT(*) FROM system_schema.tables WHERE keyspace_name = ? AND table_name = ?",
@@ -362,14 +365,15 @@ func TestIntegration_DownTo(t *testing.T) {
 	ctx := context.Background()
 
 	// Apply all migrations first
-	applied, err := migrator.Up(ctx)
+	result, err := migrator.Up(ctx)
 	td.CmpNoError(t, err)
-	td.Cmp(t, applied, 2)
+	td.Cmp(t, result.Count(), 2)
 
 	// Rollback to version 1 (exclusive, so version 2 should be rolled back)
-	rolledBack, err := migrator.DownTo(ctx, 1)
+	result, err = migrator.DownTo(ctx, 1)
 	td.CmpNoError(t, err)
-	td.Cmp(t, rolledBack, 1)
+	td.Cmp(t, result.Versions(), []uint64{2})
+	td.Cmp(t, result.Executed[0].Direction, Down)
 
 	// Verify posts table is gone
 	var count int
@@ -409,18 +413,18 @@ func TestIntegration_Version(t *testing.T) {
 	td.Cmp(t, version, uint64(0))
 
 	// Apply first migration
-	applied, err := migrator.UpTo(ctx, 1)
+	result, err := migrator.UpTo(ctx, 1)
 	td.CmpNoError(t, err)
-	td.Cmp(t, applied, 1)
+	td.Cmp(t, result.Count(), 1)
 
 	version, err = migrator.Version(ctx)
 	td.CmpNoError(t, err)
 	td.Cmp(t, version, uint64(1))
 
 	// Apply second migration
-	applied, err = migrator.UpTo(ctx, 2)
+	result, err = migrator.UpTo(ctx, 2)
 	td.CmpNoError(t, err)
-	td.Cmp(t, applied, 1)
+	td.Cmp(t, result.Count(), 1)
 
 	version, err = migrator.Version(ctx)
 	td.CmpNoError(t, err)
@@ -446,15 +450,16 @@ func TestIntegration_Steps(t *testing.T) {
 	ctx := context.Background()
 
 	// Apply 1 migration forward
-	err = migrator.Steps(ctx, 1)
+	result, err := migrator.Steps(ctx, 1)
 	td.CmpNoError(t, err)
+	td.Cmp(t, result.Versions(), []uint64{1})
 
 	version, err := migrator.Version(ctx)
 	td.CmpNoError(t, err)
 	td.Cmp(t, version, uint64(1))
 
 	// Apply 1 more migration forward
-	err = migrator.Steps(ctx, 1)
+	_, err = migrator.Steps(ctx, 1)
 	td.CmpNoError(t, err)
 
 	version, err = migrator.Version(ctx)
@@ -462,7 +467,7 @@ func TestIntegration_Steps(t *testing.T) {
 	td.Cmp(t, version, uint64(2))
 
 	// Rollback 1 migration
-	err = migrator.Steps(ctx, -1)
+	_, err = migrator.Steps(ctx, -1)
 	td.CmpNoError(t, err)
 
 	version, err = migrator.Version(ctx)
@@ -470,10 +475,751 @@ func TestIntegration_Steps(t *testing.T) {
 	td.Cmp(t, version, uint64(1))
 
 	// Rollback 1 more migration
-	err = migrator.Steps(ctx, -1)
+	_, err = migrator.Steps(ctx, -1)
 	td.CmpNoError(t, err)
 
+	// Only the applied migrations are rolled back, even if more are requested
+	_, err = migrator.Steps(ctx, 2)
+	td.CmpNoError(t, err)
+
+	result, err = migrator.Steps(ctx, -5)
+	td.CmpNoError(t, err)
+	td.Cmp(t, result.Versions(), []uint64{2, 1})
+
+	_, err = migrator.Steps(ctx, -1)
+	td.CmpErrorIs(t, err, ErrNoChange)
+
 	version, err = migrator.Version(ctx)
 	td.CmpNoError(t, err)
 	td.Cmp(t, version, uint64(0))
 }
+
+func TestIntegration_ChecksumVerification(t *testing.T) {
+	if !shouldRunIntegrationTests() {
+		t.Skip("Integration tests disabled (set SCYLLA_HOSTS and SCYLLA_KEYSPACE to enable)")
+	}
+
+	session, keyspace := getTestSession(t)
+
+	migrationDir := createTestMigrations(t)
+
+	migrator, err := New(session,
+		WithDir(migrationDir),
+		WithKeyspace(keyspace),
+	)
+	td.CmpNoError(t, err)
+	defer migrator.Close()
+
+	ctx := context.Background()
+
+	result, err := migrator.UpTo(ctx, 1)
+	td.CmpNoError(t, err)
+	td.Cmp(t, result.Count(), 1)
+
+	// Edit the already applied migration on disk.
+	err = os.WriteFile(filepath.Join(migrationDir, "000001_create_users.up.cql"),
+		[]byte("CREATE TABLE IF NOT EXISTS users (id UUID PRIMARY KEY);"), 0644)
+	td.CmpNoError(t, err)
+
+	// A new migrator is needed since the source is scanned once on creation.
+	drifted, err := New(session,
+		WithDir(migrationDir),
+		WithKeyspace(keyspace),
+	)
+	td.CmpNoError(t, err)
+	defer drifted.Close()
+
+	_, err = drifted.Up(ctx)
+	td.CmpErrorIs(t, err, ErrChecksumMismatch)
+
+	var ce *ChecksumError
+	td.Cmp(t, errors.As(err, &ce), true)
+	td.Cmp(t, len(ce.Mismatches), 1)
+	td.Cmp(t, ce.Mismatches[0].Version, uint64(1))
+
+	// Opting out allows the run to continue.
+	optOut, err := New(session,
+		WithDir(migrationDir),
+		WithKeyspace(keyspace),
+		WithChecksumVerification(false),
+	)
+	td.CmpNoError(t, err)
+	defer optOut.Close()
+
+	result, err = optOut.Up(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, result.Count(), 1)
+}
+
+func TestIntegration_ConcurrentUp(t *testing.T) {
+	if !shouldRunIntegrationTests() {
+		t.Skip("Integration tests disabled (set SCYLLA_HOSTS and SCYLLA_KEYSPACE to enable)")
+	}
+
+	session, keyspace := getTestSession(t)
+
+	migrationDir := createTestMigrations(t)
+
+	ctx := context.Background()
+
+	results := make(chan int, 2)
+	errs := make(chan error, 2)
+
+	for range 2 {
+		go func() {
+			migrator, err := New(session,
+				WithDir(migrationDir),
+				WithKeyspace(keyspace),
+			)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer migrator.Close()
+
+			result, err := migrator.Up(ctx)
+			errs <- err
+			results <- result.Count()
+		}()
+	}
+
+	total := 0
+
+	for range 2 {
+		td.CmpNoError(t, <-errs)
+		total += <-results
+	}
+
+	// Both migrators ran, but every migration was applied exactly once.
+	td.Cmp(t, total, 2)
+
+	migrator, err := New(session,
+		WithDir(migrationDir),
+		WithKeyspace(keyspace),
+	)
+	td.CmpNoError(t, err)
+	defer migrator.Close()
+
+	info, err := migrator.LockStatus(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, info, td.Nil())
+}
+
+func TestIntegration_LockHeldByAnotherProcess(t *testing.T) {
+	if !shouldRunIntegrationTests() {
+		t.Skip("Integration tests disabled (set SCYLLA_HOSTS and SCYLLA_KEYSPACE to enable)")
+	}
+
+	session, keyspace := getTestSession(t)
+
+	migrationDir := createTestMigrations(t)
+
+	migrator, err := New(session,
+		WithDir(migrationDir),
+		WithKeyspace(keyspace),
+		WithLockTimeout(0),
+	)
+	td.CmpNoError(t, err)
+	defer migrator.Close()
+
+	ctx := context.Background()
+
+	// Simulate a lock left behind by a crashed process.
+	td.CmpNoError(t, migrator.ensureLockTable(ctx))
+
+	stale, err := newLockHolder()
+	td.CmpNoError(t, err)
+	td.CmpNoError(t, migrator.acquireLock(ctx, stale))
+
+	info, err := migrator.LockStatus(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, info.Holder, stale.Holder)
+
+	_, err = migrator.Up(ctx)
+	td.CmpErrorIs(t, err, ErrLocked)
+
+	td.CmpNoError(t, migrator.ForceReleaseLock(ctx))
+
+	result, err := migrator.Up(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, result.Count(), 2)
+}
+
+func TestIntegration_DirtyState(t *testing.T) {
+	if !shouldRunIntegrationTests() {
+		t.Skip("Integration tests disabled (set SCYLLA_HOSTS and SCYLLA_KEYSPACE to enable)")
+	}
+
+	session, keyspace := getTestSession(t)
+
+	migrationDir := createTestMigrations(t)
+
+	// Migration 3 fails at its second statement.
+	err := os.WriteFile(filepath.Join(migrationDir, "000003_broken.up.cql"), []byte(`
+CREATE TABLE IF NOT EXISTS comments (id UUID PRIMARY KEY, body TEXT);
+CREATE TABLE broken (;
+`), 0644)
+	td.CmpNoError(t, err)
+
+	migrator, err := New(session,
+		WithDir(migrationDir),
+		WithKeyspace(keyspace),
+		WithDirtyPolicy(DirtyFail),
+	)
+	td.CmpNoError(t, err)
+	defer migrator.Close()
+
+	ctx := context.Background()
+
+	result, err := migrator.Up(ctx)
+	td.CmpError(t, err)
+	td.Cmp(t, result.Count(), 2)
+
+	status, err := migrator.Status(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, status.Dirty, td.Struct(&DirtyState{Version: 3, Direction: Up, Statement: 2}, nil))
+
+	// Up refuses to run while the database is dirty.
+	_, err = migrator.Up(ctx)
+	td.CmpErrorIs(t, err, ErrDirty)
+
+	var de *DirtyError
+	td.Cmp(t, errors.As(err, &de), true)
+	td.Cmp(t, de.Version, uint64(3))
+	td.Cmp(t, de.Statement, 2)
+
+	// The first statement is reverted by hand, the migration is forced as not applied.
+	td.CmpNoError(t, session.Query("DROP TABLE IF EXISTS comments").Exec())
+	td.CmpNoError(t, migrator.Force(ctx, 2))
+
+	status, err = migrator.Status(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, status.Dirty, td.Nil())
+	td.Cmp(t, status.CurrentVersion, uint64(2))
+
+	td.CmpErrorIs(t, migrator.Force(ctx, 2), ErrNoChange)
+}
+
+func TestIntegration_ResumeDirty(t *testing.T) {
+	if !shouldRunIntegrationTests() {
+		t.Skip("Integration tests disabled (set SCYLLA_HOSTS and SCYLLA_KEYSPACE to enable)")
+	}
+
+	session, keyspace := getTestSession(t)
+
+	migrationDir := createTestMigrations(t)
+
+	// The second statement depends on a table that does not exist yet, and the
+	// first statement is not idempotent, so re-running from the top would fail.
+	err := os.WriteFile(filepath.Join(migrationDir, "000003_resumable.up.cql"), []byte(`
+CREATE TABLE comments (id UUID PRIMARY KEY, body TEXT);
+CREATE INDEX comments_body_idx ON external_comments (body);
+`), 0644)
+	td.CmpNoError(t, err)
+
+	migrator, err := New(session,
+		WithDir(migrationDir),
+		WithKeyspace(keyspace),
+	)
+	td.CmpNoError(t, err)
+	defer migrator.Close()
+
+	ctx := context.Background()
+
+	result, err := migrator.Up(ctx)
+	td.CmpError(t, err)
+	td.Cmp(t, result.Count(), 2)
+
+	// Create the missing table, then resume at the failed statement.
+	td.CmpNoError(t, session.Query("CREATE TABLE external_comments (id UUID PRIMARY KEY, body TEXT)").Exec())
+	td.CmpNoError(t, session.AwaitSchemaAgreement(ctx))
+
+	result, err = migrator.Up(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, result.Count(), 1)
+
+	status, err := migrator.Status(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, status.Dirty, td.Nil())
+	td.Cmp(t, status.CurrentVersion, uint64(3))
+}
+
+func TestIntegration_ResumeDirty_ChecksumChanged(t *testing.T) {
+	if !shouldRunIntegrationTests() {
+		t.Skip("Integration tests disabled (set SCYLLA_HOSTS and SCYLLA_KEYSPACE to enable)")
+	}
+
+	session, keyspace := getTestSession(t)
+
+	migrationDir := createTestMigrations(t)
+	brokenPath := filepath.Join(migrationDir, "000003_broken.up.cql")
+
+	err := os.WriteFile(brokenPath, []byte(`
+CREATE TABLE IF NOT EXISTS comments (id UUID PRIMARY KEY, body TEXT);
+CREATE TABLE broken (;
+`), 0644)
+	td.CmpNoError(t, err)
+
+	migrator, err := New(session, WithDir(migrationDir), WithKeyspace(keyspace))
+	td.CmpNoError(t, err)
+	defer migrator.Close()
+
+	ctx := context.Background()
+
+	_, err = migrator.Up(ctx)
+	td.CmpError(t, err)
+
+	// Fix the file: resuming is refused because the content changed.
+	err = os.WriteFile(brokenPath, []byte(`
+CREATE TABLE IF NOT EXISTS comments (id UUID PRIMARY KEY, body TEXT);
+CREATE TABLE IF NOT EXISTS fixed (id UUID PRIMARY KEY);
+`), 0644)
+	td.CmpNoError(t, err)
+
+	changed, err := New(session, WithDir(migrationDir), WithKeyspace(keyspace))
+	td.CmpNoError(t, err)
+	defer changed.Close()
+
+	_, err = changed.Up(ctx)
+
+	var de *DirtyError
+	td.Cmp(t, errors.As(err, &de), true)
+	td.Cmp(t, de.ChecksumChanged, true)
+
+	// Restarting from scratch is an explicit choice.
+	restart, err := New(session,
+		WithDir(migrationDir),
+		WithKeyspace(keyspace),
+		WithDirtyPolicy(DirtyRestart),
+	)
+	td.CmpNoError(t, err)
+	defer restart.Close()
+
+	result, err := restart.Up(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, result.Count(), 1)
+}
+
+func TestIntegration_GoMigrations(t *testing.T) {
+	if !shouldRunIntegrationTests() {
+		t.Skip("Integration tests disabled (set SCYLLA_HOSTS and SCYLLA_KEYSPACE to enable)")
+	}
+
+	session, keyspace := getTestSession(t)
+
+	migrationDir := createTestMigrations(t)
+
+	backfill := &GoMigration{
+		Version:     3,
+		Description: "backfill_users",
+		Checksum:    "v1",
+		Up: func(ctx context.Context, s *gocql.Session) error {
+			return s.Query("INSERT INTO users (id, email, name) VALUES (uuid(), 'a@example.com', 'A')").
+				WithContext(ctx).Exec()
+		},
+		Down: func(ctx context.Context, s *gocql.Session) error {
+			return s.Query("TRUNCATE users").WithContext(ctx).Exec()
+		},
+	}
+
+	migrator, err := New(session,
+		WithDir(migrationDir),
+		WithKeyspace(keyspace),
+		WithGoMigrations(backfill),
+	)
+	td.CmpNoError(t, err)
+	defer migrator.Close()
+
+	ctx := context.Background()
+
+	status, err := migrator.Status(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, len(status.Pending), 3)
+
+	result, err := migrator.Up(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, result.Count(), 3)
+
+	var count int
+	td.CmpNoError(t, session.Query("SELECT COUNT(*) FROM users").Scan(&count))
+	td.Cmp(t, count, 1)
+
+	history, err := migrator.Applied(ctx)
+	td.CmpNoError(t, err)
+
+	for _, am := range history {
+		if am.Version == 3 {
+			td.Cmp(t, am.Checksum, "go:v1")
+		}
+	}
+
+	result, err = migrator.Down(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, result.Versions(), []uint64{3})
+
+	td.CmpNoError(t, session.Query("SELECT COUNT(*) FROM users").Scan(&count))
+	td.Cmp(t, count, 0)
+
+	version, err := migrator.Version(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, version, uint64(2))
+}
+
+func TestIntegration_Plan(t *testing.T) {
+	if !shouldRunIntegrationTests() {
+		t.Skip("Integration tests disabled (set SCYLLA_HOSTS and SCYLLA_KEYSPACE to enable)")
+	}
+
+	session, keyspace := getTestSession(t)
+
+	migrationDir := createTestMigrations(t)
+
+	migrator, err := New(session,
+		WithDir(migrationDir),
+		WithKeyspace(keyspace),
+	)
+	td.CmpNoError(t, err)
+	defer migrator.Close()
+
+	ctx := context.Background()
+
+	plan, err := migrator.Plan(ctx, TargetLatest())
+	td.CmpNoError(t, err)
+	td.Cmp(t, len(plan), 2)
+	td.Cmp(t, plan[0].Version, uint64(1))
+	td.Cmp(t, len(plan[0].Statements), 2)
+
+	// Planning must not apply anything
+	version, err := migrator.Version(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, version, uint64(0))
+
+	result, err := migrator.Up(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, result.Count(), 2)
+
+	plan, err = migrator.Plan(ctx, TargetSteps(-1))
+	td.CmpNoError(t, err)
+	td.Cmp(t, len(plan), 1)
+	td.Cmp(t, plan[0].Version, uint64(2))
+	td.Cmp(t, plan[0].Direction, Down)
+	td.Cmp(t, plan[0].Statements[0].Text, "DROP INDEX IF EXISTS posts_user_id_idx")
+
+	plan, err = migrator.Plan(ctx, TargetLatest())
+	td.CmpNoError(t, err)
+	td.Cmp(t, len(plan), 0)
+}
+
+func TestIntegration_Hooks(t *testing.T) {
+	if !shouldRunIntegrationTests() {
+		t.Skip("Integration tests disabled (set SCYLLA_HOSTS and SCYLLA_KEYSPACE to enable)")
+	}
+
+	session, keyspace := getTestSession(t)
+
+	migrationDir := createTestMigrations(t)
+
+	var (
+		migrations []MigrationEvent
+		statements []StatementEvent
+		agreements int
+	)
+
+	abort := errors.New("deploy freeze")
+
+	migrator, err := New(session,
+		WithDir(migrationDir),
+		WithKeyspace(keyspace),
+		WithHooks(&Hooks{
+			BeforeMigration: func(_ context.Context, e MigrationEvent) error {
+				if e.Version == 2 {
+					return abort
+				}
+
+				return nil
+			},
+			AfterMigration: func(_ context.Context, e MigrationEvent) error {
+				migrations = append(migrations, e)
+				return nil
+			},
+			AfterStatement: func(_ context.Context, e StatementEvent) error {
+				statements = append(statements, e)
+				return nil
+			},
+			AfterSchemaAgreement: func(context.Context, SchemaAgreementEvent) error {
+				agreements++
+				return nil
+			},
+		}),
+	)
+	td.CmpNoError(t, err)
+	defer migrator.Close()
+
+	ctx := context.Background()
+
+	// The hook aborts the run before migration 2
+	result, err := migrator.Up(ctx)
+	td.CmpErrorIs(t, err, abort)
+	td.Cmp(t, result.Count(), 1)
+
+	td.Cmp(t, len(migrations), 1)
+	td.Cmp(t, migrations[0].Version, uint64(1))
+	td.Cmp(t, migrations[0].Direction, Up)
+	td.CmpNil(t, migrations[0].Err)
+
+	td.Cmp(t, len(statements), 2)
+	td.Cmp(t, statements[1].Index, 2)
+	td.Cmp(t, statements[1].Text, "CREATE INDEX IF NOT EXISTS users_email_idx ON users (email)")
+	td.Cmp(t, agreements > 0, true)
+
+	version, err := migrator.Version(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, version, uint64(1))
+}
+
+func TestIntegration_Baseline(t *testing.T) {
+	if !shouldRunIntegrationTests() {
+		t.Skip("Integration tests disabled (set SCYLLA_HOSTS and SCYLLA_KEYSPACE to enable)")
+	}
+
+	session, keyspace := getTestSession(t)
+
+	migrationDir := createTestMigrations(t)
+
+	migrator, err := New(session,
+		WithDir(migrationDir),
+		WithKeyspace(keyspace),
+	)
+	td.CmpNoError(t, err)
+	defer migrator.Close()
+
+	ctx := context.Background()
+
+	// The users table already exists, created by hand
+	td.CmpNoError(t, session.Query("CREATE TABLE users (id UUID PRIMARY KEY, email TEXT, name TEXT, created_at TIMESTAMP)").
+		WithContext(ctx).Exec())
+
+	_, err = migrator.Baseline(ctx, 7)
+	td.CmpErrorIs(t, err, ErrVersionNotFound)
+
+	baselined, err := migrator.Baseline(ctx, 1)
+	td.CmpNoError(t, err)
+	td.Cmp(t, baselined, 1)
+
+	applied, err := migrator.Applied(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, len(applied), 1)
+	td.Cmp(t, applied[0].Baselined, true)
+	td.Cmp(t, applied[0].Checksum, td.Len(64))
+
+	// Baselining again does nothing
+	baselined, err = migrator.Baseline(ctx, 1)
+	td.CmpNoError(t, err)
+	td.Cmp(t, baselined, 0)
+
+	// Only migration 2 is executed
+	result, err := migrator.Up(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, result.Count(), 1)
+
+	version, err := migrator.Version(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, version, uint64(2))
+}
+
+func TestIntegration_Goto(t *testing.T) {
+	if !shouldRunIntegrationTests() {
+		t.Skip("Integration tests disabled (set SCYLLA_HOSTS and SCYLLA_KEYSPACE to enable)")
+	}
+
+	session, keyspace := getTestSession(t)
+
+	migrationDir := createTestMigrations(t)
+
+	migrator, err := New(session,
+		WithDir(migrationDir),
+		WithKeyspace(keyspace),
+	)
+	td.CmpNoError(t, err)
+	defer migrator.Close()
+
+	ctx := context.Background()
+
+	result, err := migrator.Goto(ctx, 2)
+	td.CmpNoError(t, err)
+	td.Cmp(t, result.Count(), 2)
+
+	result, err = migrator.Goto(ctx, 1)
+	td.CmpNoError(t, err)
+	td.Cmp(t, result.Count(), 1)
+
+	version, err := migrator.Version(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, version, uint64(1))
+
+	result, err = migrator.Goto(ctx, 1)
+	td.CmpNoError(t, err)
+	td.Cmp(t, result.Count(), 0)
+
+	_, err = migrator.Goto(ctx, 9)
+	td.CmpErrorIs(t, err, ErrVersionNotFound)
+
+	// Migration 2 is applied but missing from the source, as after a branch switch
+	result, err = migrator.Goto(ctx, 2)
+	td.CmpNoError(t, err)
+	td.Cmp(t, result.Count(), 1)
+
+	td.CmpNoError(t, os.Remove(filepath.Join(migrationDir, "000002_create_posts.up.cql")))
+	td.CmpNoError(t, os.Remove(filepath.Join(migrationDir, "000002_create_posts.down.cql")))
+
+	branch, err := New(session,
+		WithDir(migrationDir),
+		WithKeyspace(keyspace),
+	)
+	td.CmpNoError(t, err)
+	defer branch.Close()
+
+	_, err = branch.Goto(ctx, 1)
+
+	var me *MissingMigrationError
+	td.Cmp(t, errors.As(err, &me), true)
+	td.Cmp(t, me.Versions, []uint64{2})
+
+	version, err = branch.Version(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, version, uint64(2))
+}
+
+func TestIntegration_OutOfOrder(t *testing.T) {
+	if !shouldRunIntegrationTests() {
+		t.Skip("Integration tests disabled (set SCYLLA_HOSTS and SCYLLA_KEYSPACE to enable)")
+	}
+
+	session, keyspace := getTestSession(t)
+
+	migrationDir := createTestMigrations(t)
+
+	ctx := context.Background()
+
+	migrator, err := New(session,
+		WithDir(migrationDir),
+		WithKeyspace(keyspace),
+	)
+	td.CmpNoError(t, err)
+	defer migrator.Close()
+
+	result, err := migrator.Up(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, result.Count(), 2)
+
+	// Forget migration 1, as if it was merged after migration 2 was applied
+	td.CmpNoError(t, session.Query("DELETE FROM schema_migrations WHERE version = 1").Exec())
+
+	status, err := migrator.Status(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, status.CurrentVersion, uint64(2))
+	td.Cmp(t, status.OutOfOrder, []uint64{1})
+
+	strict, err := New(session,
+		WithDir(migrationDir),
+		WithKeyspace(keyspace),
+		WithOutOfOrderPolicy(OutOfOrderStrict),
+	)
+	td.CmpNoError(t, err)
+	defer strict.Close()
+
+	_, err = strict.Up(ctx)
+	td.CmpErrorIs(t, err, ErrOutOfOrder)
+
+	ignore, err := New(session,
+		WithDir(migrationDir),
+		WithKeyspace(keyspace),
+		WithOutOfOrderPolicy(OutOfOrderIgnore),
+	)
+	td.CmpNoError(t, err)
+	defer ignore.Close()
+
+	result, err = ignore.Up(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, result.Count(), 0)
+	td.Cmp(t, result.Skipped, []uint64{1})
+
+	result, err = migrator.Up(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, result.Count(), 1)
+}
+
+func TestIntegration_Destructive(t *testing.T) {
+	if !shouldRunIntegrationTests() {
+		t.Skip("Integration tests disabled (set SCYLLA_HOSTS and SCYLLA_KEYSPACE to enable)")
+	}
+
+	session, keyspace := getTestSession(t)
+
+	migrationDir := createTestMigrations(t)
+
+	err := os.WriteFile(filepath.Join(migrationDir, "000003_drop_posts.up.cql"), []byte(`
+DROP TABLE IF EXISTS posts;
+`), 0644)
+	td.CmpNoError(t, err)
+
+	ctx := context.Background()
+
+	migrator, err := New(session,
+		WithDir(migrationDir),
+		WithKeyspace(keyspace),
+	)
+	td.CmpNoError(t, err)
+	defer migrator.Close()
+
+	// Migrations 1 and 2 are not destructive.
+	result, err := migrator.UpTo(ctx, 2)
+	td.CmpNoError(t, err)
+	td.Cmp(t, result.Count(), 2)
+
+	_, err = migrator.Up(ctx)
+	td.CmpErrorIs(t, err, ErrDestructive)
+
+	var destructiveErr *DestructiveError
+	td.Cmp(t, errors.As(err, &destructiveErr), true)
+	td.Cmp(t, destructiveErr.Statements, []DestructiveStatement{{
+		Version:   3,
+		Direction: Up,
+		File:      "000003_drop_posts.up.cql",
+		Line:      2,
+		Text:      "DROP TABLE IF EXISTS posts",
+	}})
+
+	version, err := migrator.Version(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, version, uint64(2))
+
+	var confirmed []DestructiveStatement
+
+	refusing, err := New(session,
+		WithDir(migrationDir),
+		WithKeyspace(keyspace),
+		WithDestructiveConfirmation(func(_ context.Context, statements []DestructiveStatement) (bool, error) {
+			confirmed = statements
+			return false, nil
+		}),
+	)
+	td.CmpNoError(t, err)
+	defer refusing.Close()
+
+	_, err = refusing.Up(ctx)
+	td.CmpErrorIs(t, err, ErrDestructive)
+	td.Cmp(t, len(confirmed), 1)
+
+	allowing, err := New(session,
+		WithDir(migrationDir),
+		WithKeyspace(keyspace),
+		WithAllowDestructive(true),
+	)
+	td.CmpNoError(t, err)
+	defer allowing.Close()
+
+	result, err = allowing.Up(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, result.Count(), 1)
+}
@@ -635,9 +635,9 @@ DROP TABLE IF EXISTS test_table;
 	td.CmpNoError(t, err)
 	defer migrator.Close()
 
-	applied, err := migrator.Up(ctx)
+	result, err := migrator.Up(ctx)
 	td.CmpNoError(t, err)
-	td.Cmp(t, applied, 1)
+	td.Cmp(t, result.Count(), 1)
 
 	// Verify table was created
 	var tableName string
@@ -0,0 +1,410 @@
+package scyllamigrate
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// SeverityOff disables a lint rule. Issues are never reported with it.
+const SeverityOff Severity = "off"
+
+// Lint rules.
+const (
+	// LintDropKeyspace flags DROP KEYSPACE.
+	LintDropKeyspace IssueKind = "drop_keyspace"
+
+	// LintDropTable flags DROP TABLE.
+	LintDropTable IssueKind = "drop_table"
+
+	// LintDropColumn flags ALTER TABLE ... DROP.
+	LintDropColumn IssueKind = "drop_column"
+
+	// LintTruncate flags TRUNCATE.
+	LintTruncate IssueKind = "truncate"
+
+	// LintAlterType flags ALTER ... TYPE, which changes the type of a column or UDT field.
+	LintAlterType IssueKind = "alter_type"
+
+	// LintCreateMaterializedView flags CREATE MATERIALIZED VIEW.
+	LintCreateMaterializedView IssueKind = "create_materialized_view"
+
+	// LintCreateIndex flags CREATE INDEX.
+	LintCreateIndex IssueKind = "create_index"
+
+	// LintMissingIfNotExists flags CREATE statements without IF NOT EXISTS.
+	LintMissingIfNotExists IssueKind = "missing_if_not_exists"
+
+	// LintMissingIfExists flags DROP statements without IF EXISTS.
+	LintMissingIfExists IssueKind = "missing_if_exists"
+)
+
+// lintDisableDirective disables lint rules for a whole migration file when it
+// starts a line comment, e.g. "-- scyllamigrate:disable drop_table, truncate".
+// Without rule names it disables every rule.
+const lintDisableDirective = "scyllamigrate:disable"
+
+// LintRule describes a rule checked by Lint.
+type LintRule struct {
+	// Name identifies the rule. It is the Kind of the issues the rule reports.
+	Name IssueKind
+
+	// Severity is the default severity of the rule.
+	Severity Severity
+
+	// Description describes what the rule flags.
+	Description string
+
+	// Down is true if the rule also checks down migrations. Destructive rules
+	// only check up migrations, since undoing an up migration usually means
+	// dropping what it created.
+	Down bool
+
+	// match reports whether the rule flags a statement given its tokens.
+	match func(tokens []string) bool
+}
+
+// lintRules are the rules checked by Lint, in reporting order.
+var lintRules = []LintRule{
+	{
+		Name:        LintDropKeyspace,
+		Severity:    SeverityError,
+		Description: "DROP KEYSPACE deletes a keyspace and all of its data",
+		match:       func(t []string) bool { return hasTokens(t, "DROP", "KEYSPACE") },
+	},
+	{
+		Name:        LintDropTable,
+		Severity:    SeverityError,
+		Description: "DROP TABLE deletes a table and all of its data",
+		match: func(t []string) bool {
+			return hasTokens(t, "DROP", "TABLE") || hasTokens(t, "DROP", "COLUMNFAMILY")
+		},
+	},
+	{
+		Name:        LintDropColumn,
+		Severity:    SeverityError,
+		Description: "ALTER TABLE ... DROP deletes the data of the dropped columns",
+		match: func(t []string) bool {
+			return (hasTokens(t, "ALTER", "TABLE") || hasTokens(t, "ALTER", "COLUMNFAMILY")) &&
+				indexToken(t, 2, "DROP") >= 0
+		},
+	},
+	{
+		Name:        LintTruncate,
+		Severity:    SeverityError,
+		Description: "TRUNCATE deletes all rows of a table",
+		match:       func(t []string) bool { return hasTokens(t, "TRUNCATE") },
+	},
+	{
+		Name:        LintAlterType,
+		Severity:    SeverityError,
+		Description: "ALTER ... TYPE changes the type of existing data",
+		match: func(t []string) bool {
+			if !hasTokens(t, "ALTER") {
+				return false
+			}
+
+			// ALTER TABLE t ALTER c TYPE int, ALTER TYPE u ALTER f TYPE int.
+			i := indexToken(t, 1, "ALTER")
+
+			return i >= 0 && indexToken(t, i+1, "TYPE") >= 0
+		},
+	},
+	{
+		Name:        LintCreateMaterializedView,
+		Severity:    SeverityWarning,
+		Description: "CREATE MATERIALIZED VIEW builds the view from all rows of the base table",
+		Down:        true,
+		match:       func(t []string) bool { return hasTokens(t, "CREATE", "MATERIALIZED", "VIEW") },
+	},
+	{
+		Name:        LintCreateIndex,
+		Severity:    SeverityWarning,
+		Description: "CREATE INDEX builds the index from all rows of the table",
+		Down:        true,
+		match: func(t []string) bool {
+			return hasTokens(t, "CREATE", "INDEX") || hasTokens(t, "CREATE", "CUSTOM", "INDEX")
+		},
+	},
+	{
+		Name:        LintMissingIfNotExists,
+		Severity:    SeverityWarning,
+		Description: "CREATE without IF NOT EXISTS fails when the migration is re-run",
+		Down:        true,
+		match: func(t []string) bool {
+			return hasTokens(t, "CREATE") && !hasTokens(t, "CREATE", "OR", "REPLACE") &&
+				indexSequence(t, "IF", "NOT", "EXISTS") < 0
+		},
+	},
+	{
+		Name:        LintMissingIfExists,
+		Severity:    SeverityWarning,
+		Description: "DROP without IF EXISTS fails when the migration is re-run",
+		Down:        true,
+		match: func(t []string) bool {
+			return hasTokens(t, "DROP") && indexSequence(t, "IF", "EXISTS") < 0
+		},
+	},
+}
+
+// LintRules returns the rules checked by Lint with their default severities.
+func LintRules() []LintRule {
+	rules := make([]LintRule, len(lintRules))
+	copy(rules, lintRules)
+
+	return rules
+}
+
+// LintOption configures Lint.
+type LintOption func(*linter) error
+
+// WithLintSeverity overrides the severity of a lint rule.
+// SeverityOff disables the rule.
+func WithLintSeverity(rule IssueKind, severity Severity) LintOption {
+	return func(l *linter) error {
+		if _, ok := l.severities[rule]; !ok {
+			return fmt.Errorf("unknown lint rule: %s", rule)
+		}
+
+		switch severity {
+		case SeverityError, SeverityWarning, SeverityOff:
+		default:
+			return fmt.Errorf("invalid lint severity: %s (must be error, warning or off)", severity)
+		}
+
+		l.severities[rule] = severity
+
+		return nil
+	}
+}
+
+// linter holds the configuration of Lint.
+type linter struct {
+	severities map[IssueKind]Severity
+}
+
+// Lint checks the statements of the migrations of source for destructive,
+// expensive and non-idempotent operations without connecting to a database.
+// Every issue's Kind is the name of the rule that reported it, and its Line is
+// the line where the statement starts.
+//
+// A migration file disables rules for itself with a line comment:
+//
+//	-- scyllamigrate:disable drop_table, drop_column
+//
+// A directive without rule names disables every rule for the file.
+// Files that cannot be split into statements are skipped; Validate reports them.
+func Lint(source Source, opts ...LintOption) (*ValidationReport, error) {
+	if source == nil {
+		return nil, ErrNoSource
+	}
+
+	l := &linter{severities: make(map[IssueKind]Severity, len(lintRules))}
+	for _, rule := range lintRules {
+		l.severities[rule.Name] = rule.Severity
+	}
+
+	for _, opt := range opts {
+		if err := opt(l); err != nil {
+			return nil, err
+		}
+	}
+
+	pairs, err := source.List()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ValidationReport{Issues: []Issue{}}
+
+	for _, pair := range pairs {
+		if pair.Up != nil {
+			if err := l.lintFile(report, pair.Version, pair.Up, source.ReadUp); err != nil {
+				return nil, err
+			}
+		}
+
+		if pair.Down != nil {
+			if err := l.lintFile(report, pair.Version, pair.Down, source.ReadDown); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// lintFile reports the issues of a single migration file.
+func (l *linter) lintFile(
+	report *ValidationReport,
+	version uint64,
+	m *Migration,
+	read func(version uint64) (io.ReadCloser, error),
+) error {
+	rc, err := read(version)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		return &SourceError{Version: version, Op: "lint", Err: err}
+	}
+
+	// Files that cannot be split are reported by Validate.
+	statements, splitErr := SplitStatements(string(content))
+	if splitErr != nil {
+		return nil
+	}
+
+	disabled, all := lintDisabledRules(string(content))
+	if all {
+		return nil
+	}
+
+	for _, stmt := range statements {
+		tokens := statementTokens(stmt.Text)
+
+		for _, rule := range lintRules {
+			severity := l.severities[rule.Name]
+
+			if severity == SeverityOff || disabled[rule.Name] || (m.Direction == Down && !rule.Down) {
+				continue
+			}
+
+			if !rule.match(tokens) {
+				continue
+			}
+
+			report.add(Issue{
+				Kind:     rule.Name,
+				Severity: severity,
+				Version:  version,
+				File:     m.Raw,
+				Line:     stmt.Line,
+				Message:  rule.Description,
+			})
+		}
+	}
+
+	return nil
+}
+
+// lintDisabledRules returns the rules disabled by the directives in content,
+// and whether a directive disables every rule.
+func lintDisabledRules(content string) (map[IssueKind]bool, bool) {
+	disabled := make(map[IssueKind]bool)
+
+	for _, args := range directives(content, lintDisableDirective) {
+		names := strings.FieldsFunc(args, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+		if len(names) == 0 {
+			return nil, true
+		}
+
+		for _, name := range names {
+			disabled[IssueKind(name)] = true
+		}
+	}
+
+	return disabled, false
+}
+
+// statementTokens splits statement text into upper-cased words and single
+// punctuation characters. Quoted literals and identifiers are kept as a
+// single token including their quotes, so they never match a keyword.
+func statementTokens(text string) []string {
+	var tokens []string
+
+	for i := 0; i < len(text); {
+		c := text[i]
+
+		switch {
+		case isSpaceByte(c):
+			i++
+
+		case isWordByte(c):
+			end := i
+			for end < len(text) && isWordByte(text[end]) {
+				end++
+			}
+
+			tokens = append(tokens, strings.ToUpper(text[i:end]))
+			i = end
+
+		case c == '\'' || c == '"':
+			end := i + 1
+
+			for end < len(text) {
+				if text[end] == c {
+					if end+1 < len(text) && text[end+1] == c {
+						end += 2
+						continue
+					}
+
+					break
+				}
+
+				end++
+			}
+
+			end = min(end+1, len(text))
+			tokens = append(tokens, text[i:end])
+			i = end
+
+		case strings.HasPrefix(text[i:], "$$"):
+			end := strings.Index(text[i+2:], "$$")
+			if end < 0 {
+				end = len(text)
+			} else {
+				end += i + 4
+			}
+
+			tokens = append(tokens, text[i:end])
+			i = end
+
+		default:
+			tokens = append(tokens, text[i:i+1])
+			i++
+		}
+	}
+
+	return tokens
+}
+
+// hasTokens reports whether tokens start with the given keywords.
+func hasTokens(tokens []string, keywords ...string) bool {
+	if len(tokens) < len(keywords) {
+		return false
+	}
+
+	for i, keyword := range keywords {
+		if tokens[i] != keyword {
+			return false
+		}
+	}
+
+	return true
+}
+
+// indexToken returns the index of the first keyword token at or after from, or -1.
+func indexToken(tokens []string, from int, keyword string) int {
+	for i := from; i < len(tokens); i++ {
+		if tokens[i] == keyword {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// indexSequence returns the index where the keywords appear consecutively in tokens, or -1.
+func indexSequence(tokens []string, keywords ...string) int {
+	for i := range tokens {
+		if hasTokens(tokens[i:], keywords...) {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,230 @@
+package scyllamigrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	td "github.com/maxatome/go-testdeep/td"
+)
+
+func TestLint(t *testing.T) {
+	type tcase struct {
+		files    map[string]string
+		opts     []LintOption
+		expected []Issue
+		errorMsg string
+	}
+
+	issue := func(kind IssueKind, severity Severity, file string, line int) Issue {
+		for _, rule := range lintRules {
+			if rule.Name == kind {
+				return Issue{
+					Kind:     kind,
+					Severity: severity,
+					Version:  1,
+					File:     file,
+					Line:     line,
+					Message:  rule.Description,
+				}
+			}
+		}
+
+		t.Fatalf("unknown rule %s", kind)
+
+		return Issue{}
+	}
+
+	tests := map[string]tcase{
+		"safe migration": {
+			files: map[string]string{
+				"000001_users.up.cql":   "CREATE TABLE IF NOT EXISTS users (id int PRIMARY KEY);\nALTER TABLE users ADD email text;",
+				"000001_users.down.cql": "DROP TABLE IF EXISTS users;",
+			},
+			expected: []Issue{},
+		},
+		"destructive up migration": {
+			files: map[string]string{
+				"000001_cleanup.up.cql": "DROP TABLE IF EXISTS old_users;\n" +
+					"ALTER TABLE users DROP legacy;\n" +
+					"TRUNCATE sessions;\n" +
+					"DROP KEYSPACE IF EXISTS legacy;\n" +
+					"ALTER TABLE users ALTER age TYPE bigint;",
+			},
+			expected: []Issue{
+				issue(LintDropTable, SeverityError, "000001_cleanup.up.cql", 1),
+				issue(LintDropColumn, SeverityError, "000001_cleanup.up.cql", 2),
+				issue(LintTruncate, SeverityError, "000001_cleanup.up.cql", 3),
+				issue(LintDropKeyspace, SeverityError, "000001_cleanup.up.cql", 4),
+				issue(LintAlterType, SeverityError, "000001_cleanup.up.cql", 5),
+			},
+		},
+		"destructive rules skip down migrations": {
+			files: map[string]string{
+				"000001_users.up.cql":   "CREATE TABLE IF NOT EXISTS users (id int PRIMARY KEY);",
+				"000001_users.down.cql": "DROP TABLE users;",
+			},
+			expected: []Issue{
+				issue(LintMissingIfExists, SeverityWarning, "000001_users.down.cql", 1),
+			},
+		},
+		"expensive and non-idempotent": {
+			files: map[string]string{
+				"000001_views.up.cql": "CREATE INDEX users_email ON users (email);\n\n" +
+					"CREATE MATERIALIZED VIEW IF NOT EXISTS users_by_email AS SELECT * FROM users " +
+					"WHERE email IS NOT NULL AND id IS NOT NULL PRIMARY KEY (email, id);\n" +
+					"CREATE OR REPLACE FUNCTION f(x int) CALLED ON NULL INPUT RETURNS int LANGUAGE lua AS $$ return x $$;",
+			},
+			expected: []Issue{
+				issue(LintCreateIndex, SeverityWarning, "000001_views.up.cql", 1),
+				issue(LintMissingIfNotExists, SeverityWarning, "000001_views.up.cql", 1),
+				issue(LintCreateMaterializedView, SeverityWarning, "000001_views.up.cql", 3),
+			},
+		},
+		"keywords in literals and quoted identifiers": {
+			files: map[string]string{
+				"000001_data.up.cql": "INSERT INTO notes (id, body) VALUES (1, 'DROP TABLE users');\n" +
+					`ALTER TABLE users ADD "drop" text;`,
+			},
+			expected: []Issue{},
+		},
+		"file suppression": {
+			files: map[string]string{
+				"000001_cleanup.up.cql": "-- scyllamigrate:disable drop_table, truncate\n" +
+					"DROP TABLE IF EXISTS old_users;\n" +
+					"TRUNCATE sessions;\n" +
+					"ALTER TABLE users DROP legacy;",
+			},
+			expected: []Issue{
+				issue(LintDropColumn, SeverityError, "000001_cleanup.up.cql", 4),
+			},
+		},
+		"file suppression of every rule": {
+			files: map[string]string{
+				"000001_cleanup.up.cql": "// scyllamigrate:disable\nDROP TABLE old_users;",
+			},
+			expected: []Issue{},
+		},
+		"severity overrides": {
+			files: map[string]string{
+				"000001_index.up.cql": "CREATE INDEX users_email ON users (email);",
+			},
+			opts: []LintOption{
+				WithLintSeverity(LintCreateIndex, SeverityError),
+				WithLintSeverity(LintMissingIfNotExists, SeverityOff),
+			},
+			expected: []Issue{
+				issue(LintCreateIndex, SeverityError, "000001_index.up.cql", 1),
+			},
+		},
+		"unknown rule": {
+			files:    map[string]string{"000001_a.up.cql": "SELECT now() FROM system.local;"},
+			opts:     []LintOption{WithLintSeverity("no_such_rule", SeverityError)},
+			errorMsg: "unknown lint rule: no_such_rule",
+		},
+		"invalid severity": {
+			files:    map[string]string{"000001_a.up.cql": "SELECT now() FROM system.local;"},
+			opts:     []LintOption{WithLintSeverity(LintDropTable, "fatal")},
+			errorMsg: "invalid lint severity: fatal (must be error, warning or off)",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			fsys := fstest.MapFS{}
+			for filename, content := range tc.files {
+				fsys[filename] = &fstest.MapFile{Data: []byte(content)}
+			}
+
+			source, err := NewFSSource(fsys)
+			td.Require(t).CmpNoError(err)
+
+			report, err := Lint(source, tc.opts...)
+			if tc.errorMsg != "" {
+				td.CmpString(t, err, tc.errorMsg)
+				return
+			}
+
+			td.CmpNoError(t, err)
+			td.Cmp(t, report.Issues, tc.expected)
+		})
+	}
+}
+
+func TestLint_noSource(t *testing.T) {
+	_, err := Lint(nil)
+	td.CmpErrorIs(t, err, ErrNoSource)
+}
+
+func TestLintRules(t *testing.T) {
+	rules := LintRules()
+	td.Cmp(t, len(rules), len(lintRules))
+
+	rules[0].Severity = SeverityOff
+	td.Cmp(t, lintRules[0].Severity, SeverityError)
+}
+
+func TestStatementTokens(t *testing.T) {
+	type tcase struct {
+		input    string
+		expected []string
+	}
+
+	tests := map[string]tcase{
+		"keywords are upper-cased": {
+			input:    "drop table ks.users",
+			expected: []string{"DROP", "TABLE", "KS", ".", "USERS"},
+		},
+		"quoted literals are single tokens": {
+			input:    `INSERT INTO t ("Drop", v) VALUES ('it''s', $$x;y$$)`,
+			expected: []string{"INSERT", "INTO", "T", "(", `"Drop"`, ",", "V", ")", "VALUES", "(", "'it''s'", ",", "$$x;y$$", ")"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			td.Cmp(t, statementTokens(tc.input), tc.expected)
+		})
+	}
+}
+
+func TestLintDisabledRules(t *testing.T) {
+	type tcase struct {
+		content  string
+		expected map[IssueKind]bool
+		all      bool
+	}
+
+	tests := map[string]tcase{
+		"no directive": {
+			content:  "DROP TABLE users;",
+			expected: map[IssueKind]bool{},
+		},
+		"rules": {
+			content:  "--scyllamigrate:disable drop_table\n// scyllamigrate:disable truncate,drop_column\nDROP TABLE users;",
+			expected: map[IssueKind]bool{LintDropTable: true, LintTruncate: true, LintDropColumn: true},
+		},
+		"all rules": {
+			content: "-- scyllamigrate:disable\nDROP TABLE users;",
+			all:     true,
+		},
+		"not a comment": {
+			content:  "SELECT 'scyllamigrate:disable' FROM system.local;",
+			expected: map[IssueKind]bool{},
+		},
+		"other directive": {
+			content:  "-- scyllamigrate:disabled drop_table",
+			expected: map[IssueKind]bool{},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			disabled, all := lintDisabledRules(tc.content)
+			td.Cmp(t, all, tc.all)
+
+			if !tc.all {
+				td.Cmp(t, disabled, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,344 @@
+package scyllamigrate
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+const (
+	// defaultLockTTL is the lease duration of the migration lock.
+	// The lock is renewed by a heartbeat while it is held.
+	defaultLockTTL = time.Minute
+
+	// defaultLockTimeout is how long to wait for the lock held by another process.
+	defaultLockTimeout = 5 * time.Minute
+
+	// lockRetryInterval is the pause between two lock acquisition attempts.
+	lockRetryInterval = time.Second
+
+	// lockTableSuffix is appended to the history table name to build the lock table name.
+	lockTableSuffix = "_lock"
+
+	// lockID is the partition key of the single lock row.
+	lockID = "migrations"
+)
+
+const lockSchemaTemplate = `
+CREATE TABLE IF NOT EXISTS %s.%s (
+    lock_id text,
+    holder text,
+    host text,
+    pid int,
+    acquired_at timestamp,
+    PRIMARY KEY (lock_id)
+)`
+
+// LockInfo describes the current holder of the migration lock.
+type LockInfo struct {
+	// Holder is the unique identity of the lock owner.
+	Holder string
+
+	// Host is the hostname of the process holding the lock.
+	Host string
+
+	// PID is the process ID of the process holding the lock.
+	PID int
+
+	// AcquiredAt is when the lock was acquired.
+	AcquiredAt time.Time
+
+	// ExpiresAt is when the lock lease expires unless it is renewed.
+	ExpiresAt time.Time
+}
+
+// LockStatus returns the current holder of the migration lock.
+// Returns nil if the lock is not held.
+func (m *Migrator) LockStatus(ctx context.Context) (*LockInfo, error) {
+	if !m.tableExists(ctx, m.lockTable()) {
+		return nil, nil
+	}
+
+	query := fmt.Sprintf(
+		"SELECT holder, host, pid, acquired_at, TTL(holder) FROM %s.%s WHERE lock_id = ?",
+		m.keyspace, m.lockTable(),
+	)
+
+	var (
+		info LockInfo
+		ttl  int
+	)
+
+	if err := scanRow(m.iter(ctx, query, lockID), &info.Holder, &info.Host, &info.PID, &info.AcquiredAt, &ttl); err != nil {
+		if errors.Is(err, gocql.ErrNotFound) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to read migration lock: %w", err)
+	}
+
+	info.ExpiresAt = time.Now().Add(time.Duration(ttl) * time.Second)
+
+	return &info, nil
+}
+
+// ForceReleaseLock unconditionally removes the migration lock.
+// Use it only to recover from a lock left behind by a crashed process
+// whose lease has not expired yet.
+func (m *Migrator) ForceReleaseLock(ctx context.Context) error {
+	if !m.tableExists(ctx, m.lockTable()) {
+		return nil
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s.%s WHERE lock_id = ? IF EXISTS", m.keyspace, m.lockTable())
+
+	if _, _, err := m.execCAS(ctx, query, lockID); err != nil {
+		return fmt.Errorf("failed to force release migration lock: %w", err)
+	}
+
+	return nil
+}
+
+// lock acquires the migration lock and starts a heartbeat that renews it.
+// The returned context is cancelled if the lock is lost while it is held.
+// The returned release function stops the heartbeat and releases the lock.
+func (m *Migrator) lock(ctx context.Context) (context.Context, func(), error) {
+	if !m.useLock {
+		return ctx, func() {}, nil
+	}
+
+	if err := m.ensureLockTable(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	holder, err := newLockHolder()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := m.acquireLock(ctx, holder); err != nil {
+		return nil, nil, err
+	}
+
+	m.log(ctx, slog.LevelInfo, "Acquired migration lock", slog.String(logKeyHolder, holder.Holder))
+
+	lockCtx, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go m.heartbeat(lockCtx, holder, cancel, done, stopped)
+
+	release := func() {
+		close(done)
+		<-stopped
+		cancel(nil)
+
+		// The caller context may already be cancelled, but the lock still has to be released.
+		releaseCtx, releaseCancel := context.WithTimeout(context.WithoutCancel(ctx), m.lockTTL)
+		defer releaseCancel()
+
+		if err := m.releaseLock(releaseCtx, holder); err != nil {
+			m.log(releaseCtx, slog.LevelWarn, "Failed to release migration lock", errorAttr(err))
+			return
+		}
+
+		m.log(releaseCtx, slog.LevelInfo, "Released migration lock")
+	}
+
+	return lockCtx, release, nil
+}
+
+// acquireLock tries to insert the lock row until it succeeds or the lock timeout expires.
+func (m *Migrator) acquireLock(ctx context.Context, holder *LockInfo) error {
+	waitCtx := ctx
+
+	if m.lockTimeout > 0 {
+		var cancel context.CancelFunc
+
+		waitCtx, cancel = context.WithTimeout(ctx, m.lockTimeout)
+		defer cancel()
+	}
+
+	query := fmt.Sprintf(
+		"INSERT INTO %s.%s (lock_id, holder, host, pid, acquired_at) VALUES (?, ?, ?, ?, ?) IF NOT EXISTS USING TTL ?",
+		m.keyspace, m.lockTable(),
+	)
+
+	for {
+		applied, existing, err := m.execCAS(waitCtx, query,
+			lockID,
+			holder.Holder,
+			holder.Host,
+			holder.PID,
+			holder.AcquiredAt,
+			lockTTLSeconds(m.lockTTL),
+		)
+		if err != nil {
+			return &LockError{Err: err}
+		}
+
+		if applied {
+			return nil
+		}
+
+		current := lockInfoFromRow(existing)
+
+		if m.lockTimeout <= 0 {
+			return &LockError{Holder: current, Err: ErrLocked}
+		}
+
+		m.log(ctx, slog.LevelInfo, "Migration lock is held by another process, waiting", slog.String(logKeyHolder, current.Holder))
+
+		select {
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			return &LockError{Holder: current, Err: ErrLocked}
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+// heartbeat periodically renews the lock lease until done is closed.
+// If the lease cannot be renewed the lock context is cancelled with ErrLockLost.
+func (m *Migrator) heartbeat(ctx context.Context, holder *LockInfo, cancel context.CancelCauseFunc, done, stopped chan struct{}) {
+	defer close(stopped)
+
+	ticker := time.NewTicker(m.lockTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.renewLock(ctx, holder); err != nil {
+				m.log(ctx, slog.LevelError, "Failed to renew migration lock", errorAttr(err))
+				cancel(err)
+
+				return
+			}
+		}
+	}
+}
+
+// renewLock extends the lock lease if it is still held by the given holder.
+func (m *Migrator) renewLock(ctx context.Context, holder *LockInfo) error {
+	query := fmt.Sprintf(
+		"UPDATE %s.%s USING TTL ? SET holder = ?, host = ?, pid = ?, acquired_at = ? WHERE lock_id = ? IF holder = ?",
+		m.keyspace, m.lockTable(),
+	)
+
+	applied, _, err := m.execCAS(ctx, query,
+		lockTTLSeconds(m.lockTTL),
+		holder.Holder,
+		holder.Host,
+		holder.PID,
+		holder.AcquiredAt,
+		lockID,
+		holder.Holder,
+	)
+	if err != nil {
+		return &LockError{Err: err}
+	}
+
+	if !applied {
+		return &LockError{Err: ErrLockLost}
+	}
+
+	return nil
+}
+
+// releaseLock removes the lock row if it is still held by the given holder.
+func (m *Migrator) releaseLock(ctx context.Context, holder *LockInfo) error {
+	query := fmt.Sprintf("DELETE FROM %s.%s WHERE lock_id = ? IF holder = ?", m.keyspace, m.lockTable())
+
+	applied, _, err := m.execCAS(ctx, query, lockID, holder.Holder)
+	if err != nil {
+		return &LockError{Err: err}
+	}
+
+	if !applied {
+		return &LockError{Err: ErrLockLost}
+	}
+
+	return nil
+}
+
+// ensureLockTable creates the migration lock table if it doesn't exist.
+func (m *Migrator) ensureLockTable(ctx context.Context) error {
+	query := fmt.Sprintf(lockSchemaTemplate, m.keyspace, m.lockTable())
+
+	if err := m.exec(ctx, query); err != nil {
+		return fmt.Errorf("failed to create lock table: %w", err)
+	}
+
+	return m.awaitSchemaAgreement(ctx, 0, "")
+}
+
+// lockTable returns the name of the migration lock table.
+func (m *Migrator) lockTable() string {
+	return m.historyTable + lockTableSuffix
+}
+
+// newLockHolder builds the identity of the current process.
+func newLockHolder() (*LockInfo, error) {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("failed to generate lock holder id: %w", err)
+	}
+
+	pid := os.Getpid()
+
+	return &LockInfo{
+		Holder:     fmt.Sprintf("%s:%d:%s", host, pid, hex.EncodeToString(token)),
+		Host:       host,
+		PID:        pid,
+		AcquiredAt: time.Now(),
+	}, nil
+}
+
+// lockInfoFromRow converts the current values returned by a failed
+// lightweight transaction into a LockInfo.
+func lockInfoFromRow(row map[string]any) *LockInfo {
+	info := &LockInfo{}
+
+	if v, ok := row["holder"].(string); ok {
+		info.Holder = v
+	}
+
+	if v, ok := row["host"].(string); ok {
+		info.Host = v
+	}
+
+	if v, ok := row["pid"].(int); ok {
+		info.PID = v
+	}
+
+	if v, ok := row["acquired_at"].(time.Time); ok {
+		info.AcquiredAt = v
+	}
+
+	return info
+}
+
+// lockTTLSeconds converts the lock lease duration into a CQL TTL value.
+func lockTTLSeconds(ttl time.Duration) int {
+	return max(int(ttl/time.Second), 1)
+}
@@ -0,0 +1,107 @@
+package scyllamigrate
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	td "github.com/maxatome/go-testdeep/td"
+)
+
+func TestMigrator_lockTable(t *testing.T) {
+	m := &Migrator{historyTable: "schema_migrations"}
+	td.Cmp(t, m.lockTable(), "schema_migrations_lock")
+
+	m = &Migrator{historyTable: "custom"}
+	td.Cmp(t, m.lockTable(), "custom_lock")
+}
+
+func TestMigrator_lock_Disabled(t *testing.T) {
+	m := &Migrator{useLock: false}
+	ctx := context.Background()
+
+	lockCtx, release, err := m.lock(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, lockCtx, ctx)
+
+	// Should not panic
+	release()
+}
+
+func TestNewLockHolder(t *testing.T) {
+	first, err := newLockHolder()
+	td.CmpNoError(t, err)
+
+	second, err := newLockHolder()
+	td.CmpNoError(t, err)
+
+	td.Cmp(t, first.PID, os.Getpid())
+	td.Cmp(t, first.Host, td.NotEmpty())
+	td.Cmp(t, first.AcquiredAt.IsZero(), false)
+	td.Cmp(t, strings.HasPrefix(first.Holder, first.Host+":"), true)
+
+	// Every holder must be unique even within the same process.
+	td.Cmp(t, first.Holder != second.Holder, true)
+}
+
+func TestLockInfoFromRow(t *testing.T) {
+	acquiredAt := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	type tcase struct {
+		row      map[string]any
+		expected *LockInfo
+	}
+	tests := map[string]tcase{
+		"full row": {
+			row: map[string]any{
+				"holder":      "host-a:42:abcd",
+				"host":        "host-a",
+				"pid":         42,
+				"acquired_at": acquiredAt,
+			},
+			expected: &LockInfo{
+				Holder:     "host-a:42:abcd",
+				Host:       "host-a",
+				PID:        42,
+				AcquiredAt: acquiredAt,
+			},
+		},
+		"empty row": {
+			row:      map[string]any{},
+			expected: &LockInfo{},
+		},
+		"unexpected types are ignored": {
+			row: map[string]any{
+				"holder": 1,
+				"pid":    "42",
+			},
+			expected: &LockInfo{},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			td.Cmp(t, lockInfoFromRow(tt.row), tt.expected)
+		})
+	}
+}
+
+func TestLockTTLSeconds(t *testing.T) {
+	type tcase struct {
+		ttl      time.Duration
+		expected int
+	}
+	tests := map[string]tcase{
+		"one minute":       {ttl: time.Minute, expected: 60},
+		"fractional":       {ttl: 1500 * time.Millisecond, expected: 1},
+		"below one second": {ttl: 10 * time.Millisecond, expected: 1},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			td.Cmp(t, lockTTLSeconds(tt.ttl), tt.expected)
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package scyllamigrate
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Attribute keys of the log records emitted by the Migrator.
+const (
+	logKeyKeyspace       = "keyspace"
+	logKeyVersion        = "version"
+	logKeyDirection      = "direction"
+	logKeyDescription    = "description"
+	logKeyStatementIndex = "statement_index"
+	logKeyDuration       = "duration_ms"
+	logKeyHolder         = "holder"
+	logKeyError          = "error"
+)
+
+// log emits a record with the given level and attributes if a logger is configured.
+// Every record carries the keyspace.
+func (m *Migrator) log(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	if m.logger == nil {
+		return
+	}
+
+	attrs = append([]slog.Attr{slog.String(logKeyKeyspace, m.keyspace)}, attrs...)
+
+	m.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// versionAttr returns the log attribute of a migration version.
+func versionAttr(version uint64) slog.Attr {
+	return slog.Uint64(logKeyVersion, version)
+}
+
+// directionAttr returns the log attribute of a migration direction.
+func directionAttr(direction Direction) slog.Attr {
+	return slog.String(logKeyDirection, direction.String())
+}
+
+// descriptionAttr returns the log attribute of a migration description.
+func descriptionAttr(description string) slog.Attr {
+	return slog.String(logKeyDescription, description)
+}
+
+// statementAttr returns the log attribute of a 1-based statement index.
+func statementAttr(index int) slog.Attr {
+	return slog.Int(logKeyStatementIndex, index)
+}
+
+// durationAttr returns the log attribute of a duration in milliseconds.
+func durationAttr(d time.Duration) slog.Attr {
+	return slog.Int64(logKeyDuration, d.Milliseconds())
+}
+
+// errorAttr returns the log attribute of an error.
+func errorAttr(err error) slog.Attr {
+	return slog.String(logKeyError, err.Error())
+}
+
+// logHandler adapts a *log.Logger to slog.Handler.
+// Records at info level and above are printed as the level and message
+// followed by key=value pairs of the attributes.
+type logHandler struct {
+	logger *log.Logger
+
+	// attrs are the attributes added with WithAttrs, already formatted.
+	attrs string
+
+	// prefix is the key prefix of the groups opened with WithGroup.
+	prefix string
+}
+
+// Enabled reports whether records of the given level are printed.
+func (*logHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= slog.LevelInfo
+}
+
+// Handle prints the record.
+func (h *logHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+
+	b.WriteString(r.Level.String())
+	b.WriteByte(' ')
+	b.WriteString(r.Message)
+	b.WriteString(h.attrs)
+
+	r.Attrs(func(a slog.Attr) bool {
+		appendAttr(&b, h.prefix, a)
+		return true
+	})
+
+	h.logger.Print(b.String())
+
+	return nil
+}
+
+// WithAttrs returns a handler that prints the given attributes with every record.
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+
+	b.WriteString(h.attrs)
+
+	for _, a := range attrs {
+		appendAttr(&b, h.prefix, a)
+	}
+
+	return &logHandler{logger: h.logger, attrs: b.String(), prefix: h.prefix}
+}
+
+// WithGroup returns a handler that qualifies the keys of subsequent attributes with name.
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	return &logHandler{logger: h.logger, attrs: h.attrs, prefix: h.prefix + name + "."}
+}
+
+// appendAttr formats a as " key=value", expanding groups into dotted keys.
+func appendAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+
+		for _, ga := range a.Value.Group() {
+			appendAttr(b, prefix, ga)
+		}
+
+		return
+	}
+
+	b.WriteByte(' ')
+	b.WriteString(prefix)
+	b.WriteString(a.Key)
+	b.WriteByte('=')
+	b.WriteString(quoteLogValue(a.Value.String()))
+}
+
+// quoteLogValue quotes s if it is empty or contains spaces, quotes or control characters.
+func quoteLogValue(s string) string {
+	if s == "" {
+		return `""`
+	}
+
+	for _, r := range s {
+		if unicode.IsSpace(r) || r == '"' || r == '=' || !unicode.IsPrint(r) {
+			return strconv.Quote(s)
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,68 @@
+package scyllamigrate
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"log/slog"
+	"testing"
+
+	td "github.com/maxatome/go-testdeep/td"
+)
+
+func TestLogHandler(t *testing.T) {
+	type tcase struct {
+		log      func(l *slog.Logger)
+		expected string
+	}
+
+	tests := map[string]tcase{
+		"message only": {
+			log:      func(l *slog.Logger) { l.Info("Released migration lock") },
+			expected: "INFO Released migration lock\n",
+		},
+		"attributes": {
+			log: func(l *slog.Logger) {
+				l.LogAttrs(context.Background(), slog.LevelInfo, "Applied migration",
+					slog.String("keyspace", "myapp"), slog.Uint64("version", 3), slog.Int64("duration_ms", 12),
+				)
+			},
+			expected: "INFO Applied migration keyspace=myapp version=3 duration_ms=12\n",
+		},
+		"key value pairs": {
+			log:      func(l *slog.Logger) { l.Warn("Resuming dirty migration", "version", 2, "direction", "up") },
+			expected: "WARN Resuming dirty migration version=2 direction=up\n",
+		},
+		"quoted values": {
+			log:      func(l *slog.Logger) { l.Info("Applying migration", "description", "create users", "empty", "") },
+			expected: `INFO Applying migration description="create users" empty=""` + "\n",
+		},
+		"with attrs and group": {
+			log: func(l *slog.Logger) {
+				l.With("keyspace", "myapp").WithGroup("lock").Info("Acquired", "holder", "host:1", slog.Group("ttl", "s", 60))
+			},
+			expected: "INFO Acquired keyspace=myapp lock.holder=host:1 lock.ttl.s=60\n",
+		},
+		"debug is dropped": {
+			log:      func(l *slog.Logger) { l.Debug("Executed statement", "statement_index", 1) },
+			expected: "",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			tc.log(slog.New(&logHandler{logger: log.New(&buf, "", 0)}))
+			td.Cmp(t, buf.String(), tc.expected)
+		})
+	}
+}
+
+func TestQuoteLogValue(t *testing.T) {
+	td.Cmp(t, quoteLogValue("up"), "up")
+	td.Cmp(t, quoteLogValue(""), `""`)
+	td.Cmp(t, quoteLogValue("a b"), `"a b"`)
+	td.Cmp(t, quoteLogValue(`say "hi"`), `"say \"hi\""`)
+	td.Cmp(t, quoteLogValue("k=v"), `"k=v"`)
+}
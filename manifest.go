@@ -0,0 +1,374 @@
+package scyllamigrate
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultManifest is the name of the checksum manifest read by remote sources.
+const DefaultManifest = "SHA256SUMS"
+
+// maxRemoteFileSize limits the size of a file downloaded by a remote source.
+const maxRemoteFileSize = 32 << 20
+
+// Manifest maps the paths of migration files, relative to the root of a
+// source, to the hex-encoded SHA-256 checksums of their content.
+type Manifest map[string]string
+
+// NewManifest computes the manifest of the migration files of fsys,
+// e.g. to publish it next to the files as DefaultManifest.
+func NewManifest(fsys fs.FS) (Manifest, error) {
+	manifest := make(Manifest)
+
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() || !isRemoteFile(name) {
+			return nil
+		}
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+
+		manifest[name] = fileChecksum(data)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// ParseManifest reads a manifest in the format of sha256sum:
+//
+//	<checksum>  <path>
+//
+// Paths are slash-separated and relative to the root of the source.
+// Blank lines and lines starting with # are ignored.
+func ParseManifest(r io.Reader) (Manifest, error) {
+	manifest := make(Manifest)
+	scanner := bufio.NewScanner(r)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		checksum, name, ok := strings.Cut(text, " ")
+		// sha256sum marks files read in binary mode with "*".
+		name = strings.TrimPrefix(strings.TrimLeft(name, " "), "*")
+		name = strings.TrimPrefix(name, "./")
+
+		if !ok || !isChecksum(checksum) || !fs.ValidPath(name) || name == "." {
+			return nil, fmt.Errorf("invalid manifest line %d: %q", line, text)
+		}
+
+		if _, ok := manifest[name]; ok {
+			return nil, fmt.Errorf("invalid manifest line %d: duplicate path %s", line, name)
+		}
+
+		manifest[name] = strings.ToLower(checksum)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// WriteTo writes the manifest in the format read by ParseManifest, sorted by path.
+func (m Manifest) WriteTo(w io.Writer) (int64, error) {
+	var b bytes.Buffer
+
+	for _, name := range m.paths() {
+		fmt.Fprintf(&b, "%s  %s\n", m[name], name)
+	}
+
+	return b.WriteTo(w)
+}
+
+// paths returns the paths of the manifest in sorted order.
+func (m Manifest) paths() []string {
+	paths := make([]string, 0, len(m))
+	for name := range m {
+		paths = append(paths, name)
+	}
+
+	sort.Strings(paths)
+
+	return paths
+}
+
+// verify returns a *ManifestError for the first file that is not listed or
+// whose checksum differs, or for a listed migration file that is missing.
+func (m Manifest) verify(files memFS) error {
+	for _, name := range files.paths() {
+		expected, ok := m[name]
+		actual := fileChecksum(files[name])
+
+		if !ok || expected != actual {
+			return &ManifestError{File: name, Expected: expected, Actual: actual}
+		}
+	}
+
+	for _, name := range m.paths() {
+		if _, ok := files[name]; !ok && isRemoteFile(name) {
+			return &ManifestError{File: name, Expected: m[name]}
+		}
+	}
+
+	return nil
+}
+
+// fileChecksum returns the hex-encoded SHA-256 checksum of data.
+func fileChecksum(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// isChecksum reports whether s is a hex-encoded SHA-256 checksum.
+func isChecksum(s string) bool {
+	if len(s) != sha256.Size*2 {
+		return false
+	}
+
+	_, err := hex.DecodeString(s)
+
+	return err == nil
+}
+
+// isRemoteFile reports whether a remote source reads the file,
+// i.e. whether its name is or looks like a migration filename.
+// Files that only look like migrations are kept so Validate can report them.
+func isRemoteFile(name string) bool {
+	return migrationLikeRegex.MatchString(path.Base(name))
+}
+
+// RemoteOption configures a remote migration source.
+type RemoteOption func(*remoteConfig)
+
+type remoteConfig struct {
+	client   *http.Client
+	manifest string
+}
+
+// newRemoteConfig returns the configuration of a remote source with opts applied.
+func newRemoteConfig(opts []RemoteOption) *remoteConfig {
+	cfg := &remoteConfig{
+		client: &http.Client{Timeout: time.Minute},
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// WithHTTPClient sets the HTTP client of HTTP and S3 sources.
+// The default client times out requests after a minute.
+func WithHTTPClient(client *http.Client) RemoteOption {
+	return func(c *remoteConfig) {
+		if client != nil {
+			c.client = client
+		}
+	}
+}
+
+// WithManifest sets the path of the checksum manifest relative to the root of the source.
+// The files of the source are verified against it and must all be listed.
+// HTTP sources always read a manifest, DefaultManifest unless set.
+func WithManifest(name string) RemoteOption {
+	return func(c *remoteConfig) {
+		c.manifest = name
+	}
+}
+
+// newMemorySource verifies files against manifest unless it is nil and
+// returns a source serving them from memory.
+func newMemorySource(files memFS, manifest Manifest, location func(name string) string) (*FSSource, error) {
+	if manifest != nil {
+		if err := manifest.verify(files); err != nil {
+			return nil, err
+		}
+	}
+
+	return newFSSource(files, location)
+}
+
+// readRemoteFile reads a downloaded file, failing if it exceeds maxRemoteFileSize.
+func readRemoteFile(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxRemoteFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > maxRemoteFileSize {
+		return nil, fmt.Errorf("file exceeds %d bytes", maxRemoteFileSize)
+	}
+
+	return data, nil
+}
+
+// memFS is a read-only fs.FS of files held in memory, keyed by slash-separated path.
+// Directories are implied by the paths of the files.
+type memFS map[string][]byte
+
+// Open implements fs.FS.
+func (m memFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if data, ok := m[name]; ok {
+		return &memFile{Reader: bytes.NewReader(data), info: memInfo{name: path.Base(name), size: int64(len(data))}}, nil
+	}
+
+	if name == "." || len(m.entries(name)) > 0 {
+		return &memFile{Reader: bytes.NewReader(nil), info: memInfo{name: path.Base(name), dir: true}, entries: m.entries(name)}, nil
+	}
+
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (m memFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	entries := m.entries(name)
+	if name != "." && len(entries) == 0 {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	return entries, nil
+}
+
+// entries returns the files and directories directly within dir, sorted by name.
+func (m memFS) entries(dir string) []fs.DirEntry {
+	prefix := dir + "/"
+	if dir == "." {
+		prefix = ""
+	}
+
+	seen := make(map[string]bool)
+
+	var entries []fs.DirEntry
+
+	for _, name := range m.paths() {
+		rest, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+
+		child, _, nested := strings.Cut(rest, "/")
+		if seen[child] {
+			continue
+		}
+
+		seen[child] = true
+		info := memInfo{name: child, dir: nested}
+
+		if !nested {
+			info.size = int64(len(m[name]))
+		}
+
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	return entries
+}
+
+// paths returns the paths of the files in sorted order.
+func (m memFS) paths() []string {
+	paths := make([]string, 0, len(m))
+	for name := range m {
+		paths = append(paths, name)
+	}
+
+	sort.Strings(paths)
+
+	return paths
+}
+
+// memFile is an open file or directory of a memFS.
+type memFile struct {
+	*bytes.Reader
+	info    memInfo
+	entries []fs.DirEntry // entries of a directory not read yet.
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (f *memFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !f.info.dir {
+		return nil, &fs.PathError{Op: "readdir", Path: f.info.name, Err: errors.New("not a directory")}
+	}
+
+	if n <= 0 {
+		entries := f.entries
+		f.entries = nil
+
+		return entries, nil
+	}
+
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+
+	n = min(n, len(f.entries))
+	entries := f.entries[:n]
+	f.entries = f.entries[n:]
+
+	return entries, nil
+}
+
+// Stat implements fs.File.
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+// Close implements fs.File.
+func (*memFile) Close() error { return nil }
+
+// memInfo describes a file or directory of a memFS.
+type memInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (i memInfo) Name() string       { return i.name }
+func (i memInfo) Size() int64        { return i.size }
+func (i memInfo) ModTime() time.Time { return time.Time{} }
+func (i memInfo) IsDir() bool        { return i.dir }
+func (memInfo) Sys() any             { return nil }
+
+func (i memInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0o555
+	}
+
+	return 0o444
+}
@@ -0,0 +1,187 @@
+package scyllamigrate
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	td "github.com/maxatome/go-testdeep/td"
+)
+
+// remoteMigrations returns migration files as published by a remote source.
+func remoteMigrations() fstest.MapFS {
+	return fstest.MapFS{
+		"2024/000001_init.up.cql":   {Data: []byte("CREATE TABLE init (id int PRIMARY KEY);")},
+		"2024/000001_init.down.cql": {Data: []byte("DROP TABLE init;")},
+		"2025/000002_users.up.cql":  {Data: []byte("CREATE TABLE users (id int PRIMARY KEY);")},
+		"README.md":                 {Data: []byte("# Migrations")},
+	}
+}
+
+// testManifest returns the manifest of fsys.
+func testManifest(t *testing.T, fsys fstest.MapFS) []byte {
+	t.Helper()
+
+	manifest, err := NewManifest(fsys)
+	td.Require(t).CmpNoError(err)
+
+	var b bytes.Buffer
+
+	_, err = manifest.WriteTo(&b)
+	td.Require(t).CmpNoError(err)
+
+	return b.Bytes()
+}
+
+func TestNewManifest(t *testing.T) {
+	manifest, err := NewManifest(remoteMigrations())
+	td.Require(t).CmpNoError(err)
+
+	td.Cmp(t, manifest, Manifest{
+		"2024/000001_init.up.cql":   fileChecksum([]byte("CREATE TABLE init (id int PRIMARY KEY);")),
+		"2024/000001_init.down.cql": fileChecksum([]byte("DROP TABLE init;")),
+		"2025/000002_users.up.cql":  fileChecksum([]byte("CREATE TABLE users (id int PRIMARY KEY);")),
+	})
+
+	// The written manifest reads back the same.
+	parsed, err := ParseManifest(bytes.NewReader(testManifest(t, remoteMigrations())))
+	td.CmpNoError(t, err)
+	td.Cmp(t, parsed, manifest)
+}
+
+func TestParseManifest(t *testing.T) {
+	sum := strings.Repeat("ab", 32)
+
+	type tcase struct {
+		input       string
+		expected    Manifest
+		expectError string
+	}
+
+	tests := map[string]tcase{
+		"sha256sum output": {
+			input: sum + "  000001_init.up.cql\n" + strings.ToUpper(sum) + " *2024/000002_users.up.cql\n",
+			expected: Manifest{
+				"000001_init.up.cql":       sum,
+				"2024/000002_users.up.cql": sum,
+			},
+		},
+		"comments and blank lines": {
+			input:    "# release v1.2.0\n\n" + sum + "  ./000001_init.up.cql\n",
+			expected: Manifest{"000001_init.up.cql": sum},
+		},
+		"empty": {
+			input:    "",
+			expected: Manifest{},
+		},
+		"invalid checksum": {
+			input:       "abc  000001_init.up.cql\n",
+			expectError: "invalid manifest line 1",
+		},
+		"missing path": {
+			input:       sum + "\n",
+			expectError: "invalid manifest line 1",
+		},
+		"path outside the root": {
+			input:       sum + "  ../000001_init.up.cql\n",
+			expectError: "invalid manifest line 1",
+		},
+		"duplicate path": {
+			input:       sum + "  000001_init.up.cql\n" + sum + "  000001_init.up.cql\n",
+			expectError: "invalid manifest line 2: duplicate path 000001_init.up.cql",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			manifest, err := ParseManifest(strings.NewReader(tc.input))
+			if tc.expectError != "" {
+				td.Cmp(t, err, td.ErrorIs(td.Contains(tc.expectError)))
+				return
+			}
+
+			td.CmpNoError(t, err)
+			td.Cmp(t, manifest, tc.expected)
+		})
+	}
+}
+
+func TestManifest_verify(t *testing.T) {
+	files := memFS{
+		"000001_init.up.cql":   []byte("CREATE TABLE init;"),
+		"000001_init.down.cql": []byte("DROP TABLE init;"),
+	}
+
+	type tcase struct {
+		manifest Manifest
+		expected any
+	}
+
+	tests := map[string]tcase{
+		"match": {
+			manifest: Manifest{
+				"000001_init.up.cql":   fileChecksum(files["000001_init.up.cql"]),
+				"000001_init.down.cql": fileChecksum(files["000001_init.down.cql"]),
+				"README.md":            fileChecksum([]byte("not a migration")),
+			},
+			expected: nil,
+		},
+		"checksum": {
+			manifest: Manifest{
+				"000001_init.up.cql":   fileChecksum([]byte("CREATE TABLE other;")),
+				"000001_init.down.cql": fileChecksum(files["000001_init.down.cql"]),
+			},
+			expected: &ManifestError{
+				File:     "000001_init.up.cql",
+				Expected: fileChecksum([]byte("CREATE TABLE other;")),
+				Actual:   fileChecksum(files["000001_init.up.cql"]),
+			},
+		},
+		"not listed": {
+			manifest: Manifest{
+				"000001_init.up.cql": fileChecksum(files["000001_init.up.cql"]),
+			},
+			expected: &ManifestError{
+				File:   "000001_init.down.cql",
+				Actual: fileChecksum(files["000001_init.down.cql"]),
+			},
+		},
+		"missing": {
+			manifest: Manifest{
+				"000001_init.up.cql":   fileChecksum(files["000001_init.up.cql"]),
+				"000001_init.down.cql": fileChecksum(files["000001_init.down.cql"]),
+				"000002_users.up.cql":  fileChecksum([]byte("CREATE TABLE users;")),
+			},
+			expected: &ManifestError{
+				File:     "000002_users.up.cql",
+				Expected: fileChecksum([]byte("CREATE TABLE users;")),
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := tc.manifest.verify(files)
+			if tc.expected == nil {
+				td.CmpNoError(t, err)
+				return
+			}
+
+			td.Cmp(t, err, tc.expected)
+		})
+	}
+}
+
+func TestMemFS(t *testing.T) {
+	fsys := memFS{
+		"000001_init.up.cql":         []byte("CREATE TABLE init;"),
+		"2024/000002_users.up.cql":   []byte("CREATE TABLE users;"),
+		"2024/q1/000003_a.up.cql":    []byte("CREATE TABLE a;"),
+		"2024-old/000004_b.up.cql":   []byte("CREATE TABLE b;"),
+		"2024-old/000004_b.down.cql": []byte("DROP TABLE b;"),
+	}
+
+	td.CmpNoError(t, fstest.TestFS(fsys,
+		"000001_init.up.cql", "2024/000002_users.up.cql", "2024/q1/000003_a.up.cql", "2024-old/000004_b.down.cql"))
+}
@@ -31,7 +31,7 @@ type Migration struct {
 	// Direction indicates whether this is an up or down migration.
 	Direction Direction
 
-	// Raw is the original filename.
+	// Raw is the path of the file within its source, e.g. "2024/000001_create_users.up.cql".
 	Raw string
 }
 
@@ -60,6 +60,18 @@ func (p *MigrationPair) HasDown() bool {
 	return p.Down != nil
 }
 
+// file returns the path of the up migration, or of the down migration if there is no up migration.
+func (p *MigrationPair) file() string {
+	switch {
+	case p.Up != nil:
+		return p.Up.Raw
+	case p.Down != nil:
+		return p.Down.Raw
+	default:
+		return ""
+	}
+}
+
 // AppliedMigration represents a migration that has been applied to the database.
 type AppliedMigration struct {
 	// Version is the sequential version number.
@@ -76,6 +88,39 @@ type AppliedMigration struct {
 
 	// ExecutionMs is how long the migration took to execute in milliseconds.
 	ExecutionMs int64
+
+	// Baselined is true if the migration was recorded by Migrator.Baseline
+	// without being executed.
+	Baselined bool
+}
+
+// DirtyState describes a migration that failed partway through.
+type DirtyState struct {
+	// Version is the migration version.
+	Version uint64
+
+	// Direction is the direction the migration was executed in.
+	Direction Direction
+
+	// Statement is the 1-based index of the statement that was executing
+	// when the migration failed.
+	Statement int
+
+	// Checksum is the SHA-256 hash of the migration content that was executed.
+	Checksum string
+
+	// StartedAt is when the migration was started.
+	StartedAt time.Time
+}
+
+// from returns the 1-based statement index to start the given migration at.
+// It is the failed statement if the state refers to that migration, and 1 otherwise.
+func (d *DirtyState) from(version uint64, direction Direction) int {
+	if d == nil || d.Version != version || d.Direction != direction || d.Statement < 1 {
+		return 1
+	}
+
+	return d.Statement
 }
 
 // Status represents the current migration status.
@@ -83,11 +128,42 @@ type Status struct {
 	// CurrentVersion is the latest applied migration version (0 if none).
 	CurrentVersion uint64
 
+	// Dirty is the migration that failed partway through (nil if none).
+	Dirty *DirtyState
+
 	// Applied is the list of applied migrations.
 	Applied []*AppliedMigration
 
 	// Pending is the list of migrations that have not been applied yet.
 	Pending []*MigrationPair
+
+	// OutOfOrder lists the versions of pending migrations that are older than
+	// CurrentVersion, e.g. merged from a branch after newer migrations were applied.
+	OutOfOrder []uint64
+}
+
+// TimestampVersionLayout is the time layout of timestamp-based versions (YYYYMMDDHHMMSS).
+// Timestamp-based versions avoid version conflicts between parallel branches
+// and fit the uint64 version of sequential migrations.
+const TimestampVersionLayout = "20060102150405"
+
+// TimestampVersion returns the timestamp-based version of t in UTC.
+func TimestampVersion(t time.Time) uint64 {
+	version, _ := strconv.ParseUint(t.UTC().Format(TimestampVersionLayout), 10, 64)
+	return version
+}
+
+// IsTimestampVersion reports whether version is a timestamp-based version
+// rather than a sequential number.
+func IsTimestampVersion(version uint64) bool {
+	s := strconv.FormatUint(version, 10)
+	if len(s) != len(TimestampVersionLayout) {
+		return false
+	}
+
+	_, err := time.Parse(TimestampVersionLayout, s)
+
+	return err == nil
 }
 
 // migrationRegex matches migration filenames.
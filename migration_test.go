@@ -2,6 +2,7 @@ package scyllamigrate
 
 import (
 	"testing"
+	"time"
 
 	td "github.com/maxatome/go-testdeep/td"
 )
@@ -281,3 +282,36 @@ func TestMigrationPair_HasDown(t *testing.T) {
 		})
 	}
 }
+
+func TestTimestampVersion(t *testing.T) {
+	local := time.FixedZone("UTC+2", 2*60*60)
+
+	td.Cmp(t, TimestampVersion(time.Date(2024, 3, 15, 14, 30, 5, 0, time.UTC)), uint64(20240315143005))
+	td.Cmp(t, TimestampVersion(time.Date(2024, 3, 15, 16, 30, 5, 0, local)), uint64(20240315143005))
+
+	m, err := ParseMigration("20240315143005_create_users.up.cql")
+	td.CmpNoError(t, err)
+	td.Cmp(t, m.Version, uint64(20240315143005))
+}
+
+func TestIsTimestampVersion(t *testing.T) {
+	type tcase struct {
+		version  uint64
+		expected bool
+	}
+
+	tests := map[string]tcase{
+		"timestamp":       {version: 20240315143005, expected: true},
+		"sequential":      {version: 42, expected: false},
+		"too short":       {version: 2024031514300, expected: false},
+		"invalid month":   {version: 20241315143005, expected: false},
+		"invalid seconds": {version: 20240315143060, expected: false},
+		"fourteen digits": {version: 10000000000000, expected: false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			td.Cmp(t, IsTimestampVersion(tc.version), tc.expected)
+		})
+	}
+}
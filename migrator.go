@@ -4,11 +4,11 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"sort"
-	"strings"
 	"time"
 
 	"github.com/gocql/gocql"
@@ -20,27 +20,55 @@ const defaultHistoryTable = "schema_migrations"
 
 // Migrator manages database migrations.
 type Migrator struct {
-	session                *gocql.Session
+	session                Session
 	source                 Source
 	keyspace               string
 	historyTable           string
 	logger                 *slog.Logger
 	consistency            gocql.Consistency
 	waitForSchemaAgreement bool
-	schemaAgreementTimeout int
+	schemaAgreementTimeout time.Duration
+	statementTimeout       time.Duration
+	migrationTimeout       time.Duration
+	verifyChecksums        bool
+	useLock                bool
+	lockTTL                time.Duration
+	lockTimeout            time.Duration
+	dirtyPolicy            DirtyPolicy
+	outOfOrderPolicy       OutOfOrderPolicy
+	goMigrations           map[uint64]*GoMigration
+	hooks                  []*Hooks
+	allowDestructive       bool
+	confirmDestructive     ConfirmFunc
 }
 
 // New creates a new Migrator with the given gocql session and options.
+// Sessions of the scylladb/gocql fork are used as is. gocqlx sessions are
+// adapted by the gocqlxsession module, which shares their connection pool.
 func New(session *gocql.Session, opts ...Option) (*Migrator, error) {
 	if session == nil {
 		return nil, ErrNoSession
 	}
 
+	return NewWithSession(NewGocqlSession(session), opts...)
+}
+
+// NewWithSession creates a new Migrator that talks to the database through
+// the given Session, e.g. an adapter of another driver or an in-memory fake.
+func NewWithSession(session Session, opts ...Option) (*Migrator, error) {
+	if session == nil {
+		return nil, ErrNoSession
+	}
+
 	m := &Migrator{
 		session:                session,
 		historyTable:           defaultHistoryTable,
 		consistency:            gocql.Quorum,
 		waitForSchemaAgreement: true,
+		verifyChecksums:        true,
+		useLock:                true,
+		lockTTL:                defaultLockTTL,
+		lockTimeout:            defaultLockTimeout,
 	}
 
 	for _, opt := range opts {
@@ -57,161 +85,284 @@ func New(session *gocql.Session, opts ...Option) (*Migrator, error) {
 		return nil, ErrNoKeyspace
 	}
 
+	if err := m.checkGoMigrations(); err != nil {
+		return nil, err
+	}
+
 	return m, nil
 }
 
 // Up applies all pending migrations.
-func (m *Migrator) Up(ctx context.Context) (int, error) {
+// The result lists the migrations executed before an error and is never nil.
+func (m *Migrator) Up(ctx context.Context) (*Result, error) {
+	return m.run(ctx, TargetLatest())
+}
+
+// UpTo applies migrations up to and including the specified version.
+// The result lists the migrations executed before an error and is never nil.
+func (m *Migrator) UpTo(ctx context.Context, version uint64) (*Result, error) {
+	return m.run(ctx, TargetUpTo(version))
+}
+
+// Down rolls back the last applied migration.
+// Returns ErrNoChange if no migration is applied.
+func (m *Migrator) Down(ctx context.Context) (*Result, error) {
+	return m.Steps(ctx, -1)
+}
+
+// DownTo rolls back migrations down to (but not including) the specified version.
+// The result lists the migrations executed before an error and is never nil.
+func (m *Migrator) DownTo(ctx context.Context, version uint64) (*Result, error) {
+	return m.run(ctx, TargetDownTo(version))
+}
+
+// Goto migrates the schema to the specified version in whichever direction is needed:
+// applied migrations above version are rolled back, then pending migrations up to
+// and including version are applied. Version 0 rolls back every migration.
+// Returns a *MissingMigrationError if a migration that would have to be rolled back
+// is missing from the source. The result lists the migrations executed before
+// an error and is never nil.
+func (m *Migrator) Goto(ctx context.Context, version uint64) (*Result, error) {
+	result := &Result{}
+
 	if err := m.ensureHistoryTable(ctx); err != nil {
-		return 0, err
+		return result, err
 	}
 
-	pending, err := m.Pending(ctx)
+	ctx, unlock, err := m.lock(ctx)
 	if err != nil {
-		return 0, err
+		return result, err
 	}
+	defer unlock()
 
-	if len(pending) == 0 {
-		return 0, nil
+	if version != 0 && m.findPair(version) == nil {
+		return result, &MigrationError{Version: version, Direction: Up, Err: ErrVersionNotFound}
 	}
 
-	applied := 0
+	down, _, err := m.selectMigrations(ctx, TargetDownTo(version))
+	if err != nil {
+		return result, err
+	}
 
-	for _, pair := range pending {
-		if err := m.applyUp(ctx, pair); err != nil {
-			return applied, err
-		}
+	if missing := missingVersions(down); len(missing) > 0 {
+		return result, &MissingMigrationError{Versions: missing}
+	}
 
-		applied++
+	// The out-of-order policy applies to the state after the rollback.
+	up, skipped, err := m.selectMigrations(ctx, Target{direction: Up, version: version, rolledBack: true})
+	if err != nil {
+		return result, err
 	}
 
-	return applied, nil
-}
+	result.Skipped = skipped
 
-// UpTo applies migrations up to and including the specified version.
-func (m *Migrator) UpTo(ctx context.Context, version uint64) (int, error) {
-	if err := m.ensureHistoryTable(ctx); err != nil {
-		return 0, err
+	direction := Up
+	if len(down) > 0 {
+		direction = Down
 	}
 
-	pending, err := m.Pending(ctx)
+	resume, err := m.recoverDirty(ctx, direction)
 	if err != nil {
-		return 0, err
+		return result, err
 	}
 
-	applied := 0
+	if err := m.verify(ctx); err != nil {
+		return result, err
+	}
 
-	for _, pair := range pending {
-		if pair.Version > version {
-			break
-		}
+	if err := m.checkDestructive(ctx, down, Down); err != nil {
+		return result, err
+	}
 
-		if err := m.applyUp(ctx, pair); err != nil {
-			return applied, err
-		}
+	if err := m.checkDestructive(ctx, up, Up); err != nil {
+		return result, err
+	}
 
-		applied++
+	if err := m.execute(ctx, result, down, Down, resume); err != nil {
+		return result, err
 	}
 
-	return applied, nil
+	return result, m.execute(ctx, result, up, Up, resume)
 }
 
-// Down rolls back the last applied migration.
-func (m *Migrator) Down(ctx context.Context) error {
-	return m.Steps(ctx, -1)
-}
+// run executes the migrations selected by target under the lock, after
+// recovering the dirty state, verifying checksums and checking for
+// destructive statements.
+func (m *Migrator) run(ctx context.Context, target Target) (*Result, error) {
+	result := &Result{}
 
-// DownTo rolls back migrations down to (but not including) the specified version.
-func (m *Migrator) DownTo(ctx context.Context, version uint64) (int, error) {
 	if err := m.ensureHistoryTable(ctx); err != nil {
-		return 0, err
+		return result, err
 	}
 
-	applied, err := m.getAppliedMigrations(ctx)
+	ctx, unlock, err := m.lock(ctx)
 	if err != nil {
-		return 0, err
+		return result, err
 	}
+	defer unlock()
 
-	// Sort by version descending.
-	sort.Slice(applied, func(i, j int) bool {
-		return applied[i].Version > applied[j].Version
-	})
+	resume, err := m.recoverDirty(ctx, target.direction)
+	if err != nil {
+		return result, err
+	}
 
-	rolledBack := 0
+	if err := m.verify(ctx); err != nil {
+		return result, err
+	}
 
-	for _, am := range applied {
-		if am.Version <= version {
-			break
-		}
+	selected, skipped, err := m.selectMigrations(ctx, target)
+	if err != nil {
+		return result, err
+	}
 
-		if err := m.applyDown(ctx, am.Version); err != nil {
-			return rolledBack, err
-		}
+	result.Skipped = skipped
 
-		rolledBack++
+	// Steps reports a run without migrations, unlike Up, UpTo and DownTo.
+	if target.limited && len(selected) == 0 {
+		return result, ErrNoChange
 	}
 
-	return rolledBack, nil
+	if err := m.checkDestructive(ctx, selected, target.direction); err != nil {
+		return result, err
+	}
+
+	return result, m.execute(ctx, result, selected, target.direction, resume)
 }
 
-// Steps applies n migrations. Positive n moves up, negative moves down.
-func (m *Migrator) Steps(ctx context.Context, n int) error {
-	if err := m.ensureHistoryTable(ctx); err != nil {
-		return err
+// execute runs the selected migrations in the given direction and adds every
+// migration that was applied to result, including one whose AfterMigration
+// hook failed afterwards.
+func (m *Migrator) execute(
+	ctx context.Context,
+	result *Result,
+	selected []*MigrationPair,
+	direction Direction,
+	resume *DirtyState,
+) error {
+	for _, pair := range selected {
+		var err error
+
+		if direction == Up {
+			err = m.applyUp(ctx, result, pair, resume)
+		} else {
+			err = m.applyDown(ctx, result, pair.Version, resume)
+		}
+
+		if err != nil {
+			return err
+		}
 	}
 
-	if n == 0 {
-		return nil
+	return nil
+}
+
+// applyOutOfOrderPolicy applies the out-of-order policy to the pending
+// migrations selected for an up run, given the current version.
+// Returns the migrations to run and the versions skipped by OutOfOrderIgnore.
+func (m *Migrator) applyOutOfOrderPolicy(
+	ctx context.Context,
+	pending []*MigrationPair,
+	current uint64,
+) ([]*MigrationPair, []uint64, error) {
+	outOfOrder := outOfOrderVersions(pending, current)
+	if len(outOfOrder) == 0 {
+		return pending, nil, nil
 	}
 
-	if n > 0 {
-		// Apply up migrations.
-		pending, err := m.Pending(ctx)
-		if err != nil {
-			return err
+	switch m.outOfOrderPolicy {
+	case OutOfOrderStrict:
+		return nil, nil, &OutOfOrderError{Current: current, Versions: outOfOrder}
+
+	case OutOfOrderIgnore:
+		var inOrder []*MigrationPair
+
+		for _, pair := range pending {
+			if pair.Version > current {
+				inOrder = append(inOrder, pair)
+			}
 		}
 
-		if len(pending) == 0 {
-			return ErrNoChange
+		for _, version := range outOfOrder {
+			m.log(ctx, slog.LevelWarn, "Skipping out-of-order migration",
+				versionAttr(version), slog.Uint64("current_version", current),
+			)
 		}
 
-		count := n
+		return inOrder, outOfOrder, nil
 
-		if count > len(pending) {
-			count = len(pending)
+	default:
+		for _, version := range outOfOrder {
+			m.log(ctx, slog.LevelWarn, "Migration is out of order",
+				versionAttr(version), slog.Uint64("current_version", current),
+			)
 		}
 
-		for i := 0; i < count; i++ {
-			if err := m.applyUp(ctx, pending[i]); err != nil {
-				return err
-			}
-		}
-	} else {
-		// Rollback migrations.
-		applied, err := m.getAppliedMigrations(ctx)
-		if err != nil {
-			return err
+		return pending, nil, nil
+	}
+}
+
+// outOfOrderVersions returns the versions of the pending migrations that are
+// older than the current version.
+func outOfOrderVersions(pending []*MigrationPair, current uint64) []uint64 {
+	var versions []uint64
+
+	for _, pair := range pending {
+		if pair.Version < current {
+			versions = append(versions, pair.Version)
 		}
+	}
+
+	return versions
+}
+
+// latestVersion returns the highest version of the set, or 0 if it is empty.
+func latestVersion(versions map[uint64]bool) uint64 {
+	var latest uint64
+
+	for version := range versions {
+		latest = max(latest, version)
+	}
+
+	return latest
+}
 
-		if len(applied) == 0 {
-			return ErrNoChange
+// latestVersionUpTo returns the highest version that is not above limit, or 0 if there is none.
+func latestVersionUpTo(versions map[uint64]bool, limit uint64) uint64 {
+	var latest uint64
+
+	for version := range versions {
+		if version <= limit {
+			latest = max(latest, version)
 		}
+	}
 
-		// Sort by version descending.
-		sort.Slice(applied, func(i, j int) bool {
-			return applied[i].Version > applied[j].Version
-		})
+	return latest
+}
 
-		count := min(-n, len(applied))
+// missingVersions returns the versions of the selected migrations that are
+// missing from the migrations, in the order they were selected.
+func missingVersions(selected []*MigrationPair) []uint64 {
+	var missing []uint64
 
-		for i := range count {
-			if err := m.applyDown(ctx, applied[i].Version); err != nil {
-				return err
-			}
+	for _, pair := range selected {
+		if !pair.HasUp() && !pair.HasDown() {
+			missing = append(missing, pair.Version)
 		}
 	}
 
-	return nil
+	return missing
+}
+
+// Steps applies n migrations. Positive n moves up, negative moves down.
+// Fewer migrations are executed if fewer are pending or applied.
+// Returns ErrNoChange if there is no migration to execute.
+// The result lists the migrations executed before an error and is never nil.
+func (m *Migrator) Steps(ctx context.Context, n int) (*Result, error) {
+	if n == 0 {
+		return &Result{}, m.ensureHistoryTable(ctx)
+	}
+
+	return m.run(ctx, TargetSteps(n))
 }
 
 // Status returns the current migration status.
@@ -230,6 +381,11 @@ func (m *Migrator) Status(ctx context.Context) (*Status, error) {
 		return nil, err
 	}
 
+	dirty, err := m.getDirty(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var currentVersion uint64
 	for _, am := range applied {
 		if am.Version > currentVersion {
@@ -239,8 +395,10 @@ func (m *Migrator) Status(ctx context.Context) (*Status, error) {
 
 	return &Status{
 		CurrentVersion: currentVersion,
+		Dirty:          dirty,
 		Applied:        applied,
 		Pending:        pending,
+		OutOfOrder:     outOfOrderVersions(pending, currentVersion),
 	}, nil
 }
 
@@ -255,7 +413,7 @@ func (m *Migrator) Version(ctx context.Context) (uint64, error) {
 
 // Pending returns migrations that have not been applied yet.
 func (m *Migrator) Pending(ctx context.Context) ([]*MigrationPair, error) {
-	all, err := m.source.List()
+	all, err := m.migrations()
 	if err != nil {
 		return nil, err
 	}
@@ -285,6 +443,149 @@ func (m *Migrator) Applied(ctx context.Context) ([]*AppliedMigration, error) {
 	return m.getAppliedMigrations(ctx)
 }
 
+// Force clears the dirty state left by a migration that failed partway through.
+// Call it after the schema has been repaired manually. The dirty migration is
+// considered applied if its version is less than or equal to the given version,
+// and not applied otherwise; the history table is updated accordingly.
+// Returns ErrNoChange if no migration is dirty.
+func (m *Migrator) Force(ctx context.Context, version uint64) error {
+	if err := m.ensureHistoryTable(ctx); err != nil {
+		return err
+	}
+
+	ctx, unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	dirty, err := m.getDirty(ctx)
+	if err != nil {
+		return err
+	}
+
+	if dirty == nil {
+		return ErrNoChange
+	}
+
+	applied := dirty.Version <= version
+
+	switch {
+	case applied && dirty.Direction == Up:
+		var description string
+
+		if pair := m.findPair(dirty.Version); pair != nil {
+			description = pair.Description
+		}
+
+		if err := m.recordMigration(ctx, migrationRecord{
+			version:     dirty.Version,
+			description: description,
+			checksum:    dirty.Checksum,
+		}); err != nil {
+			return err
+		}
+
+	case !applied && dirty.Direction == Down:
+		if err := m.removeMigration(ctx, dirty.Version); err != nil {
+			return err
+		}
+	}
+
+	if err := m.clearDirty(ctx, dirty.Version); err != nil {
+		return err
+	}
+
+	if applied {
+		m.log(ctx, slog.LevelWarn, "Forced migration as applied", versionAttr(dirty.Version), directionAttr(dirty.Direction))
+	} else {
+		m.log(ctx, slog.LevelWarn, "Forced migration as not applied", versionAttr(dirty.Version), directionAttr(dirty.Direction))
+	}
+
+	return nil
+}
+
+// Baseline records every migration up to and including version as applied
+// without executing it. Use it to adopt scyllamigrate on a keyspace whose schema
+// was created by other means. Checksums are computed from the source and the
+// records are marked as baselined. Already applied migrations are left untouched.
+// Returns the number of migrations recorded.
+func (m *Migrator) Baseline(ctx context.Context, version uint64) (int, error) {
+	if err := m.ensureHistoryTable(ctx); err != nil {
+		return 0, err
+	}
+
+	ctx, unlock, err := m.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	dirty, err := m.getDirty(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if dirty != nil {
+		return 0, &DirtyError{Version: dirty.Version, Direction: dirty.Direction, Statement: dirty.Statement}
+	}
+
+	if m.findPair(version) == nil {
+		return 0, &MigrationError{Version: version, Direction: Up, Err: ErrVersionNotFound}
+	}
+
+	pending, _, err := m.selectMigrations(ctx, TargetUpTo(version))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, pair := range pending {
+		if !pair.HasUp() {
+			return 0, &MigrationError{Version: pair.Version, Direction: Up, Err: ErrMissingUp}
+		}
+	}
+
+	baselined := 0
+
+	for _, pair := range pending {
+		checksum, err := m.migrationChecksum(pair.Version, Up)
+		if err != nil {
+			return baselined, err
+		}
+
+		if err := m.recordMigration(ctx, migrationRecord{
+			version:     pair.Version,
+			description: pair.Description,
+			checksum:    checksum,
+			baselined:   true,
+		}); err != nil {
+			return baselined, err
+		}
+
+		m.log(ctx, slog.LevelInfo, "Baselined migration", versionAttr(pair.Version), descriptionAttr(pair.Description))
+
+		baselined++
+	}
+
+	return baselined, nil
+}
+
+// Verify compares the checksum recorded for every applied migration with the
+// checksum of the corresponding up migration in the source.
+// Returns a *ChecksumError listing every version whose content has drifted.
+func (m *Migrator) Verify(ctx context.Context) error {
+	if !m.historyTableExists(ctx) {
+		return nil
+	}
+
+	applied, err := m.getAppliedMigrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	return m.compareChecksums(applied)
+}
+
 // Close releases resources.
 func (m *Migrator) Close() error {
 	if m.source != nil {
@@ -294,8 +595,10 @@ func (m *Migrator) Close() error {
 	return nil
 }
 
-// applyUp applies a single up migration.
-func (m *Migrator) applyUp(ctx context.Context, pair *MigrationPair) error {
+// applyUp applies a single up migration and adds it to result once it is
+// recorded in the history table.
+// If resume refers to this migration, execution starts at the statement that failed before.
+func (m *Migrator) applyUp(ctx context.Context, result *Result, pair *MigrationPair, resume *DirtyState) error {
 	if !pair.HasUp() {
 		return &MigrationError{
 			Version:   pair.Version,
@@ -304,40 +607,78 @@ func (m *Migrator) applyUp(ctx context.Context, pair *MigrationPair) error {
 		}
 	}
 
-	m.log("Applying migration %d: %s", pair.Version, pair.Description)
+	m.log(ctx, slog.LevelInfo, "Applying migration",
+		versionAttr(pair.Version), directionAttr(Up), descriptionAttr(pair.Description),
+	)
 
-	content, err := m.readMigrationContent(pair.Version, Up)
-	if err != nil {
+	event := MigrationEvent{
+		Version:     pair.Version,
+		Description: pair.Description,
+		Direction:   Up,
+	}
+
+	if err := m.beforeMigration(ctx, event); err != nil {
 		return err
 	}
 
-	checksum := m.checksum(content)
+	runCtx, cancel := withTimeout(ctx, m.migrationTimeout)
+	defer cancel()
 
 	start := time.Now()
+	err := m.runUp(runCtx, pair, resume, start)
+	event.Duration = time.Since(start)
 
-	if err := m.executeStatements(ctx, pair.Version, Up, content); err != nil {
-		return err
+	if err == nil {
+		result.add(pair, Up, event.Duration)
+
+		m.log(ctx, slog.LevelInfo, "Applied migration",
+			versionAttr(pair.Version), directionAttr(Up), descriptionAttr(pair.Description), durationAttr(event.Duration),
+		)
 	}
 
-	duration := time.Since(start)
+	return m.afterMigration(ctx, event, err)
+}
+
+// runUp executes an up migration that started at start and records it in the history table.
+func (m *Migrator) runUp(ctx context.Context, pair *MigrationPair, resume *DirtyState, start time.Time) error {
+	var checksum string
+
+	if gm, ok := m.goMigrations[pair.Version]; ok {
+		checksum = gm.checksum()
+
+		if err := m.executeGo(ctx, gm, Up); err != nil {
+			return err
+		}
+	} else {
+		content, err := m.readMigrationContent(pair.Version, Up)
+		if err != nil {
+			return err
+		}
+
+		checksum = m.checksum(content)
+
+		if err := m.executeStatements(ctx, pair.Up, content, resume.from(pair.Version, Up)); err != nil {
+			return err
+		}
+	}
 
 	if err := m.recordMigration(ctx, migrationRecord{
 		version:     pair.Version,
 		description: pair.Description,
 		checksum:    checksum,
-		duration:    duration,
+		duration:    time.Since(start),
 	}); err != nil {
 		return err
 	}
 
-	m.log("Applied migration %d in %v", pair.Version, duration)
-
-	return nil
+	return m.clearDirty(ctx, pair.Version)
 }
 
-// applyDown applies a single down migration.
-func (m *Migrator) applyDown(ctx context.Context, version uint64) error {
-	pairs, err := m.source.List()
+// applyDown applies a single down migration and adds it to result once it is
+// removed from the history table.
+// If resume refers to this migration, execution starts at the statement that failed before.
+func (m *Migrator) applyDown(ctx context.Context, result *Result, version uint64, resume *DirtyState) error {
+	pairs, err := m.migrations()
 	if err != nil {
 		return err
 	}
@@ -367,30 +708,221 @@ func (m *Migrator) applyDown(ctx context.Context, version uint64) error {
 		}
 	}
 
-	m.log("Rolling back migration %d: %s", pair.Version, pair.Description)
+	m.log(ctx, slog.LevelInfo, "Rolling back migration",
+		versionAttr(pair.Version), directionAttr(Down), descriptionAttr(pair.Description),
+	)
 
-	content, err := m.readMigrationContent(version, Down)
-	if err != nil {
+	event := MigrationEvent{
+		Version:     pair.Version,
+		Description: pair.Description,
+		Direction:   Down,
+	}
+
+	if err := m.beforeMigration(ctx, event); err != nil {
 		return err
 	}
 
+	runCtx, cancel := withTimeout(ctx, m.migrationTimeout)
+	defer cancel()
+
 	start := time.Now()
+	err = m.runDown(runCtx, pair, resume)
+	event.Duration = time.Since(start)
+
+	if err == nil {
+		result.add(pair, Down, event.Duration)
+
+		m.log(ctx, slog.LevelInfo, "Rolled back migration",
+			versionAttr(version), directionAttr(Down), descriptionAttr(pair.Description), durationAttr(event.Duration),
+		)
+	}
+
+	return m.afterMigration(ctx, event, err)
+}
+
+// runDown executes a down migration and removes it from the history table.
+func (m *Migrator) runDown(ctx context.Context, pair *MigrationPair, resume *DirtyState) error {
+	if gm, ok := m.goMigrations[pair.Version]; ok {
+		if err := m.executeGo(ctx, gm, Down); err != nil {
+			return err
+		}
+	} else {
+		content, err := m.readMigrationContent(pair.Version, Down)
+		if err != nil {
+			return err
+		}
+
+		if err := m.executeStatements(ctx, pair.Down, content, resume.from(pair.Version, Down)); err != nil {
+			return err
+		}
+	}
+
+	if err := m.removeMigration(ctx, pair.Version); err != nil {
+		return err
+	}
+
+	return m.clearDirty(ctx, pair.Version)
+}
+
+// recoverDirty inspects the dirty state before migrations run in the given direction.
+// Depending on the dirty policy it returns a *DirtyError, the state to resume
+// from, or nil to run the dirty migration again from its first statement.
+// Resuming is only allowed if the migration content is unchanged since it failed.
+func (m *Migrator) recoverDirty(ctx context.Context, direction Direction) (*DirtyState, error) {
+	dirty, err := m.getDirty(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if dirty == nil {
+		return nil, nil
+	}
+
+	dirtyErr := &DirtyError{
+		Version:   dirty.Version,
+		Direction: dirty.Direction,
+		Statement: dirty.Statement,
+	}
+
+	if m.dirtyPolicy == DirtyFail || dirty.Direction != direction {
+		return nil, dirtyErr
+	}
+
+	if m.dirtyPolicy == DirtyRestart {
+		m.log(ctx, slog.LevelWarn, "Restarting dirty migration from the first statement",
+			versionAttr(dirty.Version), directionAttr(dirty.Direction),
+		)
+		return nil, nil
+	}
+
+	checksum, err := m.migrationChecksum(dirty.Version, dirty.Direction)
+	if err != nil {
+		return nil, err
+	}
+
+	if checksum != dirty.Checksum {
+		dirtyErr.ChecksumChanged = true
+		return nil, dirtyErr
+	}
+
+	m.log(ctx, slog.LevelWarn, "Resuming dirty migration",
+		versionAttr(dirty.Version), directionAttr(dirty.Direction), statementAttr(dirty.Statement),
+	)
+
+	return dirty, nil
+}
+
+// findPair returns the migration pair for the given version, or nil if
+// the migrations do not contain it or cannot be listed.
+func (m *Migrator) findPair(version uint64) *MigrationPair {
+	pairs, err := m.migrations()
+	if err != nil {
+		return nil
+	}
+
+	for _, p := range pairs {
+		if p.Version == version {
+			return p
+		}
+	}
+
+	return nil
+}
 
-	if err := m.executeStatements(ctx, version, Down, content); err != nil {
+// verify runs checksum verification unless it has been disabled.
+// Every drifted migration is logged as a warning.
+func (m *Migrator) verify(ctx context.Context) error {
+	if !m.verifyChecksums {
+		return nil
+	}
+
+	applied, err := m.getAppliedMigrations(ctx)
+	if err != nil {
 		return err
 	}
 
-	duration := time.Since(start)
+	err = m.compareChecksums(applied)
+
+	var checksumErr *ChecksumError
+	if errors.As(err, &checksumErr) {
+		for _, mm := range checksumErr.Mismatches {
+			m.log(ctx, slog.LevelWarn, "Migration was modified after being applied",
+				versionAttr(mm.Version),
+				slog.String("recorded_checksum", mm.Recorded),
+				slog.String("actual_checksum", mm.Actual),
+			)
+		}
+	}
 
-	if err := m.removeMigration(ctx, version); err != nil {
+	return err
+}
+
+// compareChecksums re-reads every applied migration from the source and
+// compares its checksum with the recorded one. Applied versions that are no
+// longer present in the source and records without a checksum are skipped.
+func (m *Migrator) compareChecksums(applied []*AppliedMigration) error {
+	pairs, err := m.migrations()
+	if err != nil {
 		return err
 	}
 
-	m.log("Rolled back migration %d in %v", version, duration)
+	available := make(map[uint64]*MigrationPair, len(pairs))
+	for _, pair := range pairs {
+		available[pair.Version] = pair
+	}
+
+	sort.Slice(applied, func(i, j int) bool {
+		return applied[i].Version < applied[j].Version
+	})
+
+	var mismatches []ChecksumMismatch
+
+	for _, am := range applied {
+		if am.Checksum == "" {
+			continue
+		}
+
+		pair, ok := available[am.Version]
+		if !ok || !pair.HasUp() {
+			continue
+		}
+
+		actual, err := m.migrationChecksum(am.Version, Up)
+		if err != nil {
+			return err
+		}
+
+		if actual != am.Checksum {
+			mismatches = append(mismatches, ChecksumMismatch{
+				Version:  am.Version,
+				Recorded: am.Checksum,
+				Actual:   actual,
+			})
+		}
+	}
+
+	if len(mismatches) > 0 {
+		return &ChecksumError{Mismatches: mismatches}
+	}
 
 	return nil
 }
 
+// migrationChecksum returns the checksum of a migration in the given direction:
+// the identifier of a Go migration, or the SHA-256 hash of a migration file.
+func (m *Migrator) migrationChecksum(version uint64, direction Direction) (string, error) {
+	if gm, ok := m.goMigrations[version]; ok {
+		return gm.checksum(), nil
+	}
+
+	content, err := m.readMigrationContent(version, direction)
+	if err != nil {
+		return "", err
+	}
+
+	return m.checksum(content), nil
+}
+
 // readMigrationContent reads the content of a migration file.
 func (m *Migrator) readMigrationContent(version uint64, direction Direction) ([]byte, error) {
 	var reader io.ReadCloser
@@ -420,77 +952,101 @@ func (m *Migrator) readMigrationContent(version uint64, direction Direction) ([]
 	return content, nil
 }
 
-// executeStatements parses and executes CQL statements from migration content.
-func (m *Migrator) executeStatements(ctx context.Context, version uint64, direction Direction, content []byte) error {
-	statements := m.parseStatements(string(content))
+// executeStatements splits and executes CQL statements from migration content,
+// starting at the 1-based statement index from. The migration is marked dirty
+// before each statement, so a failure leaves a record pointing at the statement that failed.
+func (m *Migrator) executeStatements(ctx context.Context, migration *Migration, content []byte, from int) error {
+	version, direction := migration.Version, migration.Direction
 
-	for i, stmt := range statements {
-		if err := m.session.Query(stmt).WithContext(ctx).Consistency(m.consistency).Exec(); err != nil {
-			return &MigrationError{
-				Version:   version,
-				Direction: direction,
-				Statement: i + 1,
-				Err:       err,
-			}
-		}
-	}
+	statements, err := SplitStatements(string(content))
+	if err != nil {
+		migrationErr := &MigrationError{Version: version, Direction: direction, File: migration.Raw, Err: err}
 
-	if m.waitForSchemaAgreement {
-		if err := m.session.AwaitSchemaAgreement(ctx); err != nil {
-			return fmt.Errorf("failed to wait for schema agreement: %w", err)
+		var syntaxErr *SyntaxError
+		if errors.As(err, &syntaxErr) {
+			migrationErr.Line = syntaxErr.Line
 		}
-	}
 
-	return nil
-}
+		return migrationErr
+	}
 
-// parseStatements splits migration content into individual CQL statements.
-// Statements are separated by semicolons. Lines starting with -- are comments.
-func (*Migrator) parseStatements(content string) []string {
-	var statements []string
+	state := DirtyState{
+		Version:   version,
+		Direction: direction,
+		Checksum:  m.checksum(content),
+		StartedAt: time.Now(),
+	}
 
-	var current strings.Builder
+	for i, stmt := range statements {
+		if i+1 < from {
+			continue
+		}
 
-	lines := strings.Split(content, "\n")
+		state.Statement = i + 1
 
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
+		if err := m.markDirty(ctx, state); err != nil {
+			return err
+		}
 
-		// Skip empty lines and comments.
-		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
-			continue
+		event := StatementEvent{
+			Version:   version,
+			Direction: direction,
+			Index:     i + 1,
+			Text:      stmt.Text,
+			Line:      stmt.Line,
 		}
 
-		current.WriteString(line)
-		current.WriteString("\n")
+		if err := m.beforeStatement(ctx, event); err != nil {
+			return err
+		}
 
-		// Check if line ends with semicolon.
-		if strings.HasSuffix(trimmed, ";") {
-			stmt := strings.TrimSpace(current.String())
-			// Remove trailing semicolon for gocql.
-			stmt = strings.TrimSuffix(stmt, ";")
+		start := time.Now()
 
-			stmt = strings.TrimSpace(stmt)
-			if stmt != "" {
-				statements = append(statements, stmt)
+		if err := m.execStatement(ctx, stmt.Text); err != nil {
+			err = &MigrationError{
+				Version:   version,
+				Direction: direction,
+				Statement: i + 1,
+				File:      migration.Raw,
+				Line:      stmt.Line,
+				Err:       err,
 			}
 
-			current.Reset()
+			event.Duration = time.Since(start)
+
+			return m.afterStatement(ctx, event, err)
 		}
-	}
 
-	// Handle case where last statement doesn't end with semicolon.
-	remaining := strings.TrimSpace(current.String())
-	if remaining != "" {
-		statements = append(statements, remaining)
+		event.Duration = time.Since(start)
+
+		m.log(ctx, slog.LevelDebug, "Executed statement",
+			versionAttr(version), directionAttr(direction), statementAttr(i+1), durationAttr(event.Duration),
+		)
+
+		if err := m.afterStatement(ctx, event, nil); err != nil {
+			return err
+		}
 	}
 
-	// Return empty slice instead of nil if no statements found.
-	if statements == nil {
-		return []string{}
+	return m.awaitSchemaAgreement(ctx, version, direction)
+}
+
+// execStatement executes a single migration statement within the statement timeout.
+func (m *Migrator) execStatement(ctx context.Context, stmt string) error {
+	ctx, cancel := withTimeout(ctx, m.statementTimeout)
+	defer cancel()
+
+	return m.exec(ctx, stmt)
+}
+
+// withTimeout derives a context that expires after timeout.
+// A zero timeout returns ctx unchanged.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
 	}
 
-	return statements
+	return context.WithTimeout(ctx, timeout)
 }
 
 // checksum calculates a SHA-256 checksum of migration content.
@@ -498,10 +1054,3 @@ func (*Migrator) checksum(content []byte) string {
 	hash := sha256.Sum256(content)
 	return hex.EncodeToString(hash[:])
 }
-
-// log logs a message if a logger is configured.
-func (m *Migrator) log(format string, v ...any) {
-	if m.logger != nil {
-		m.logger.Info(fmt.Sprintf(format, v...))
-	}
-}
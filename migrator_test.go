@@ -9,6 +9,7 @@ import (
 	"strings"
 	"testing"
 	"testing/fstest"
+	"time"
 
 	"github.com/gocql/gocql"
 	td "github.com/maxatome/go-testdeep/td"
@@ -115,74 +116,157 @@ func TestNew_DefaultValues(t *testing.T) {
 	td.Cmp(t, m.historyTable, "schema_migrations")
 	td.Cmp(t, m.consistency, gocql.Quorum)
 	td.Cmp(t, m.waitForSchemaAgreement, true)
+	td.Cmp(t, m.verifyChecksums, true)
+	td.Cmp(t, m.useLock, true)
+	td.Cmp(t, m.lockTTL, defaultLockTTL)
+	td.Cmp(t, m.lockTimeout, defaultLockTimeout)
+	td.Cmp(t, m.dirtyPolicy, DirtyResume)
 }
 
-func TestMigrator_parseStatements(t *testing.T) {
-	m := &Migrator{}
+func TestMigrator_compareChecksums(t *testing.T) {
+	fsys := fstest.MapFS{
+		"000001_create_users.up.cql":   {Data: []byte("CREATE TABLE users;")},
+		"000001_create_users.down.cql": {Data: []byte("DROP TABLE users;")},
+		"000002_create_posts.up.cql":   {Data: []byte("CREATE TABLE posts;")},
+		"000003_create_tags.up.cql":    {Data: []byte("CREATE TABLE tags;")},
+	}
+	source, err := NewFSSource(fsys)
+	td.CmpNoError(t, err)
+
+	m := &Migrator{source: source}
+
+	usersSum := m.checksum([]byte("CREATE TABLE users;"))
+	postsSum := m.checksum([]byte("CREATE TABLE posts;"))
+	tagsSum := m.checksum([]byte("CREATE TABLE tags;"))
 
 	type tcase struct {
-		content  string
-		expected []string
+		applied  []*AppliedMigration
+		expected []ChecksumMismatch
 	}
 	tests := map[string]tcase{
-		"single statement": {
-			content:  "CREATE TABLE users (id UUID PRIMARY KEY);",
-			expected: []string{"CREATE TABLE users (id UUID PRIMARY KEY)"},
+		"no applied migrations": {
+			applied: nil,
 		},
-		"multiple statements": {
-			content:  "CREATE TABLE users (id UUID PRIMARY KEY);\nCREATE INDEX idx ON users (id);",
-			expected: []string{"CREATE TABLE users (id UUID PRIMARY KEY)", "CREATE INDEX idx ON users (id)"},
-		},
-		"statements with comments": {
-			content:  "-- This is a comment\nCREATE TABLE users;\n-- Another comment\nCREATE INDEX idx;",
-			expected: []string{"CREATE TABLE users", "CREATE INDEX idx"},
+		"all checksums match": {
+			applied: []*AppliedMigration{
+				{Version: 1, Checksum: usersSum},
+				{Version: 2, Checksum: postsSum},
+			},
 		},
-		"statements with empty lines": {
-			content:  "CREATE TABLE users;\n\nCREATE INDEX idx;\n",
-			expected: []string{"CREATE TABLE users", "CREATE INDEX idx"},
+		"single drifted version": {
+			applied: []*AppliedMigration{
+				{Version: 1, Checksum: usersSum},
+				{Version: 2, Checksum: "stale"},
+			},
+			expected: []ChecksumMismatch{
+				{Version: 2, Recorded: "stale", Actual: postsSum},
+			},
 		},
-		"statement without semicolon": {
-			content:  "CREATE TABLE users",
-			expected: []string{"CREATE TABLE users"},
+		"every drifted version is reported in order": {
+			applied: []*AppliedMigration{
+				{Version: 3, Checksum: "stale-3"},
+				{Version: 1, Checksum: "stale-1"},
+				{Version: 2, Checksum: postsSum},
+			},
+			expected: []ChecksumMismatch{
+				{Version: 1, Recorded: "stale-1", Actual: usersSum},
+				{Version: 3, Recorded: "stale-3", Actual: tagsSum},
+			},
 		},
-		"multiple statements, last without semicolon": {
-			content:  "CREATE TABLE users;\nCREATE INDEX idx",
-			expected: []string{"CREATE TABLE users", "CREATE INDEX idx"},
+		"empty recorded checksum is skipped": {
+			applied: []*AppliedMigration{
+				{Version: 1, Checksum: ""},
+			},
 		},
-		"empty content": {
-			content:  "",
-			expected: []string{},
+		"version missing from source is skipped": {
+			applied: []*AppliedMigration{
+				{Version: 99, Checksum: "whatever"},
+			},
 		},
-		"only comments": {
-			content:  "-- Comment 1\n-- Comment 2",
-			expected: []string{},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := m.compareChecksums(tt.applied)
+			if tt.expected == nil {
+				td.CmpNoError(t, err)
+				return
+			}
+
+			td.CmpErrorIs(t, err, ErrChecksumMismatch)
+
+			var ce *ChecksumError
+			td.Cmp(t, errors.As(err, &ce), true)
+			td.Cmp(t, ce.Mismatches, tt.expected)
+		})
+	}
+}
+
+func TestMigrator_verify_Disabled(t *testing.T) {
+	// With verification disabled verify must return before touching the session.
+	m := &Migrator{verifyChecksums: false}
+	td.CmpNoError(t, m.verify(context.Background()))
+}
+
+func TestMigrator_findPair(t *testing.T) {
+	source := &mockSource{
+		pairs: []*MigrationPair{
+			{Version: 1, Description: "first"},
+			{Version: 3, Description: "third"},
 		},
-		"only empty lines": {
-			content:  "\n\n\n",
-			expected: []string{},
+	}
+
+	m := &Migrator{source: source}
+
+	td.Cmp(t, m.findPair(3), source.pairs[1])
+	td.Cmp(t, m.findPair(2), td.Nil())
+}
+
+func TestDirtyState_from(t *testing.T) {
+	state := &DirtyState{Version: 3, Direction: Up, Statement: 4}
+
+	type tcase struct {
+		state     *DirtyState
+		version   uint64
+		direction Direction
+		expected  int
+	}
+	tests := map[string]tcase{
+		"nil state": {
+			state:     nil,
+			version:   3,
+			direction: Up,
+			expected:  1,
 		},
-		"statement with trailing whitespace": {
-			content:  "CREATE TABLE users;  \n",
-			expected: []string{"CREATE TABLE users"},
+		"matching migration": {
+			state:     state,
+			version:   3,
+			direction: Up,
+			expected:  4,
 		},
-		"multi-line statement": {
-			content:  "CREATE TABLE users (\n    id UUID PRIMARY KEY,\n    name TEXT\n);",
-			expected: []string{"CREATE TABLE users (\n    id UUID PRIMARY KEY,\n    name TEXT\n)"},
+		"other version": {
+			state:     state,
+			version:   4,
+			direction: Up,
+			expected:  1,
 		},
-		"comment at end of line": {
-			content:  "CREATE TABLE users; -- inline comment",
-			expected: []string{"CREATE TABLE users; -- inline comment"}, // Parser doesn't strip inline comments
+		"other direction": {
+			state:     state,
+			version:   3,
+			direction: Down,
+			expected:  1,
 		},
-		"statement with semicolon in string": {
-			content:  "INSERT INTO users (name) VALUES ('test;value');",
-			expected: []string{"INSERT INTO users (name) VALUES ('test;value')"},
+		"statement not recorded": {
+			state:     &DirtyState{Version: 3, Direction: Up},
+			version:   3,
+			direction: Up,
+			expected:  1,
 		},
 	}
 
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
-			got := m.parseStatements(tt.content)
-			td.Cmp(t, got, tt.expected)
+			td.Cmp(t, tt.state.from(tt.version, tt.direction), tt.expected)
 		})
 	}
 }
@@ -229,19 +313,18 @@ func TestMigrator_checksum(t *testing.T) {
 func TestMigrator_log(t *testing.T) {
 	type tcase struct {
 		logger *slog.Logger
-		format string
-		args   []any
+		msg    string
+		attrs  []slog.Attr
 	}
 	tests := map[string]tcase{
 		"with logger": {
 			logger: slog.Default(),
-			format: "test message %d",
-			args:   []any{42},
+			msg:    "test message",
+			attrs:  []slog.Attr{versionAttr(42)},
 		},
 		"nil logger": {
 			logger: nil,
-			format: "test message",
-			args:   []any{},
+			msg:    "test message",
 		},
 	}
 
@@ -249,7 +332,7 @@ func TestMigrator_log(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			m := &Migrator{logger: tt.logger}
 			// Should not panic
-			m.log(tt.format, tt.args...)
+			m.log(context.Background(), slog.LevelInfo, tt.msg, tt.attrs...)
 		})
 	}
 }
@@ -412,7 +495,7 @@ func TestMigrator_applyUp_ErrorCases(t *testing.T) {
 			m := &Migrator{
 				source: tt.source,
 			}
-			err := m.applyUp(ctx, tt.pair)
+			err := m.applyUp(ctx, &Result{}, tt.pair, nil)
 			if tt.wantErr {
 				td.CmpError(t, err)
 			} else {
@@ -478,7 +561,7 @@ func TestMigrator_applyDown_ErrorCases(t *testing.T) {
 			m := &Migrator{
 				source: tt.source,
 			}
-			err := m.applyDown(ctx, tt.version)
+			err := m.applyDown(ctx, &Result{}, tt.version, nil)
 			if tt.wantErr {
 				td.CmpError(t, err)
 			} else {
@@ -505,44 +588,6 @@ func TestMigrator_Steps_Zero(t *testing.T) {
 	// For now, we verify the logic exists in the code
 }
 
-func TestMigrator_parseStatements_EdgeCases(t *testing.T) {
-	m := &Migrator{}
-
-	type tcase struct {
-		content  string
-		expected int // expected number of statements
-	}
-	tests := map[string]tcase{
-		"semicolon only": {
-			content:  ";",
-			expected: 0,
-		},
-		"multiple semicolons on same line": {
-			content:  "CREATE TABLE a;;CREATE TABLE b;",
-			expected: 1, // Parser splits on line breaks, not semicolons within a line
-		},
-		"whitespace only": {
-			content:  "   \n\t  ",
-			expected: 0,
-		},
-		"statement with only whitespace": {
-			content:  "   ;",
-			expected: 0,
-		},
-		"mixed content": {
-			content:  "-- Comment\nCREATE TABLE a;\n\n-- Another\nCREATE TABLE b;\nDROP TABLE c",
-			expected: 3,
-		},
-	}
-
-	for name, tt := range tests {
-		t.Run(name, func(t *testing.T) {
-			got := m.parseStatements(tt.content)
-			td.Cmp(t, len(got), tt.expected)
-		})
-	}
-}
-
 func TestMigrator_checksum_Consistency(t *testing.T) {
 	m := &Migrator{}
 
@@ -599,17 +644,19 @@ func TestMigrator_log_WithLogger(t *testing.T) {
 	var buf bytes.Buffer
 	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
 
-	m := &Migrator{logger: logger}
-	m.log("test message %d", 42)
+	m := &Migrator{logger: logger, keyspace: "myapp"}
+	m.log(context.Background(), slog.LevelInfo, "Applied migration", versionAttr(42), directionAttr(Up), durationAttr(1500*time.Millisecond))
+	m.log(context.Background(), slog.LevelDebug, "Executed statement", versionAttr(42), statementAttr(1))
 
 	output := buf.String()
-	td.Cmp(t, output, td.Contains("test message 42"))
+	td.Cmp(t, output, td.Contains(`level=INFO msg="Applied migration" keyspace=myapp version=42 direction=up duration_ms=1500`))
+	td.Cmp(t, output, td.Not(td.Contains("Executed statement")))
 }
 
 func TestMigrator_log_WithoutLogger(t *testing.T) {
 	m := &Migrator{logger: nil}
 	// Should not panic
-	m.log("test message")
+	m.log(context.Background(), slog.LevelInfo, "test message")
 }
 
 func TestMigrator_readMigrationContent_CloseReader(t *testing.T) {
@@ -666,3 +713,76 @@ func (m *mockSourceWithReader) ReadDown(version uint64) (io.ReadCloser, error) {
 func (m *mockSourceWithReader) Close() error {
 	return nil
 }
+
+func TestWithTimeout(t *testing.T) {
+	ctx := context.Background()
+
+	unbounded, cancel := withTimeout(ctx, 0)
+	cancel()
+	td.Cmp(t, unbounded, ctx)
+
+	bounded, cancel := withTimeout(ctx, time.Minute)
+	defer cancel()
+
+	deadline, ok := bounded.Deadline()
+	td.Cmp(t, ok, true)
+	td.Cmp(t, time.Until(deadline) <= time.Minute, true)
+}
+
+func TestMissingVersions(t *testing.T) {
+	selected := []*MigrationPair{
+		{Version: 9},
+		{Version: 8, Down: &Migration{Version: 8, Direction: Down}},
+		{Version: 7},
+		{Version: 6, Up: &Migration{Version: 6, Direction: Up}},
+	}
+
+	td.Cmp(t, missingVersions(selected), []uint64{9, 7})
+	td.CmpNil(t, missingVersions(selected[3:]))
+}
+
+func TestMigrator_applyOutOfOrderPolicy(t *testing.T) {
+	pending := []*MigrationPair{{Version: 3}, {Version: 5}, {Version: 8}, {Version: 9}}
+
+	type tcase struct {
+		policy          OutOfOrderPolicy
+		current         uint64
+		expected        []*MigrationPair
+		expectedSkipped []uint64
+		expectError     bool
+	}
+
+	tests := map[string]tcase{
+		"allow":           {policy: OutOfOrderAllow, current: 7, expected: pending},
+		"strict":          {policy: OutOfOrderStrict, current: 7, expectError: true},
+		"ignore":          {policy: OutOfOrderIgnore, current: 7, expected: pending[2:], expectedSkipped: []uint64{3, 5}},
+		"strict in order": {policy: OutOfOrderStrict, current: 2, expected: pending},
+		"nothing applied": {policy: OutOfOrderIgnore, current: 0, expected: pending},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &Migrator{outOfOrderPolicy: tc.policy}
+
+			selected, skipped, err := m.applyOutOfOrderPolicy(context.Background(), pending, tc.current)
+
+			if tc.expectError {
+				var oe *OutOfOrderError
+				td.Cmp(t, errors.As(err, &oe), true)
+				td.Cmp(t, oe.Versions, []uint64{3, 5})
+				td.Cmp(t, oe.Current, uint64(7))
+
+				return
+			}
+
+			td.CmpNoError(t, err)
+			td.Cmp(t, selected, tc.expected)
+			td.Cmp(t, skipped, tc.expectedSkipped)
+		})
+	}
+}
+
+func TestLatestVersion(t *testing.T) {
+	td.Cmp(t, latestVersion(nil), uint64(0))
+	td.Cmp(t, latestVersion(map[uint64]bool{3: true, 7: true, 5: true}), uint64(7))
+}
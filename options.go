@@ -1,10 +1,12 @@
 package scyllamigrate
 
 import (
-	"context"
+	"errors"
+	"fmt"
 	"io/fs"
 	"log"
 	"log/slog"
+	"time"
 
 	"github.com/gocql/gocql"
 )
@@ -67,6 +69,8 @@ func WithHistoryTable(table string) Option {
 }
 
 // WithLogger sets a logger for migration progress.
+// Every migration is logged at info level, every statement at debug level and
+// drifted or dirty migrations at warn level, with structured attributes.
 func WithLogger(logger *slog.Logger) Option {
 	return func(m *Migrator) error {
 		m.logger = logger
@@ -75,7 +79,8 @@ func WithLogger(logger *slog.Logger) Option {
 }
 
 // WithStdLogger sets the standard library logger for migration progress.
-// It wraps the log.Logger in a slog.Logger for compatibility.
+// It wraps the log.Logger in a slog.Logger that prints records at info level
+// and above with their attributes as key=value pairs.
 func WithStdLogger(l *log.Logger) Option {
 	return func(m *Migrator) error {
 		if l == nil {
@@ -89,55 +94,255 @@ func WithStdLogger(l *log.Logger) Option {
 	}
 }
 
-// logHandler adapts a *log.Logger to slog.Handler for backward compatibility.
-type logHandler struct {
-	logger *log.Logger
+// WithConsistency sets the consistency level for migration queries.
+// Default is gocql.Quorum.
+func WithConsistency(consistency gocql.Consistency) Option {
+	return func(m *Migrator) error {
+		m.consistency = consistency
+		return nil
+	}
 }
 
-func (*logHandler) Enabled(_ context.Context, _ slog.Level) bool {
-	return true
+// WithSchemaAgreement sets whether to wait for schema agreement after each migration.
+// Default is true.
+func WithSchemaAgreement(wait bool) Option {
+	return func(m *Migrator) error {
+		m.waitForSchemaAgreement = wait
+		return nil
+	}
 }
 
-func (*logHandler) Handle(_ context.Context, _ slog.Record) error {
-	// This method is not used since we use LogAttrs.
-	return nil
-}
+// WithSchemaAgreementTimeout sets the timeout for every wait for schema agreement,
+// including the one after creating the internal tables.
+// Zero means only the caller's context and the session's MaxWaitSchemaAgreement apply.
+// Default is 0.
+func WithSchemaAgreementTimeout(timeout time.Duration) Option {
+	return func(m *Migrator) error {
+		if timeout < 0 {
+			return fmt.Errorf("scyllamigrate: schema agreement timeout must not be negative, got %s", timeout)
+		}
 
-func (h *logHandler) WithAttrs(_ []slog.Attr) slog.Handler {
-	return h
+		m.schemaAgreementTimeout = timeout
+
+		return nil
+	}
 }
 
-func (h *logHandler) WithGroup(_ string) slog.Handler {
-	return h
+// WithStatementTimeout sets the timeout for executing a single statement of a migration file.
+// Zero means only the caller's context and the session's timeout apply.
+// Default is 0.
+func WithStatementTimeout(timeout time.Duration) Option {
+	return func(m *Migrator) error {
+		if timeout < 0 {
+			return fmt.Errorf("scyllamigrate: statement timeout must not be negative, got %s", timeout)
+		}
+
+		m.statementTimeout = timeout
+
+		return nil
+	}
 }
 
-func (h *logHandler) LogAttrs(_ context.Context, _ slog.Level, msg string, _ ...slog.Attr) {
-	h.logger.Printf("%s", msg)
+// WithMigrationTimeout sets the timeout for running a single migration, including
+// all of its statements and the schema agreement wait. A migration that times out
+// is left dirty like any other failed migration.
+// Zero means only the caller's context applies.
+// Default is 0.
+func WithMigrationTimeout(timeout time.Duration) Option {
+	return func(m *Migrator) error {
+		if timeout < 0 {
+			return fmt.Errorf("scyllamigrate: migration timeout must not be negative, got %s", timeout)
+		}
+
+		m.migrationTimeout = timeout
+
+		return nil
+	}
 }
 
-// WithConsistency sets the consistency level for migration queries.
-// Default is gocql.Quorum.
-func WithConsistency(consistency gocql.Consistency) Option {
+// WithChecksumVerification sets whether the checksums of applied migrations are
+// compared with the source before Up, UpTo, Steps and DownTo run.
+// Disable it if already applied migration files are reformatted on purpose.
+// Default is true.
+func WithChecksumVerification(verify bool) Option {
 	return func(m *Migrator) error {
-		m.consistency = consistency
+		m.verifyChecksums = verify
 		return nil
 	}
 }
 
-// WithSchemaAgreement sets whether to wait for schema agreement after each migration.
+// WithLock sets whether mutating operations take the cluster-wide migration lock.
+// The lock is stored in a table next to the history table and prevents
+// concurrent migrators from applying the same migrations.
 // Default is true.
-func WithSchemaAgreement(wait bool) Option {
+func WithLock(enabled bool) Option {
 	return func(m *Migrator) error {
-		m.waitForSchemaAgreement = wait
+		m.useLock = enabled
 		return nil
 	}
 }
 
-// WithSchemaAgreementTimeout sets the timeout for waiting for schema agreement.
-// If not set, the session's default timeout is used.
-func WithSchemaAgreementTimeout(timeout int) Option {
+// WithLockTTL sets the lease duration of the migration lock.
+// The lease is renewed by a heartbeat while migrations run, so it only needs
+// to outlive a crashed process for as long as you are willing to wait.
+// Default is 1 minute.
+func WithLockTTL(ttl time.Duration) Option {
 	return func(m *Migrator) error {
-		m.schemaAgreementTimeout = timeout
+		if ttl < time.Second {
+			return fmt.Errorf("scyllamigrate: lock TTL must be at least 1s, got %s", ttl)
+		}
+
+		m.lockTTL = ttl
+
+		return nil
+	}
+}
+
+// WithLockTimeout sets how long to wait for a migration lock held by another process.
+// Zero means fail immediately with ErrLocked if the lock is taken.
+// Default is 5 minutes.
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(m *Migrator) error {
+		if timeout < 0 {
+			return fmt.Errorf("scyllamigrate: lock timeout must not be negative, got %s", timeout)
+		}
+
+		m.lockTimeout = timeout
+
+		return nil
+	}
+}
+
+// DirtyPolicy controls how a migration that failed partway through is handled
+// by the next run.
+type DirtyPolicy int
+
+const (
+	// DirtyResume resumes the failed migration at the statement that failed,
+	// provided the migration content is unchanged. This is the default.
+	DirtyResume DirtyPolicy = iota
+
+	// DirtyRestart runs the failed migration again from its first statement.
+	DirtyRestart
+
+	// DirtyFail refuses to run until the dirty state is cleared with Migrator.Force.
+	DirtyFail
+)
+
+// WithDirtyPolicy sets how a migration that failed partway through is handled
+// by the next run. Default is DirtyResume.
+func WithDirtyPolicy(policy DirtyPolicy) Option {
+	return func(m *Migrator) error {
+		switch policy {
+		case DirtyResume, DirtyRestart, DirtyFail:
+			m.dirtyPolicy = policy
+			return nil
+		default:
+			return fmt.Errorf("scyllamigrate: unknown dirty policy: %d", policy)
+		}
+	}
+}
+
+// OutOfOrderPolicy controls how pending migrations older than the current
+// version are handled, e.g. a version 5 merged after version 7 was applied.
+type OutOfOrderPolicy int
+
+const (
+	// OutOfOrderAllow applies out-of-order migrations and logs a warning for each.
+	// This is the default.
+	OutOfOrderAllow OutOfOrderPolicy = iota
+
+	// OutOfOrderStrict refuses to run with an *OutOfOrderError listing the out-of-order versions.
+	OutOfOrderStrict
+
+	// OutOfOrderIgnore skips out-of-order migrations and leaves them pending.
+	OutOfOrderIgnore
+)
+
+// WithOutOfOrderPolicy sets how pending migrations older than the current
+// version are handled. Default is OutOfOrderAllow.
+func WithOutOfOrderPolicy(policy OutOfOrderPolicy) Option {
+	return func(m *Migrator) error {
+		switch policy {
+		case OutOfOrderAllow, OutOfOrderStrict, OutOfOrderIgnore:
+			m.outOfOrderPolicy = policy
+			return nil
+		default:
+			return fmt.Errorf("scyllamigrate: unknown out-of-order policy: %d", policy)
+		}
+	}
+}
+
+// WithGoMigrations registers migrations implemented in Go.
+// They are merged with the migrations of the source; a version used by both
+// makes New fail with ErrDuplicateVersion.
+func WithGoMigrations(migrations ...*GoMigration) Option {
+	return func(m *Migrator) error {
+		if m.goMigrations == nil {
+			m.goMigrations = make(map[uint64]*GoMigration, len(migrations))
+		}
+
+		for _, gm := range migrations {
+			if gm == nil || (gm.Up == nil) == (gm.UpSession == nil) {
+				return errors.New("scyllamigrate: go migration must have exactly one up function")
+			}
+
+			if gm.Down != nil && gm.DownSession != nil {
+				return errors.New("scyllamigrate: go migration must have at most one down function")
+			}
+
+			if _, ok := m.goMigrations[gm.Version]; ok {
+				return &SourceError{Version: gm.Version, Op: "register go migration", Err: ErrDuplicateVersion}
+			}
+
+			m.goMigrations[gm.Version] = gm
+		}
+
+		return nil
+	}
+}
+
+// WithHooks registers lifecycle hooks invoked while migrations run.
+// The option may be given several times; hooks are called in registration order.
+func WithHooks(hooks ...*Hooks) Option {
+	return func(m *Migrator) error {
+		for _, h := range hooks {
+			if h == nil {
+				return errors.New("scyllamigrate: hooks must not be nil")
+			}
+
+			m.hooks = append(m.hooks, h)
+		}
+
+		return nil
+	}
+}
+
+// WithAllowDestructive sets whether migrations may execute destructive statements,
+// such as DROP, TRUNCATE or ALTER TABLE ... DROP, in either direction.
+// When not allowed, Up, UpTo, Steps, DownTo and Goto fail with a *DestructiveError
+// before executing anything, unless every migration containing such statements
+// carries a "-- scyllamigrate:allow-destructive" comment or the function set with
+// WithDestructiveConfirmation confirms them.
+// Default is false.
+func WithAllowDestructive(allow bool) Option {
+	return func(m *Migrator) error {
+		m.allowDestructive = allow
+		return nil
+	}
+}
+
+// WithDestructiveConfirmation sets the function asked to confirm destructive
+// statements that are not allowed by WithAllowDestructive, e.g. by prompting the user.
+// It is called once per run, with the migration lock held.
+func WithDestructiveConfirmation(confirm ConfirmFunc) Option {
+	return func(m *Migrator) error {
+		if confirm == nil {
+			return errors.New("scyllamigrate: destructive confirmation function must not be nil")
+		}
+
+		m.confirmDestructive = confirm
+
 		return nil
 	}
 }
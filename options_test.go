@@ -1,11 +1,13 @@
 package scyllamigrate
 
 import (
+	"context"
 	"log"
 	"log/slog"
 	"os"
 	"testing"
 	"testing/fstest"
+	"time"
 
 	"github.com/gocql/gocql"
 	td "github.com/maxatome/go-testdeep/td"
@@ -131,10 +133,154 @@ func TestWithSchemaAgreement(t *testing.T) {
 
 func TestWithSchemaAgreementTimeout(t *testing.T) {
 	m := &Migrator{}
-	opt := WithSchemaAgreementTimeout(5000)
+	opt := WithSchemaAgreementTimeout(5 * time.Second)
 
 	td.CmpNoError(t, opt(m))
-	td.Cmp(t, m.schemaAgreementTimeout, 5000)
+	td.Cmp(t, m.schemaAgreementTimeout, 5*time.Second)
+
+	td.CmpError(t, WithSchemaAgreementTimeout(-time.Second)(m))
+}
+
+func TestWithStatementTimeout(t *testing.T) {
+	m := &Migrator{}
+
+	td.CmpNoError(t, WithStatementTimeout(10*time.Second)(m))
+	td.Cmp(t, m.statementTimeout, 10*time.Second)
+
+	td.CmpError(t, WithStatementTimeout(-time.Second)(m))
+}
+
+func TestWithMigrationTimeout(t *testing.T) {
+	m := &Migrator{}
+
+	td.CmpNoError(t, WithMigrationTimeout(time.Hour)(m))
+	td.Cmp(t, m.migrationTimeout, time.Hour)
+
+	td.CmpError(t, WithMigrationTimeout(-time.Second)(m))
+}
+
+func TestWithChecksumVerification(t *testing.T) {
+	m := &Migrator{verifyChecksums: true}
+	opt := WithChecksumVerification(false)
+
+	td.CmpNoError(t, opt(m))
+	td.Cmp(t, m.verifyChecksums, false)
+
+	opt = WithChecksumVerification(true)
+	td.CmpNoError(t, opt(m))
+	td.Cmp(t, m.verifyChecksums, true)
+}
+
+func TestWithLock(t *testing.T) {
+	m := &Migrator{useLock: true}
+	opt := WithLock(false)
+
+	td.CmpNoError(t, opt(m))
+	td.Cmp(t, m.useLock, false)
+}
+
+func TestWithLockTTL(t *testing.T) {
+	m := &Migrator{}
+
+	td.CmpNoError(t, WithLockTTL(30*time.Second)(m))
+	td.Cmp(t, m.lockTTL, 30*time.Second)
+
+	td.CmpError(t, WithLockTTL(500*time.Millisecond)(m))
+	td.Cmp(t, m.lockTTL, 30*time.Second)
+}
+
+func TestWithLockTimeout(t *testing.T) {
+	m := &Migrator{}
+	opt := WithLockTimeout(time.Minute)
+
+	td.CmpNoError(t, opt(m))
+	td.Cmp(t, m.lockTimeout, time.Minute)
+
+	td.CmpNoError(t, WithLockTimeout(0)(m))
+	td.Cmp(t, m.lockTimeout, time.Duration(0))
+
+	td.CmpError(t, WithLockTimeout(-time.Second)(m))
+	td.Cmp(t, m.lockTimeout, time.Duration(0))
+}
+
+func TestWithDirtyPolicy(t *testing.T) {
+	m := &Migrator{}
+
+	td.CmpNoError(t, WithDirtyPolicy(DirtyFail)(m))
+	td.Cmp(t, m.dirtyPolicy, DirtyFail)
+
+	td.CmpNoError(t, WithDirtyPolicy(DirtyRestart)(m))
+	td.Cmp(t, m.dirtyPolicy, DirtyRestart)
+
+	td.CmpError(t, WithDirtyPolicy(DirtyPolicy(42))(m))
+	td.Cmp(t, m.dirtyPolicy, DirtyRestart)
+}
+
+func TestWithGoMigrations(t *testing.T) {
+	up := func(context.Context, *gocql.Session) error { return nil }
+	upSession := func(context.Context, Session) error { return nil }
+
+	m := &Migrator{}
+	td.CmpNoError(t, WithGoMigrations(
+		&GoMigration{Version: 1, Up: up},
+		&GoMigration{Version: 2, Up: up},
+	)(m))
+	td.Cmp(t, len(m.goMigrations), 2)
+
+	// Registering more migrations later adds to the existing ones.
+	td.CmpNoError(t, WithGoMigrations(&GoMigration{Version: 3, Up: up})(m))
+	td.Cmp(t, len(m.goMigrations), 3)
+
+	td.CmpErrorIs(t, WithGoMigrations(&GoMigration{Version: 2, Up: up})(m), ErrDuplicateVersion)
+	td.CmpNoError(t, WithGoMigrations(&GoMigration{Version: 4, UpSession: upSession})(m))
+	td.Cmp(t, len(m.goMigrations), 4)
+
+	td.CmpError(t, WithGoMigrations(&GoMigration{Version: 5})(m))
+	td.CmpError(t, WithGoMigrations(&GoMigration{Version: 5, Up: up, UpSession: upSession})(m))
+	td.CmpError(t, WithGoMigrations(&GoMigration{Version: 5, Up: up, Down: up, DownSession: upSession})(m))
+	td.CmpError(t, WithGoMigrations(nil)(m))
+}
+
+func TestWithOutOfOrderPolicy(t *testing.T) {
+	m := &Migrator{}
+
+	td.CmpNoError(t, WithOutOfOrderPolicy(OutOfOrderStrict)(m))
+	td.Cmp(t, m.outOfOrderPolicy, OutOfOrderStrict)
+
+	td.CmpNoError(t, WithOutOfOrderPolicy(OutOfOrderIgnore)(m))
+	td.Cmp(t, m.outOfOrderPolicy, OutOfOrderIgnore)
+
+	td.CmpError(t, WithOutOfOrderPolicy(OutOfOrderPolicy(42))(m))
+}
+
+func TestWithHooks(t *testing.T) {
+	m := &Migrator{}
+
+	first, second := &Hooks{}, &Hooks{}
+
+	td.CmpNoError(t, WithHooks(first)(m))
+	td.CmpNoError(t, WithHooks(second)(m))
+	td.Cmp(t, m.hooks, []*Hooks{first, second})
+
+	td.CmpError(t, WithHooks(nil)(m))
+}
+
+func TestWithAllowDestructive(t *testing.T) {
+	m := &Migrator{}
+
+	td.CmpNoError(t, WithAllowDestructive(true)(m))
+	td.Cmp(t, m.allowDestructive, true)
+}
+
+func TestWithDestructiveConfirmation(t *testing.T) {
+	m := &Migrator{}
+
+	td.CmpNoError(t, WithDestructiveConfirmation(func(context.Context, []DestructiveStatement) (bool, error) {
+		return true, nil
+	})(m))
+	td.Cmp(t, m.confirmDestructive, td.NotNil())
+
+	td.CmpError(t, WithDestructiveConfirmation(nil)(m))
 }
 
 func TestMultipleOptions(t *testing.T) {
@@ -150,7 +296,7 @@ func TestMultipleOptions(t *testing.T) {
 		WithLogger(slog.Default()),
 		WithConsistency(gocql.Quorum),
 		WithSchemaAgreement(true),
-		WithSchemaAgreementTimeout(1000),
+		WithSchemaAgreementTimeout(30*time.Second),
 	}
 
 	for _, opt := range opts {
@@ -163,5 +309,5 @@ func TestMultipleOptions(t *testing.T) {
 	td.Cmp(t, m.logger, td.NotNil())
 	td.Cmp(t, m.consistency, gocql.Quorum)
 	td.Cmp(t, m.waitForSchemaAgreement, true)
-	td.Cmp(t, m.schemaAgreementTimeout, 1000)
+	td.Cmp(t, m.schemaAgreementTimeout, 30*time.Second)
 }
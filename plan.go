@@ -0,0 +1,271 @@
+package scyllamigrate
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+)
+
+// Target describes which migrations a run executes.
+// Use TargetLatest, TargetUpTo, TargetDownTo or TargetSteps to build one.
+type Target struct {
+	direction Direction
+
+	// version bounds the run: up runs apply versions <= version,
+	// down runs roll back versions > version.
+	version uint64
+
+	// limit is the maximum number of migrations to execute if limited is set.
+	limit   int
+	limited bool
+
+	// rolledBack is set for the up step of Goto, which runs after applied
+	// versions above version were rolled back.
+	rolledBack bool
+}
+
+// TargetLatest targets all pending migrations, as run by Up.
+func TargetLatest() Target {
+	return Target{direction: Up, version: math.MaxUint64}
+}
+
+// TargetUpTo targets pending migrations up to and including version, as run by UpTo.
+func TargetUpTo(version uint64) Target {
+	return Target{direction: Up, version: version}
+}
+
+// TargetDownTo targets applied migrations down to (but not including) version, as run by DownTo.
+func TargetDownTo(version uint64) Target {
+	return Target{direction: Down, version: version}
+}
+
+// TargetSteps targets n migrations, as run by Steps.
+// Positive n moves up, negative moves down.
+func TargetSteps(n int) Target {
+	if n < 0 {
+		return Target{direction: Down, limit: -n, limited: true}
+	}
+
+	return Target{direction: Up, version: math.MaxUint64, limit: n, limited: true}
+}
+
+// Direction returns the direction of the run.
+func (t Target) Direction() Direction { return t.direction }
+
+// PlannedMigration is a migration that a run would execute.
+type PlannedMigration struct {
+	// Version is the migration version.
+	Version uint64
+
+	// Description is the human-readable description.
+	Description string
+
+	// Direction is the direction the migration would run in.
+	Direction Direction
+
+	// File is the migration filename. Empty for Go migrations.
+	File string
+
+	// Go reports whether the migration is implemented in Go.
+	// Go migrations have no statements.
+	Go bool
+
+	// FromStatement is the 1-based index of the first statement that would run.
+	// It is greater than 1 when a dirty migration would be resumed.
+	FromStatement int
+
+	// Statements are the CQL statements that would run, in order.
+	Statements []Statement
+}
+
+// Plan returns the migrations and statements a run towards target would execute,
+// in execution order, without executing anything. It only reads the migration
+// history, so it does not create the history table and does not take the lock.
+// Plan fails with the same errors the run would fail with before executing
+// anything, such as a *DirtyError or a *ChecksumError, except *DestructiveError.
+func (m *Migrator) Plan(ctx context.Context, target Target) ([]*PlannedMigration, error) {
+	var resume *DirtyState
+
+	if m.historyTableExists(ctx) {
+		if m.tableExists(ctx, m.dirtyTable()) {
+			var err error
+
+			if resume, err = m.recoverDirty(ctx, target.direction); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := m.verify(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	pairs, _, err := m.selectMigrations(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := make([]*PlannedMigration, 0, len(pairs))
+
+	for _, pair := range pairs {
+		planned, err := m.planMigration(pair, target.direction, resume)
+		if err != nil {
+			return nil, err
+		}
+
+		plan = append(plan, planned)
+	}
+
+	return plan, nil
+}
+
+// planMigration reads and splits a single migration the way applyUp and applyDown execute it.
+func (m *Migrator) planMigration(pair *MigrationPair, direction Direction, resume *DirtyState) (*PlannedMigration, error) {
+	migration := pair.Up
+	if direction == Down {
+		migration = pair.Down
+	}
+
+	if migration == nil {
+		missing := ErrMissingUp
+
+		if direction == Down {
+			missing = ErrMissingDown
+
+			if m.findPair(pair.Version) == nil {
+				missing = ErrVersionNotFound
+			}
+		}
+
+		return nil, &MigrationError{Version: pair.Version, Direction: direction, Err: missing}
+	}
+
+	planned := &PlannedMigration{
+		Version:       pair.Version,
+		Description:   pair.Description,
+		Direction:     direction,
+		File:          migration.Raw,
+		FromStatement: resume.from(pair.Version, direction),
+	}
+
+	if _, ok := m.goMigrations[pair.Version]; ok {
+		planned.Go = true
+		return planned, nil
+	}
+
+	content, err := m.readMigrationContent(pair.Version, direction)
+	if err != nil {
+		return nil, err
+	}
+
+	statements, err := SplitStatements(string(content))
+	if err != nil {
+		migrationErr := &MigrationError{Version: pair.Version, Direction: direction, File: migration.Raw, Err: err}
+
+		var syntaxErr *SyntaxError
+		if errors.As(err, &syntaxErr) {
+			migrationErr.Line = syntaxErr.Line
+		}
+
+		return nil, migrationErr
+	}
+
+	planned.Statements = statements[min(planned.FromStatement-1, len(statements)):]
+
+	return planned, nil
+}
+
+// selectMigrations returns the migrations a run towards target executes, in execution order,
+// and the versions of pending migrations skipped by the out-of-order policy.
+// For down runs, applied versions that are missing from the migrations are
+// returned as pairs without up and down migrations.
+func (m *Migrator) selectMigrations(ctx context.Context, target Target) ([]*MigrationPair, []uint64, error) {
+	if target.limited && target.limit == 0 {
+		return nil, nil, nil
+	}
+
+	var selected []*MigrationPair
+
+	var skipped []uint64
+
+	switch target.direction {
+	case Up:
+		pairs, err := m.migrations()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Without a history table nothing has been applied yet.
+		applied := make(map[uint64]bool)
+
+		if m.historyTableExists(ctx) {
+			if applied, err = m.getAppliedVersions(ctx); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		for _, pair := range pairs {
+			if pair.Version > target.version {
+				break
+			}
+
+			if !applied[pair.Version] {
+				selected = append(selected, pair)
+			}
+		}
+
+		current := latestVersion(applied)
+		if target.rolledBack {
+			current = latestVersionUpTo(applied, target.version)
+		}
+
+		if selected, skipped, err = m.applyOutOfOrderPolicy(ctx, selected, current); err != nil {
+			return nil, nil, err
+		}
+
+	case Down:
+		if !m.historyTableExists(ctx) {
+			return nil, nil, nil
+		}
+
+		applied, err := m.getAppliedMigrations(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		pairs, err := m.migrations()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		available := make(map[uint64]*MigrationPair, len(pairs))
+		for _, pair := range pairs {
+			available[pair.Version] = pair
+		}
+
+		// Sort by version descending.
+		sort.Slice(applied, func(i, j int) bool {
+			return applied[i].Version > applied[j].Version
+		})
+
+		for _, am := range applied {
+			if am.Version <= target.version {
+				break
+			}
+
+			pair, ok := available[am.Version]
+			if !ok {
+				pair = &MigrationPair{Version: am.Version, Description: am.Description}
+			}
+
+			selected = append(selected, pair)
+		}
+	}
+
+	if target.limited && len(selected) > target.limit {
+		selected = selected[:target.limit]
+	}
+
+	return selected, skipped, nil
+}
@@ -0,0 +1,110 @@
+package scyllamigrate
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"testing/fstest"
+
+	td "github.com/maxatome/go-testdeep/td"
+)
+
+func TestTargets(t *testing.T) {
+	type tcase struct {
+		target   Target
+		expected Target
+	}
+
+	tests := map[string]tcase{
+		"latest":     {target: TargetLatest(), expected: Target{direction: Up, version: math.MaxUint64}},
+		"up to":      {target: TargetUpTo(3), expected: Target{direction: Up, version: 3}},
+		"down to":    {target: TargetDownTo(1), expected: Target{direction: Down, version: 1}},
+		"steps up":   {target: TargetSteps(2), expected: Target{direction: Up, version: math.MaxUint64, limit: 2, limited: true}},
+		"steps down": {target: TargetSteps(-2), expected: Target{direction: Down, limit: 2, limited: true}},
+		"zero steps": {target: TargetSteps(0), expected: Target{direction: Up, version: math.MaxUint64, limited: true}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			td.Cmp(t, tc.target, tc.expected)
+			td.Cmp(t, tc.target.Direction(), tc.expected.direction)
+		})
+	}
+}
+
+func TestMigrator_planMigration(t *testing.T) {
+	fsys := fstest.MapFS{
+		"000001_create_users.up.cql":   {Data: []byte("CREATE TABLE users (id int PRIMARY KEY);\n\n-- index\nCREATE INDEX ON users (id);\n")},
+		"000001_create_users.down.cql": {Data: []byte("DROP TABLE users;")},
+		"000002_create_posts.up.cql":   {Data: []byte("CREATE TABLE posts (id int PRIMARY KEY);")},
+		"000003_broken.up.cql":         {Data: []byte("INSERT INTO t (v) VALUES ('oops);")},
+	}
+
+	source, err := NewFSSource(fsys)
+	td.CmpNoError(t, err)
+
+	m := &Migrator{source: source}
+	td.CmpNoError(t, WithGoMigrations(&GoMigration{Version: 4, Description: "backfill", Up: noopGoMigration})(m))
+
+	pairs, err := m.migrations()
+	td.CmpNoError(t, err)
+
+	t.Run("up", func(t *testing.T) {
+		planned, err := m.planMigration(pairs[0], Up, nil)
+		td.CmpNoError(t, err)
+		td.Cmp(t, planned, &PlannedMigration{
+			Version:       1,
+			Description:   "create_users",
+			Direction:     Up,
+			File:          "000001_create_users.up.cql",
+			FromStatement: 1,
+			Statements: []Statement{
+				{Text: "CREATE TABLE users (id int PRIMARY KEY)", Line: 1},
+				{Text: "CREATE INDEX ON users (id)", Line: 4},
+			},
+		})
+	})
+
+	t.Run("down", func(t *testing.T) {
+		planned, err := m.planMigration(pairs[0], Down, nil)
+		td.CmpNoError(t, err)
+		td.Cmp(t, planned.File, "000001_create_users.down.cql")
+		td.Cmp(t, planned.Statements, []Statement{{Text: "DROP TABLE users", Line: 1}})
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		planned, err := m.planMigration(pairs[0], Up, &DirtyState{Version: 1, Direction: Up, Statement: 2})
+		td.CmpNoError(t, err)
+		td.Cmp(t, planned.FromStatement, 2)
+		td.Cmp(t, planned.Statements, []Statement{{Text: "CREATE INDEX ON users (id)", Line: 4}})
+	})
+
+	t.Run("missing down", func(t *testing.T) {
+		_, err := m.planMigration(pairs[1], Down, nil)
+		td.CmpErrorIs(t, err, ErrMissingDown)
+	})
+
+	t.Run("version not found", func(t *testing.T) {
+		_, err := m.planMigration(&MigrationPair{Version: 9}, Down, nil)
+		td.CmpErrorIs(t, err, ErrVersionNotFound)
+	})
+
+	t.Run("syntax error", func(t *testing.T) {
+		_, err := m.planMigration(pairs[2], Up, nil)
+
+		var syntaxErr *SyntaxError
+		td.Cmp(t, errors.As(err, &syntaxErr), true)
+
+		var migrationErr *MigrationError
+		td.Cmp(t, errors.As(err, &migrationErr), true)
+		td.Cmp(t, migrationErr.Line, 1)
+	})
+
+	t.Run("go migration", func(t *testing.T) {
+		planned, err := m.planMigration(pairs[3], Up, nil)
+		td.CmpNoError(t, err)
+		td.Cmp(t, planned.Go, true)
+		td.Cmp(t, planned.File, "")
+		td.CmpNil(t, planned.Statements)
+	})
+}
@@ -0,0 +1,62 @@
+package scyllamigrate
+
+import "time"
+
+// Result describes what a migration run did.
+type Result struct {
+	// Executed are the migrations that ran successfully, in execution order.
+	Executed []ExecutedMigration
+
+	// Skipped are the versions of pending migrations that were not applied
+	// because they are out of order and the policy is OutOfOrderIgnore.
+	Skipped []uint64
+}
+
+// ExecutedMigration is a migration executed by a run.
+type ExecutedMigration struct {
+	// Version is the migration version.
+	Version uint64
+
+	// Description is the human-readable description.
+	Description string
+
+	// Direction is the direction the migration ran in.
+	Direction Direction
+
+	// Duration is how long the migration took.
+	Duration time.Duration
+}
+
+// Count returns the number of executed migrations.
+func (r *Result) Count() int {
+	if r == nil {
+		return 0
+	}
+
+	return len(r.Executed)
+}
+
+// Versions returns the versions of the executed migrations in execution order.
+func (r *Result) Versions() []uint64 {
+	if r == nil {
+		return nil
+	}
+
+	versions := make([]uint64, 0, len(r.Executed))
+
+	for _, em := range r.Executed {
+		versions = append(versions, em.Version)
+	}
+
+	return versions
+}
+
+// add records a successfully executed migration.
+func (r *Result) add(pair *MigrationPair, direction Direction, duration time.Duration) {
+	r.Executed = append(r.Executed, ExecutedMigration{
+		Version:     pair.Version,
+		Description: pair.Description,
+		Direction:   direction,
+		Duration:    duration,
+	})
+}
@@ -0,0 +1,28 @@
+package scyllamigrate
+
+import (
+	"testing"
+	"time"
+
+	td "github.com/maxatome/go-testdeep/td"
+)
+
+func TestResult(t *testing.T) {
+	var result *Result
+
+	td.Cmp(t, result.Count(), 0)
+	td.CmpNil(t, result.Versions())
+
+	result = &Result{}
+	result.add(&MigrationPair{Version: 3, Description: "add_index"}, Down, 2*time.Second)
+	result.add(&MigrationPair{Version: 2, Description: "create_posts"}, Down, time.Second)
+
+	td.Cmp(t, result.Count(), 2)
+	td.Cmp(t, result.Versions(), []uint64{3, 2})
+	td.Cmp(t, result.Executed[0], ExecutedMigration{
+		Version:     3,
+		Description: "add_index",
+		Direction:   Down,
+		Duration:    2 * time.Second,
+	})
+}
@@ -0,0 +1,330 @@
+package scyllamigrate
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// emptyPayloadHash is the SHA-256 checksum of an empty request body.
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Config configures a source reading migration files from an S3-compatible
+// object storage such as Amazon S3, MinIO or Ceph.
+type S3Config struct {
+	// Endpoint is the URL of the storage, e.g. "https://s3.eu-west-1.amazonaws.com"
+	// or "http://localhost:9000". Objects are addressed path-style as Endpoint/Bucket/Key.
+	Endpoint string
+
+	// Region is the region requests are signed for, "us-east-1" if empty.
+	Region string
+
+	// Bucket is the bucket holding the migration files.
+	Bucket string
+
+	// Prefix is the key prefix of the migration files, e.g. "migrations/v1.4.0/".
+	Prefix string
+
+	// AccessKeyID is the access key. Requests are anonymous if it is empty.
+	AccessKeyID string
+
+	// SecretAccessKey is the secret key.
+	SecretAccessKey string
+
+	// SessionToken is the session token of temporary credentials.
+	SessionToken string
+}
+
+// NewS3Source creates a Source from the migration files stored under the
+// prefix of an S3-compatible bucket, including nested "directories".
+//
+// If WithManifest is given, the manifest object is read relative to the prefix
+// and the source fails with a *ManifestError if a file is not listed or its
+// checksum does not match. The files are held in memory.
+func NewS3Source(ctx context.Context, cfg S3Config, opts ...RemoteOption) (*FSSource, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, errors.New("scyllamigrate: S3 source requires an endpoint and a bucket")
+	}
+
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, &SourceError{Op: "list", Err: err}
+	}
+
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	cfg.Prefix = strings.TrimPrefix(cfg.Prefix, "/")
+	if cfg.Prefix != "" && !strings.HasSuffix(cfg.Prefix, "/") {
+		cfg.Prefix += "/"
+	}
+
+	remote := newRemoteConfig(opts)
+	client := &s3Client{
+		endpoint: endpoint,
+		config:   cfg,
+		http:     remote.client,
+		signer: &s3Signer{
+			region:       cfg.Region,
+			accessKey:    cfg.AccessKeyID,
+			secretKey:    cfg.SecretAccessKey,
+			sessionToken: cfg.SessionToken,
+			now:          time.Now,
+		},
+	}
+
+	keys, err := client.list(ctx)
+	if err != nil {
+		return nil, &SourceError{Op: "list", Err: err}
+	}
+
+	var manifest Manifest
+
+	if remote.manifest != "" {
+		data, err := client.get(ctx, cfg.Prefix+remote.manifest)
+		if err != nil {
+			return nil, &SourceError{Op: "fetch manifest", Err: err}
+		}
+
+		if manifest, err = ParseManifest(bytes.NewReader(data)); err != nil {
+			return nil, &SourceError{Op: "fetch manifest", Err: err}
+		}
+	}
+
+	files := make(memFS)
+
+	for _, key := range keys {
+		name := strings.TrimPrefix(key, cfg.Prefix)
+		if !fs.ValidPath(name) || !isRemoteFile(name) {
+			continue
+		}
+
+		data, err := client.get(ctx, key)
+		if err != nil {
+			return nil, &SourceError{Op: "fetch", Err: err}
+		}
+
+		files[name] = data
+	}
+
+	return newMemorySource(files, manifest, func(name string) string {
+		return "s3://" + cfg.Bucket + "/" + cfg.Prefix + name
+	})
+}
+
+// s3Client sends the requests of an S3 source.
+type s3Client struct {
+	endpoint *url.URL
+	config   S3Config
+	http     *http.Client
+	signer   *s3Signer
+}
+
+// list returns the keys of all objects under the prefix, following continuation tokens.
+func (c *s3Client) list(ctx context.Context) ([]string, error) {
+	var (
+		keys  []string
+		token string
+	)
+
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {c.config.Prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		u := c.objectURL("")
+		u.RawQuery = s3Query(query)
+
+		data, err := httpGet(ctx, c.http, u.String(), c.signer.sign)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+
+		if err := xml.Unmarshal(data, &result); err != nil {
+			return nil, fmt.Errorf("invalid ListObjectsV2 response: %w", err)
+		}
+
+		for _, object := range result.Contents {
+			keys = append(keys, object.Key)
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return keys, nil
+		}
+
+		token = result.NextContinuationToken
+	}
+}
+
+// get downloads the object with the given key.
+func (c *s3Client) get(ctx context.Context, key string) ([]byte, error) {
+	return httpGet(ctx, c.http, c.objectURL(key).String(), c.signer.sign)
+}
+
+// objectURL returns the path-style URL of the object with the given key,
+// or of the bucket if key is empty.
+func (c *s3Client) objectURL(key string) *url.URL {
+	u := *c.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + c.config.Bucket
+	u.RawQuery = ""
+
+	if key != "" {
+		u.Path += "/" + key
+	}
+
+	// Send the path encoded exactly as it is signed.
+	u.RawPath = s3Escape(u.Path, true)
+
+	return &u
+}
+
+// s3Signer signs requests with AWS Signature Version 4.
+type s3Signer struct {
+	region       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	now          func() time.Time
+}
+
+// sign adds the Authorization header to req, signing the host and all headers of req.
+// Requests are left anonymous without an access key.
+func (s *s3Signer) sign(req *http.Request) {
+	if s.accessKey == "" {
+		return
+	}
+
+	amzDate := s.now().UTC().Format("20060102T150405Z")
+	scope := amzDate[:8] + "/" + s.region + "/s3/aws4_request"
+
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	if req.Header.Get("X-Amz-Content-Sha256") == "" {
+		req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+	}
+
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(strings.Fields(strings.Join(values, ",")), " ")
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalPath := s3Escape(req.URL.Path, true)
+	if canonicalPath == "" {
+		canonicalPath = "/"
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalPath,
+		s3Query(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		req.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := []byte("AWS4" + s.secretKey)
+	for _, part := range []string{amzDate[:8], s.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, hex.EncodeToString(hmacSHA256(key, stringToSign))))
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with key.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+
+	return mac.Sum(nil)
+}
+
+// s3Query encodes query parameters sorted by name as required for signing.
+func s3Query(query url.Values) string {
+	type param struct{ name, value string }
+
+	var params []param
+
+	for name, values := range query {
+		for _, value := range values {
+			params = append(params, param{s3Escape(name, false), s3Escape(value, false)})
+		}
+	}
+
+	sort.Slice(params, func(i, j int) bool {
+		if params[i].name != params[j].name {
+			return params[i].name < params[j].name
+		}
+
+		return params[i].value < params[j].value
+	})
+
+	encoded := make([]string, len(params))
+	for i, p := range params {
+		encoded[i] = p.name + "=" + p.value
+	}
+
+	return strings.Join(encoded, "&")
+}
+
+// s3Escape percent-encodes every byte of s except unreserved characters and,
+// if keepSlash is set, "/".
+func s3Escape(s string, keepSlash bool) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && keepSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
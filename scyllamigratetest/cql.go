@@ -0,0 +1,419 @@
+package scyllamigratetest
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// tokenKind is the kind of a CQL token.
+type tokenKind int
+
+const (
+	tokenIdent tokenKind = iota
+	tokenQuotedIdent
+	tokenString
+	tokenNumber
+	tokenBind
+	tokenPunct
+)
+
+// token is a single CQL token.
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// tokenize splits a CQL statement into tokens.
+func tokenize(stmt string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(stmt); {
+		c := rune(stmt[i])
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '\'' || c == '"':
+			text, n, err := scanQuoted(stmt[i:], byte(c))
+			if err != nil {
+				return nil, err
+			}
+
+			kind := tokenString
+			if c == '"' {
+				kind = tokenQuotedIdent
+			}
+
+			tokens = append(tokens, token{kind: kind, text: text})
+			i += n
+
+		case c == '?':
+			tokens = append(tokens, token{kind: tokenBind, text: "?"})
+			i++
+
+		case unicode.IsDigit(c) || (c == '-' && i+1 < len(stmt) && unicode.IsDigit(rune(stmt[i+1]))):
+			j := i + 1
+			for j < len(stmt) && (unicode.IsDigit(rune(stmt[j])) || stmt[j] == '.') {
+				j++
+			}
+
+			tokens = append(tokens, token{kind: tokenNumber, text: stmt[i:j]})
+			i = j
+
+		case unicode.IsLetter(c) || c == '_':
+			j := i + 1
+			for j < len(stmt) && (unicode.IsLetter(rune(stmt[j])) || unicode.IsDigit(rune(stmt[j])) || stmt[j] == '_') {
+				j++
+			}
+
+			tokens = append(tokens, token{kind: tokenIdent, text: stmt[i:j]})
+			i = j
+
+		default:
+			tokens = append(tokens, token{kind: tokenPunct, text: string(c)})
+			i++
+		}
+	}
+
+	return tokens, nil
+}
+
+// scanQuoted reads a literal quoted with q, where a doubled quote escapes it.
+// Returns the unquoted text and the number of bytes consumed.
+func scanQuoted(s string, q byte) (string, int, error) {
+	var b strings.Builder
+
+	for i := 1; i < len(s); i++ {
+		if s[i] != q {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		if i+1 < len(s) && s[i+1] == q {
+			b.WriteByte(q)
+			i++
+
+			continue
+		}
+
+		return b.String(), i + 1, nil
+	}
+
+	return "", 0, errors.New("unterminated quoted literal")
+}
+
+// parser reads the tokens of a statement and binds the ? markers to values in order.
+type parser struct {
+	tokens []token
+	pos    int
+	values []any
+	bound  int
+}
+
+// newParser tokenizes stmt.
+func newParser(stmt string, values []any) (*parser, error) {
+	tokens, err := tokenize(stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parser{tokens: tokens, values: values}, nil
+}
+
+// done reports whether all tokens were consumed.
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+// isKeyword reports whether the token at offset n from the current position is the keyword kw.
+func (p *parser) isKeyword(n int, kw string) bool {
+	i := p.pos + n
+
+	return i < len(p.tokens) && p.tokens[i].kind == tokenIdent && strings.EqualFold(p.tokens[i].text, kw)
+}
+
+// acceptKeywords consumes the keywords if the next tokens are exactly these keywords.
+func (p *parser) acceptKeywords(kws ...string) bool {
+	for i, kw := range kws {
+		if !p.isKeyword(i, kw) {
+			return false
+		}
+	}
+
+	p.pos += len(kws)
+
+	return true
+}
+
+// expectKeywords consumes the keywords or returns an error.
+func (p *parser) expectKeywords(kws ...string) error {
+	if !p.acceptKeywords(kws...) {
+		return fmt.Errorf("expected %s at %s", strings.Join(kws, " "), p.near())
+	}
+
+	return nil
+}
+
+// isPunct reports whether the next token is the punctuation s.
+func (p *parser) isPunct(s string) bool {
+	return !p.done() && p.tokens[p.pos].kind == tokenPunct && p.tokens[p.pos].text == s
+}
+
+// acceptPunct consumes the punctuation s if it is next.
+func (p *parser) acceptPunct(s string) bool {
+	if !p.isPunct(s) {
+		return false
+	}
+
+	p.pos++
+
+	return true
+}
+
+// expectPunct consumes the punctuation s or returns an error.
+func (p *parser) expectPunct(s string) error {
+	if !p.acceptPunct(s) {
+		return fmt.Errorf("expected %q at %s", s, p.near())
+	}
+
+	return nil
+}
+
+// ident reads an identifier. Unquoted identifiers are case-insensitive and lowercased.
+func (p *parser) ident() (string, error) {
+	if p.done() {
+		return "", errors.New("expected an identifier at end of statement")
+	}
+
+	tok := p.tokens[p.pos]
+
+	switch tok.kind {
+	case tokenIdent:
+		p.pos++
+		return strings.ToLower(tok.text), nil
+	case tokenQuotedIdent:
+		p.pos++
+		return tok.text, nil
+	default:
+		return "", fmt.Errorf("expected an identifier at %s", p.near())
+	}
+}
+
+// tableName reads a table name optionally qualified with a keyspace.
+// The keyspace is empty if the name is not qualified.
+func (p *parser) tableName() (string, string, error) {
+	name, err := p.ident()
+	if err != nil {
+		return "", "", err
+	}
+
+	if !p.acceptPunct(".") {
+		return "", name, nil
+	}
+
+	table, err := p.ident()
+	if err != nil {
+		return "", "", err
+	}
+
+	return name, table, nil
+}
+
+// term reads a bind marker or a literal value.
+func (p *parser) term() (any, error) {
+	if p.done() {
+		return nil, errors.New("expected a value at end of statement")
+	}
+
+	tok := p.tokens[p.pos]
+	p.pos++
+
+	switch tok.kind {
+	case tokenBind:
+		if p.bound >= len(p.values) {
+			return nil, fmt.Errorf("not enough values for %d bind markers", p.bound+1)
+		}
+
+		v := p.values[p.bound]
+		p.bound++
+
+		return v, nil
+
+	case tokenString:
+		return tok.text, nil
+
+	case tokenNumber:
+		if strings.Contains(tok.text, ".") {
+			return strconv.ParseFloat(tok.text, 64)
+		}
+
+		return strconv.ParseInt(tok.text, 10, 64)
+
+	case tokenIdent:
+		switch strings.ToLower(tok.text) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+	}
+
+	p.pos--
+
+	return nil, fmt.Errorf("expected a value at %s", p.near())
+}
+
+// skipNested skips tokens up to the next top-level "," or ")" without consuming it
+// and returns the text of the skipped tokens. Nested (), <> and {} are skipped whole.
+func (p *parser) skipNested() string {
+	var (
+		parts []string
+		depth int
+	)
+
+	for !p.done() {
+		tok := p.tokens[p.pos]
+
+		if tok.kind == tokenPunct {
+			switch tok.text {
+			case "(", "<", "{", "[":
+				depth++
+			case ")", ">", "}", "]":
+				if depth == 0 {
+					return strings.Join(parts, "")
+				}
+
+				depth--
+			case ",":
+				if depth == 0 {
+					return strings.Join(parts, "")
+				}
+			}
+		}
+
+		text := tok.text
+		if tok.kind == tokenIdent {
+			text = strings.ToLower(text)
+		}
+
+		if len(parts) > 0 && tok.kind == tokenIdent && p.tokens[p.pos-1].kind == tokenIdent {
+			text = " " + text
+		}
+
+		parts = append(parts, text)
+		p.pos++
+	}
+
+	return strings.Join(parts, "")
+}
+
+// near describes the current position for error messages.
+func (p *parser) near() string {
+	if p.done() {
+		return "end of statement"
+	}
+
+	return strconv.Quote(p.tokens[p.pos].text)
+}
+
+// condition is a "column = value" relation of a WHERE or IF clause.
+type condition struct {
+	column string
+	value  any
+}
+
+// conditions reads relations joined with AND. Only equality is supported.
+func (p *parser) conditions() ([]condition, error) {
+	var conds []condition
+
+	for {
+		column, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expectPunct("="); err != nil {
+			return nil, err
+		}
+
+		value, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+
+		conds = append(conds, condition{column: column, value: value})
+
+		if !p.acceptKeywords("AND") {
+			return conds, nil
+		}
+	}
+}
+
+// identList reads a parenthesized, comma-separated list of identifiers.
+func (p *parser) identList() ([]string, error) {
+	if err := p.expectPunct("("); err != nil {
+		return nil, err
+	}
+
+	var names []string
+
+	for {
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+
+		names = append(names, name)
+
+		if !p.acceptPunct(",") {
+			break
+		}
+	}
+
+	return names, p.expectPunct(")")
+}
+
+// ttl reads an optional USING TTL clause. USING TIMESTAMP is accepted and ignored.
+// Returns 0 if there is no TTL.
+func (p *parser) ttl() (int64, error) {
+	if !p.acceptKeywords("USING") {
+		return 0, nil
+	}
+
+	var ttl int64
+
+	for {
+		switch {
+		case p.acceptKeywords("TTL"):
+			v, err := p.term()
+			if err != nil {
+				return 0, err
+			}
+
+			n, ok := toInt64(v)
+			if !ok {
+				return 0, fmt.Errorf("invalid TTL %v", v)
+			}
+
+			ttl = n
+
+		case p.acceptKeywords("TIMESTAMP"):
+			if _, err := p.term(); err != nil {
+				return 0, err
+			}
+
+		default:
+			return 0, fmt.Errorf("expected TTL or TIMESTAMP at %s", p.near())
+		}
+
+		if !p.acceptKeywords("AND") {
+			return ttl, nil
+		}
+	}
+}
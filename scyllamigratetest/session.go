@@ -0,0 +1,962 @@
+// Package scyllamigratetest provides an in-memory scyllamigrate.Session for
+// testing migrations without a ScyllaDB cluster.
+//
+// The fake interprets the subset of CQL used by the Migrator and by typical
+// schema migrations:
+//   - CREATE KEYSPACE, DROP KEYSPACE and USE
+//   - CREATE TABLE, ALTER TABLE ... ADD/DROP, DROP TABLE and TRUNCATE
+//   - INSERT, UPDATE and DELETE with equality WHERE clauses, USING TTL,
+//     IF NOT EXISTS, IF EXISTS and IF column = value conditions
+//   - SELECT of columns, *, COUNT(*) and TTL(column) with equality WHERE clauses
+//   - SELECT from system_schema.keyspaces, system_schema.tables and system_schema.columns
+//
+// Other statements, e.g. CREATE INDEX or BEGIN BATCH, are recorded but have no effect.
+package scyllamigratetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocql/gocql"
+
+	"github.com/heartwilltell/scyllamigrate"
+)
+
+// Compile time check that Session implements scyllamigrate.Session.
+var _ scyllamigrate.Session = (*Session)(nil)
+
+// Session is an in-memory implementation of scyllamigrate.Session.
+// It is safe for concurrent use.
+type Session struct {
+	mu         sync.Mutex
+	keyspace   string
+	keyspaces  map[string]bool
+	tables     map[string]*table
+	statements []string
+	failures   map[string]error
+	clock      time.Duration
+}
+
+// NewSession creates an empty session. The keyspace, if not empty, exists and
+// is used for table names that are not qualified with a keyspace, like the
+// keyspace of a gocql cluster configuration.
+func NewSession(keyspace string) *Session {
+	s := &Session{
+		keyspace:  keyspace,
+		keyspaces: make(map[string]bool),
+		tables:    make(map[string]*table),
+		failures:  make(map[string]error),
+	}
+
+	if keyspace != "" {
+		s.keyspaces[keyspace] = true
+	}
+
+	return s
+}
+
+// FailOn makes every statement containing match fail with err.
+// A nil err removes the failure registered for match.
+func (s *Session) FailOn(match string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err == nil {
+		delete(s.failures, match)
+		return
+	}
+
+	s.failures[match] = err
+}
+
+// Advance moves the clock of the session forward, expiring rows written with a TTL.
+func (s *Session) Advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.clock += d
+}
+
+// Statements returns every statement executed so far, including queries, in
+// execution order. Whitespace is collapsed to single spaces.
+func (s *Session) Statements() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.statements...)
+}
+
+// Tables returns the names of the tables of a keyspace, sorted.
+func (s *Session) Tables(keyspace string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var names []string
+
+	for _, t := range s.tables {
+		if t.keyspace == keyspace {
+			names = append(names, t.name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Rows returns the live rows of a table ordered by primary key.
+// Returns nil if the table does not exist.
+func (s *Session) Rows(keyspace, table string) []map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.tables[keyspace+"."+table]
+	if t == nil {
+		return nil
+	}
+
+	rows := make([]map[string]any, 0, len(t.rows))
+
+	for _, r := range t.sortedRows(s.now()) {
+		rows = append(rows, r.copyValues())
+	}
+
+	return rows
+}
+
+// Exec implements scyllamigrate.Session.
+func (s *Session) Exec(ctx context.Context, _ gocql.Consistency, stmt string, values ...any) error {
+	_, _, err := s.execute(ctx, stmt, values)
+	return err
+}
+
+// Iter implements scyllamigrate.Session.
+func (s *Session) Iter(ctx context.Context, _ gocql.Consistency, stmt string, values ...any) scyllamigrate.Iter {
+	res, _, err := s.execute(ctx, stmt, values)
+	if err != nil {
+		return &Iter{err: err}
+	}
+
+	return &Iter{rows: res.rows}
+}
+
+// ExecCAS implements scyllamigrate.Session. Statements without a condition are always applied.
+func (s *Session) ExecCAS(ctx context.Context, _ gocql.Consistency, stmt string, values ...any) (bool, map[string]any, error) {
+	res, existing, err := s.execute(ctx, stmt, values)
+	if err != nil {
+		return false, nil, err
+	}
+
+	if existing == nil {
+		existing = make(map[string]any)
+	}
+
+	return res.applied, existing, nil
+}
+
+// AwaitSchemaAgreement implements scyllamigrate.Session. The schema of the
+// session is always in agreement, so it only fails if ctx is done.
+func (s *Session) AwaitSchemaAgreement(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// now returns the current time of the session clock.
+func (s *Session) now() time.Time {
+	return time.Now().Add(s.clock)
+}
+
+// result is the outcome of a statement.
+type result struct {
+	rows    [][]any
+	applied bool
+}
+
+// execute records and runs a statement.
+// For conditional statements that were not applied it also returns the existing row.
+func (s *Session) execute(ctx context.Context, stmt string, values []any) (*result, map[string]any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	stmt = strings.TrimSuffix(strings.Join(strings.Fields(stmt), " "), ";")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.statements = append(s.statements, stmt)
+
+	for match, err := range s.failures {
+		if strings.Contains(stmt, match) {
+			return nil, nil, err
+		}
+	}
+
+	p, err := newParser(stmt, values)
+	if err != nil {
+		return nil, nil, fmt.Errorf("scyllamigratetest: %q: %w", stmt, err)
+	}
+
+	res, existing, err := s.run(p)
+	if err != nil {
+		return nil, nil, fmt.Errorf("scyllamigratetest: %q: %w", stmt, err)
+	}
+
+	return res, existing, nil
+}
+
+// run dispatches a statement on its leading keywords.
+func (s *Session) run(p *parser) (*result, map[string]any, error) {
+	applied := &result{applied: true}
+
+	switch {
+	case p.acceptKeywords("CREATE", "KEYSPACE"):
+		return applied, nil, s.createKeyspace(p)
+	case p.acceptKeywords("DROP", "KEYSPACE"):
+		return applied, nil, s.dropKeyspace(p)
+	case p.acceptKeywords("USE"):
+		return applied, nil, s.use(p)
+	case p.acceptKeywords("CREATE", "TABLE"), p.acceptKeywords("CREATE", "COLUMNFAMILY"):
+		return applied, nil, s.createTable(p)
+	case p.acceptKeywords("ALTER", "TABLE"):
+		return applied, nil, s.alterTable(p)
+	case p.acceptKeywords("DROP", "TABLE"):
+		return applied, nil, s.dropTable(p)
+	case p.acceptKeywords("TRUNCATE"):
+		return applied, nil, s.truncate(p)
+	case p.acceptKeywords("INSERT", "INTO"):
+		return s.insert(p)
+	case p.acceptKeywords("UPDATE"):
+		return s.update(p)
+	case p.acceptKeywords("DELETE"):
+		return s.delete(p)
+	case p.acceptKeywords("SELECT"):
+		res, err := s.query(p)
+		return res, nil, err
+	default:
+		return applied, nil, nil
+	}
+}
+
+// createKeyspace handles CREATE KEYSPACE. Replication settings are ignored.
+func (s *Session) createKeyspace(p *parser) error {
+	ifNotExists := p.acceptKeywords("IF", "NOT", "EXISTS")
+
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+
+	if s.keyspaces[name] && !ifNotExists {
+		return fmt.Errorf("keyspace %s already exists", name)
+	}
+
+	s.keyspaces[name] = true
+
+	return nil
+}
+
+// dropKeyspace handles DROP KEYSPACE and drops the tables of the keyspace.
+func (s *Session) dropKeyspace(p *parser) error {
+	ifExists := p.acceptKeywords("IF", "EXISTS")
+
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+
+	if !s.keyspaces[name] {
+		if ifExists {
+			return nil
+		}
+
+		return fmt.Errorf("keyspace %s does not exist", name)
+	}
+
+	delete(s.keyspaces, name)
+
+	for key, t := range s.tables {
+		if t.keyspace == name {
+			delete(s.tables, key)
+		}
+	}
+
+	return nil
+}
+
+// use handles USE.
+func (s *Session) use(p *parser) error {
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+
+	if !s.keyspaces[name] {
+		return fmt.Errorf("keyspace %s does not exist", name)
+	}
+
+	s.keyspace = name
+
+	return nil
+}
+
+// createTable handles CREATE TABLE. Table options after the column definitions are ignored.
+func (s *Session) createTable(p *parser) error {
+	ifNotExists := p.acceptKeywords("IF", "NOT", "EXISTS")
+
+	keyspace, name, err := s.tableName(p)
+	if err != nil {
+		return err
+	}
+
+	t := newTable(keyspace, name)
+
+	if err := p.expectPunct("("); err != nil {
+		return err
+	}
+
+	for {
+		if err := t.parseDefinition(p); err != nil {
+			return err
+		}
+
+		if !p.acceptPunct(",") {
+			break
+		}
+	}
+
+	if err := p.expectPunct(")"); err != nil {
+		return err
+	}
+
+	if len(t.primaryKey) == 0 {
+		return errors.New("no PRIMARY KEY specified")
+	}
+
+	for _, column := range t.primaryKey {
+		if _, ok := t.types[column]; !ok {
+			return fmt.Errorf("unknown primary key column %s", column)
+		}
+	}
+
+	if _, ok := s.tables[t.key()]; ok {
+		if ifNotExists {
+			return nil
+		}
+
+		return fmt.Errorf("table %s already exists", t.key())
+	}
+
+	s.tables[t.key()] = t
+
+	return nil
+}
+
+// alterTable handles ALTER TABLE ... ADD and ALTER TABLE ... DROP.
+// Other alterations are accepted and ignored.
+func (s *Session) alterTable(p *parser) error {
+	t, err := s.existingTable(p)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case p.acceptKeywords("ADD"):
+		parenthesized := p.acceptPunct("(")
+
+		for {
+			column, err := p.ident()
+			if err != nil {
+				return err
+			}
+
+			if _, ok := t.types[column]; ok {
+				return fmt.Errorf("column %s already exists in %s", column, t.key())
+			}
+
+			t.addColumn(column, p.skipNested())
+
+			if !parenthesized || !p.acceptPunct(",") {
+				break
+			}
+		}
+
+		if parenthesized {
+			return p.expectPunct(")")
+		}
+
+	case p.acceptKeywords("DROP"):
+		column, err := p.ident()
+		if err != nil {
+			return err
+		}
+
+		if err := t.dropColumn(column); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dropTable handles DROP TABLE.
+func (s *Session) dropTable(p *parser) error {
+	ifExists := p.acceptKeywords("IF", "EXISTS")
+
+	keyspace, name, err := s.tableName(p)
+	if err != nil {
+		return err
+	}
+
+	key := keyspace + "." + name
+
+	if _, ok := s.tables[key]; !ok {
+		if ifExists {
+			return nil
+		}
+
+		return fmt.Errorf("unconfigured table %s", key)
+	}
+
+	delete(s.tables, key)
+
+	return nil
+}
+
+// truncate handles TRUNCATE.
+func (s *Session) truncate(p *parser) error {
+	p.acceptKeywords("TABLE")
+
+	t, err := s.existingTable(p)
+	if err != nil {
+		return err
+	}
+
+	t.rows = make(map[string]*row)
+
+	return nil
+}
+
+// insert handles INSERT INTO ... VALUES with optional IF NOT EXISTS and USING TTL.
+func (s *Session) insert(p *parser) (*result, map[string]any, error) {
+	t, err := s.existingTable(p)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	columns, err := p.identList()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := p.expectKeywords("VALUES"); err != nil {
+		return nil, nil, err
+	}
+
+	if err := p.expectPunct("("); err != nil {
+		return nil, nil, err
+	}
+
+	values := make(map[string]any, len(columns))
+
+	for i, column := range columns {
+		if i > 0 {
+			if err := p.expectPunct(","); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		if values[column], err = p.term(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := p.expectPunct(")"); err != nil {
+		return nil, nil, err
+	}
+
+	ifNotExists := p.acceptKeywords("IF", "NOT", "EXISTS")
+
+	ttl, err := p.ttl()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.finish(p); err != nil {
+		return nil, nil, err
+	}
+
+	key, err := t.rowKey(values)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := t.checkColumns(values); err != nil {
+		return nil, nil, err
+	}
+
+	now := s.now()
+
+	if current := t.liveRow(key, now); current != nil && ifNotExists {
+		return &result{}, current.copyValues(), nil
+	}
+
+	t.write(key, values, ttl, now)
+
+	return &result{applied: true}, nil, nil
+}
+
+// update handles UPDATE ... SET ... WHERE with optional USING TTL and IF conditions.
+func (s *Session) update(p *parser) (*result, map[string]any, error) {
+	t, err := s.existingTable(p)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ttl, err := p.ttl()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := p.expectKeywords("SET"); err != nil {
+		return nil, nil, err
+	}
+
+	assignments, err := p.assignments()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := p.expectKeywords("WHERE"); err != nil {
+		return nil, nil, err
+	}
+
+	where, err := p.conditions()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ifExists, ifConds, err := p.ifClause()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.finish(p); err != nil {
+		return nil, nil, err
+	}
+
+	values := conditionValues(where)
+
+	key, err := t.rowKey(values)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for column, v := range assignments {
+		values[column] = v
+	}
+
+	if err := t.checkColumns(values); err != nil {
+		return nil, nil, err
+	}
+
+	now := s.now()
+	current := t.liveRow(key, now)
+
+	if applied, existing := checkIf(current, ifExists, ifConds); !applied {
+		return &result{}, existing, nil
+	}
+
+	t.write(key, values, ttl, now)
+
+	return &result{applied: true}, nil, nil
+}
+
+// delete handles DELETE FROM ... WHERE with optional IF conditions.
+// Deleting individual columns is not supported, the whole row is deleted.
+func (s *Session) delete(p *parser) (*result, map[string]any, error) {
+	if err := p.expectKeywords("FROM"); err != nil {
+		return nil, nil, err
+	}
+
+	t, err := s.existingTable(p)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := p.ttl(); err != nil {
+		return nil, nil, err
+	}
+
+	if err := p.expectKeywords("WHERE"); err != nil {
+		return nil, nil, err
+	}
+
+	where, err := p.conditions()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ifExists, ifConds, err := p.ifClause()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.finish(p); err != nil {
+		return nil, nil, err
+	}
+
+	key, err := t.rowKey(conditionValues(where))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	current := t.liveRow(key, s.now())
+
+	if applied, existing := checkIf(current, ifExists, ifConds); !applied {
+		return &result{}, existing, nil
+	}
+
+	delete(t.rows, key)
+
+	return &result{applied: true}, nil, nil
+}
+
+// query handles SELECT.
+func (s *Session) query(p *parser) (*result, error) {
+	selectors, err := p.selectors()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expectKeywords("FROM"); err != nil {
+		return nil, err
+	}
+
+	t, err := s.readableTable(p)
+	if err != nil {
+		return nil, err
+	}
+
+	var where []condition
+
+	if p.acceptKeywords("WHERE") {
+		if where, err = p.conditions(); err != nil {
+			return nil, err
+		}
+	}
+
+	limit := -1
+
+	if p.acceptKeywords("LIMIT") {
+		v, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+
+		n, ok := toInt64(v)
+		if !ok {
+			return nil, fmt.Errorf("invalid LIMIT %v", v)
+		}
+
+		limit = int(n)
+	}
+
+	p.acceptKeywords("ALLOW", "FILTERING")
+
+	if err := s.finish(p); err != nil {
+		return nil, err
+	}
+
+	for _, sel := range selectors {
+		if err := t.checkSelector(sel); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, cond := range where {
+		if _, ok := t.types[cond.column]; !ok {
+			return nil, fmt.Errorf("undefined column name %s", cond.column)
+		}
+	}
+
+	now := s.now()
+
+	var matched []*row
+
+	for _, r := range t.sortedRows(now) {
+		if r.matches(where) {
+			matched = append(matched, r)
+		}
+	}
+
+	if len(selectors) == 1 && selectors[0].kind == selectCount {
+		return &result{rows: [][]any{{int64(len(matched))}}, applied: true}, nil
+	}
+
+	if limit >= 0 && len(matched) > limit {
+		matched = matched[:limit]
+	}
+
+	rows := make([][]any, 0, len(matched))
+
+	for _, r := range matched {
+		rows = append(rows, r.project(t, selectors, now))
+	}
+
+	return &result{rows: rows, applied: true}, nil
+}
+
+// finish returns an error if the statement has unparsed tokens.
+func (s *Session) finish(p *parser) error {
+	if !p.done() {
+		return fmt.Errorf("unsupported syntax at %s", p.near())
+	}
+
+	return nil
+}
+
+// tableName reads a table name and qualifies it with the session keyspace if needed.
+func (s *Session) tableName(p *parser) (string, string, error) {
+	keyspace, name, err := p.tableName()
+	if err != nil {
+		return "", "", err
+	}
+
+	if keyspace == "" {
+		if s.keyspace == "" {
+			return "", "", errors.New("no keyspace has been specified")
+		}
+
+		keyspace = s.keyspace
+	}
+
+	if !s.keyspaces[keyspace] {
+		return "", "", fmt.Errorf("keyspace %s does not exist", keyspace)
+	}
+
+	return keyspace, name, nil
+}
+
+// existingTable reads a table name and returns the table.
+func (s *Session) existingTable(p *parser) (*table, error) {
+	keyspace, name, err := s.tableName(p)
+	if err != nil {
+		return nil, err
+	}
+
+	t := s.tables[keyspace+"."+name]
+	if t == nil {
+		return nil, fmt.Errorf("unconfigured table %s.%s", keyspace, name)
+	}
+
+	return t, nil
+}
+
+// readableTable reads a table name and returns the table, including the
+// system_schema tables describing the schema of the session.
+func (s *Session) readableTable(p *parser) (*table, error) {
+	if p.isKeyword(0, "system_schema") {
+		p.pos++
+
+		if err := p.expectPunct("."); err != nil {
+			return nil, err
+		}
+
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+
+		return s.systemSchemaTable(name)
+	}
+
+	return s.existingTable(p)
+}
+
+// systemSchemaTable builds a snapshot of a system_schema table.
+func (s *Session) systemSchemaTable(name string) (*table, error) {
+	t := newTable("system_schema", name)
+
+	switch name {
+	case "keyspaces":
+		t.addColumn("keyspace_name", "text")
+		t.primaryKey = []string{"keyspace_name"}
+
+		for keyspace := range s.keyspaces {
+			t.write(keyspace, map[string]any{"keyspace_name": keyspace}, 0, time.Time{})
+		}
+
+	case "tables":
+		t.addColumn("keyspace_name", "text")
+		t.addColumn("table_name", "text")
+		t.primaryKey = []string{"keyspace_name", "table_name"}
+
+		for key, st := range s.tables {
+			t.write(key, map[string]any{"keyspace_name": st.keyspace, "table_name": st.name}, 0, time.Time{})
+		}
+
+	case "columns":
+		t.addColumn("keyspace_name", "text")
+		t.addColumn("table_name", "text")
+		t.addColumn("column_name", "text")
+		t.addColumn("type", "text")
+		t.primaryKey = []string{"keyspace_name", "table_name", "column_name"}
+
+		for key, st := range s.tables {
+			for _, column := range st.columns {
+				t.write(key+"."+column, map[string]any{
+					"keyspace_name": st.keyspace,
+					"table_name":    st.name,
+					"column_name":   column,
+					"type":          st.types[column],
+				}, 0, time.Time{})
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("unconfigured table system_schema.%s", name)
+	}
+
+	return t, nil
+}
+
+// conditionValues returns the values of equality conditions by column.
+func conditionValues(conds []condition) map[string]any {
+	values := make(map[string]any, len(conds))
+
+	for _, cond := range conds {
+		values[cond.column] = cond.value
+	}
+
+	return values
+}
+
+// checkIf evaluates the IF clause of a statement against the current row.
+// If the statement is not applied, it also returns the existing row.
+func checkIf(current *row, ifExists bool, conds []condition) (bool, map[string]any) {
+	switch {
+	case ifExists:
+		return current != nil, nil
+	case len(conds) > 0:
+		if current == nil {
+			return false, nil
+		}
+
+		if !current.matches(conds) {
+			return false, current.copyValues()
+		}
+	}
+
+	return true, nil
+}
+
+// assignments reads the "column = value" assignments of an UPDATE.
+func (p *parser) assignments() (map[string]any, error) {
+	values := make(map[string]any)
+
+	for {
+		column, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expectPunct("="); err != nil {
+			return nil, err
+		}
+
+		if values[column], err = p.term(); err != nil {
+			return nil, err
+		}
+
+		if !p.acceptPunct(",") {
+			return values, nil
+		}
+	}
+}
+
+// ifClause reads an optional IF EXISTS or IF column = value clause.
+func (p *parser) ifClause() (bool, []condition, error) {
+	if !p.acceptKeywords("IF") {
+		return false, nil, nil
+	}
+
+	if p.acceptKeywords("EXISTS") {
+		return true, nil, nil
+	}
+
+	conds, err := p.conditions()
+
+	return false, conds, err
+}
+
+// Iter is the iterator returned by Session.Iter.
+// Values are converted to the destination types the way gocql does for
+// compatible types, e.g. an int64 count can be scanned into an int.
+type Iter struct {
+	rows [][]any
+	pos  int
+	err  error
+}
+
+// Scan implements scyllamigrate.Iter.
+func (it *Iter) Scan(dest ...any) bool {
+	if it.err != nil || it.pos >= len(it.rows) {
+		return false
+	}
+
+	row := it.rows[it.pos]
+	it.pos++
+
+	if len(dest) != len(row) {
+		it.err = fmt.Errorf("scyllamigratetest: scan of %d columns into %d destinations", len(row), len(dest))
+		return false
+	}
+
+	for i, d := range dest {
+		if err := assign(d, row[i]); err != nil {
+			it.err = err
+			return false
+		}
+	}
+
+	return true
+}
+
+// Close implements scyllamigrate.Iter.
+func (it *Iter) Close() error {
+	return it.err
+}
+
+// assign stores v in the variable dest points to.
+func assign(dest, v any) error {
+	d := reflect.ValueOf(dest)
+	if d.Kind() != reflect.Pointer || d.IsNil() {
+		return fmt.Errorf("scyllamigratetest: scan destination %T is not a non-nil pointer", dest)
+	}
+
+	e := d.Elem()
+
+	if v == nil {
+		e.SetZero()
+		return nil
+	}
+
+	sv := reflect.ValueOf(v)
+
+	switch {
+	case sv.Type().AssignableTo(e.Type()):
+		e.Set(sv)
+	case isNumber(sv.Kind()) && isNumber(e.Kind()), sv.Kind() == reflect.String && e.Kind() == reflect.String:
+		e.Set(sv.Convert(e.Type()))
+	default:
+		return fmt.Errorf("scyllamigratetest: can not scan %T into %T", v, dest)
+	}
+
+	return nil
+}
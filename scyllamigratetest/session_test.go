@@ -0,0 +1,270 @@
+package scyllamigratetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	td "github.com/maxatome/go-testdeep/td"
+)
+
+// newTestSession returns a session with a users table.
+func newTestSession(t *testing.T) *Session {
+	t.Helper()
+
+	s := NewSession("app")
+	td.Require(t).CmpNoError(s.Exec(context.Background(), 0, `
+		CREATE TABLE users (
+			id bigint,
+			name text,
+			tags set<text>,
+			PRIMARY KEY (id)
+		) WITH comment = 'users'`))
+
+	return s
+}
+
+func TestSession_Exec(t *testing.T) {
+	type tcase struct {
+		stmt        string
+		values      []any
+		expectError string
+		expectRows  []map[string]any
+	}
+
+	tests := map[string]tcase{
+		"insert literals": {
+			stmt:       "INSERT INTO users (id, name) VALUES (1, 'it''s me')",
+			expectRows: []map[string]any{{"id": int64(1), "name": "it's me"}},
+		},
+		"insert bind markers": {
+			stmt:       "INSERT INTO app.users (id, name) VALUES (?, ?);",
+			values:     []any{uint64(2), "bob"},
+			expectRows: []map[string]any{{"id": uint64(2), "name": "bob"}},
+		},
+		"update": {
+			stmt:       "UPDATE users SET name = ? WHERE id = ?",
+			values:     []any{"carol", 3},
+			expectRows: []map[string]any{{"id": 3, "name": "carol"}},
+		},
+		"unknown statements are ignored": {
+			stmt:       "CREATE INDEX users_name_idx ON users (name)",
+			expectRows: []map[string]any{},
+		},
+		"unknown column": {
+			stmt:        "INSERT INTO users (id, email) VALUES (1, 'a@b.c')",
+			expectError: "undefined column name email",
+		},
+		"missing primary key": {
+			stmt:        "INSERT INTO users (name) VALUES ('bob')",
+			expectError: "missing value of primary key column id",
+		},
+		"unknown table": {
+			stmt:        "INSERT INTO posts (id) VALUES (1)",
+			expectError: "unconfigured table app.posts",
+		},
+		"unknown keyspace": {
+			stmt:        "INSERT INTO other.users (id) VALUES (1)",
+			expectError: "keyspace other does not exist",
+		},
+		"missing bind value": {
+			stmt:        "INSERT INTO users (id, name) VALUES (?, ?)",
+			values:      []any{1},
+			expectError: "not enough values",
+		},
+		"table exists": {
+			stmt:        "CREATE TABLE users (id int PRIMARY KEY)",
+			expectError: "table app.users already exists",
+		},
+		"table without primary key": {
+			stmt:        "CREATE TABLE posts (id int)",
+			expectError: "no PRIMARY KEY specified",
+		},
+		"unsupported relation": {
+			stmt:        "DELETE FROM users WHERE id IN (1, 2)",
+			expectError: `expected "="`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := newTestSession(t)
+
+			err := s.Exec(context.Background(), 0, tc.stmt, tc.values...)
+			if tc.expectError != "" {
+				td.Cmp(t, err, td.ErrorIs(td.Contains(tc.expectError)))
+				return
+			}
+
+			td.CmpNoError(t, err)
+			td.Cmp(t, s.Rows("app", "users"), tc.expectRows)
+		})
+	}
+}
+
+func TestSession_Iter(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSession(t)
+
+	for _, id := range []int{3, 1, 2} {
+		td.Require(t).CmpNoError(s.Exec(ctx, 0, "INSERT INTO users (id, name) VALUES (?, ?)", id, "user"))
+	}
+
+	t.Run("rows in primary key order", func(t *testing.T) {
+		iter := s.Iter(ctx, 0, "SELECT id, name FROM users")
+
+		var (
+			ids  []uint64
+			id   uint64
+			name string
+		)
+
+		for iter.Scan(&id, &name) {
+			ids = append(ids, id)
+		}
+
+		td.CmpNoError(t, iter.Close())
+		td.Cmp(t, ids, []uint64{1, 2, 3})
+	})
+
+	t.Run("where and count", func(t *testing.T) {
+		var count int
+
+		iter := s.Iter(ctx, 0, "SELECT COUNT(*) FROM users WHERE name = ?", "user")
+		td.Cmp(t, iter.Scan(&count), true)
+		td.CmpNoError(t, iter.Close())
+		td.Cmp(t, count, 3)
+	})
+
+	t.Run("system schema", func(t *testing.T) {
+		var columns []string
+
+		iter := s.Iter(ctx, 0, "SELECT column_name FROM system_schema.columns WHERE keyspace_name = ? AND table_name = ?", "app", "users")
+
+		var column string
+
+		for iter.Scan(&column) {
+			columns = append(columns, column)
+		}
+
+		td.CmpNoError(t, iter.Close())
+		td.Cmp(t, columns, td.Bag("id", "name", "tags"))
+
+		var typ string
+
+		iter = s.Iter(ctx, 0, "SELECT type FROM system_schema.columns WHERE table_name = 'users' AND column_name = 'tags'")
+		td.Cmp(t, iter.Scan(&typ), true)
+		td.CmpNoError(t, iter.Close())
+		td.Cmp(t, typ, "set<text>")
+	})
+
+	t.Run("incompatible destination", func(t *testing.T) {
+		var id time.Time
+
+		iter := s.Iter(ctx, 0, "SELECT id FROM users")
+		td.Cmp(t, iter.Scan(&id), false)
+		td.Cmp(t, iter.Close(), td.ErrorIs(td.Contains("can not scan int into *time.Time")))
+	})
+
+	t.Run("unknown column", func(t *testing.T) {
+		iter := s.Iter(ctx, 0, "SELECT email FROM users")
+		td.Cmp(t, iter.Close(), td.ErrorIs(td.Contains("undefined column name email")))
+	})
+}
+
+func TestSession_ExecCAS(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSession(t)
+
+	applied, _, err := s.ExecCAS(ctx, 0, "INSERT INTO users (id, name) VALUES (1, 'alice') IF NOT EXISTS")
+	td.CmpNoError(t, err)
+	td.CmpTrue(t, applied)
+
+	applied, existing, err := s.ExecCAS(ctx, 0, "INSERT INTO users (id, name) VALUES (1, 'bob') IF NOT EXISTS")
+	td.CmpNoError(t, err)
+	td.CmpFalse(t, applied)
+	td.Cmp(t, existing, map[string]any{"id": int64(1), "name": "alice"})
+
+	applied, existing, err = s.ExecCAS(ctx, 0, "UPDATE users SET name = 'carol' WHERE id = 1 IF name = 'bob'")
+	td.CmpNoError(t, err)
+	td.CmpFalse(t, applied)
+	td.Cmp(t, existing["name"], "alice")
+
+	applied, _, err = s.ExecCAS(ctx, 0, "DELETE FROM users WHERE id = 1 IF name = 'alice'")
+	td.CmpNoError(t, err)
+	td.CmpTrue(t, applied)
+
+	applied, existing, err = s.ExecCAS(ctx, 0, "DELETE FROM users WHERE id = 1 IF EXISTS")
+	td.CmpNoError(t, err)
+	td.CmpFalse(t, applied)
+	td.CmpEmpty(t, existing)
+}
+
+func TestSession_TTL(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSession(t)
+
+	td.Require(t).CmpNoError(s.Exec(ctx, 0, "INSERT INTO users (id, name) VALUES (1, 'alice') USING TTL ?", 30))
+
+	s.Advance(10 * time.Second)
+
+	var ttl int
+
+	iter := s.Iter(ctx, 0, "SELECT TTL(name) FROM users WHERE id = 1")
+	td.Cmp(t, iter.Scan(&ttl), true)
+	td.CmpNoError(t, iter.Close())
+	td.Cmp(t, ttl, 20)
+
+	s.Advance(20 * time.Second)
+	td.CmpEmpty(t, s.Rows("app", "users"))
+}
+
+func TestSession_Schema(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSession(t)
+
+	td.CmpNoError(t, s.Exec(ctx, 0, "ALTER TABLE users ADD email text"))
+	td.Cmp(t, s.Exec(ctx, 0, "ALTER TABLE users ADD email text"), td.ErrorIs(td.Contains("already exists")))
+	td.CmpNoError(t, s.Exec(ctx, 0, "INSERT INTO users (id, email) VALUES (1, 'a@b.c')"))
+	td.CmpNoError(t, s.Exec(ctx, 0, "ALTER TABLE users DROP email"))
+	td.Cmp(t, s.Rows("app", "users"), []map[string]any{{"id": int64(1)}})
+
+	td.CmpNoError(t, s.Exec(ctx, 0, "CREATE TABLE IF NOT EXISTS users (id int PRIMARY KEY)"))
+	td.CmpNoError(t, s.Exec(ctx, 0, "CREATE KEYSPACE other WITH replication = {'class': 'SimpleStrategy', 'replication_factor': 1}"))
+	td.CmpNoError(t, s.Exec(ctx, 0, "CREATE TABLE other.events (day date, ts timestamp, data map<text, text>, PRIMARY KEY ((day), ts)) WITH CLUSTERING ORDER BY (ts DESC)"))
+	td.Cmp(t, s.Tables("other"), []string{"events"})
+
+	td.CmpNoError(t, s.Exec(ctx, 0, "TRUNCATE users"))
+	td.CmpEmpty(t, s.Rows("app", "users"))
+
+	td.CmpNoError(t, s.Exec(ctx, 0, "DROP TABLE users"))
+	td.CmpNoError(t, s.Exec(ctx, 0, "DROP TABLE IF EXISTS users"))
+	td.Cmp(t, s.Exec(ctx, 0, "DROP TABLE users"), td.ErrorIs(td.Contains("unconfigured table app.users")))
+	td.CmpEmpty(t, s.Tables("app"))
+
+	td.CmpNoError(t, s.Exec(ctx, 0, "DROP KEYSPACE other"))
+	td.CmpEmpty(t, s.Tables("other"))
+}
+
+func TestSession_FailOn(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSession(t)
+	failure := errors.New("write timeout")
+
+	s.FailOn("name = 'bob'", failure)
+
+	td.CmpErrorIs(t, s.Exec(ctx, 0, "UPDATE users SET name = 'bob' WHERE id = 1"), failure)
+	td.CmpNoError(t, s.Exec(ctx, 0, "UPDATE users SET name = 'alice' WHERE id = 1"))
+
+	s.FailOn("name = 'bob'", nil)
+	td.CmpNoError(t, s.Exec(ctx, 0, "UPDATE users SET name = 'bob' WHERE id = 1"))
+
+	td.Cmp(t, s.Statements(), td.Len(4))
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	td.CmpErrorIs(t, s.Exec(cancelled, 0, "TRUNCATE users"), context.Canceled)
+	td.CmpErrorIs(t, s.AwaitSchemaAgreement(cancelled), context.Canceled)
+}
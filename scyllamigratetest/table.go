@@ -0,0 +1,464 @@
+package scyllamigratetest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// table is an in-memory table. Rows are keyed by their primary key values.
+type table struct {
+	keyspace   string
+	name       string
+	columns    []string
+	types      map[string]string
+	primaryKey []string
+	rows       map[string]*row
+}
+
+// row is a table row. A zero expiresAt means the row does not expire.
+type row struct {
+	values    map[string]any
+	expiresAt time.Time
+}
+
+// newTable creates an empty table without columns.
+func newTable(keyspace, name string) *table {
+	return &table{
+		keyspace: keyspace,
+		name:     name,
+		types:    make(map[string]string),
+		rows:     make(map[string]*row),
+	}
+}
+
+// key returns the keyspace qualified name of the table.
+func (t *table) key() string {
+	return t.keyspace + "." + t.name
+}
+
+// addColumn adds a column of the given CQL type.
+func (t *table) addColumn(column, typ string) {
+	t.columns = append(t.columns, column)
+	t.types[column] = typ
+}
+
+// dropColumn removes a column that is not part of the primary key.
+func (t *table) dropColumn(column string) error {
+	if _, ok := t.types[column]; !ok {
+		return fmt.Errorf("column %s was not found in table %s", column, t.key())
+	}
+
+	for _, pk := range t.primaryKey {
+		if pk == column {
+			return fmt.Errorf("cannot drop primary key column %s", column)
+		}
+	}
+
+	delete(t.types, column)
+
+	for i, c := range t.columns {
+		if c == column {
+			t.columns = append(t.columns[:i], t.columns[i+1:]...)
+			break
+		}
+	}
+
+	for _, r := range t.rows {
+		delete(r.values, column)
+	}
+
+	return nil
+}
+
+// parseDefinition reads a column definition or a PRIMARY KEY clause of CREATE TABLE.
+func (t *table) parseDefinition(p *parser) error {
+	if p.acceptKeywords("PRIMARY", "KEY") {
+		return t.parsePrimaryKey(p)
+	}
+
+	column, err := p.ident()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := t.types[column]; ok {
+		return fmt.Errorf("multiple definition of identifier %s", column)
+	}
+
+	typ := p.skipNested()
+
+	if stripped, ok := strings.CutSuffix(typ, " primary key"); ok {
+		if len(t.primaryKey) > 0 {
+			return errors.New("multiple PRIMARY KEY definitions")
+		}
+
+		typ = stripped
+		t.primaryKey = []string{column}
+	}
+
+	typ = strings.TrimSuffix(typ, " static")
+
+	if typ == "" {
+		return fmt.Errorf("missing type of column %s", column)
+	}
+
+	t.addColumn(column, typ)
+
+	return nil
+}
+
+// parsePrimaryKey reads the column list of a PRIMARY KEY clause, e.g.
+// "(id)", "(id, ts)" or "((tenant, id), ts)".
+func (t *table) parsePrimaryKey(p *parser) error {
+	if len(t.primaryKey) > 0 {
+		return errors.New("multiple PRIMARY KEY definitions")
+	}
+
+	if err := p.expectPunct("("); err != nil {
+		return err
+	}
+
+	if p.isPunct("(") {
+		partition, err := p.identList()
+		if err != nil {
+			return err
+		}
+
+		t.primaryKey = partition
+	} else {
+		column, err := p.ident()
+		if err != nil {
+			return err
+		}
+
+		t.primaryKey = []string{column}
+	}
+
+	for p.acceptPunct(",") {
+		column, err := p.ident()
+		if err != nil {
+			return err
+		}
+
+		t.primaryKey = append(t.primaryKey, column)
+	}
+
+	return p.expectPunct(")")
+}
+
+// rowKey builds the key of the row identified by the primary key values.
+func (t *table) rowKey(values map[string]any) (string, error) {
+	parts := make([]string, 0, len(t.primaryKey))
+
+	for _, column := range t.primaryKey {
+		v, ok := values[column]
+		if !ok || v == nil {
+			return "", fmt.Errorf("missing value of primary key column %s", column)
+		}
+
+		parts = append(parts, canonical(v))
+	}
+
+	return strings.Join(parts, "\x00"), nil
+}
+
+// checkColumns returns an error if a value refers to an unknown column.
+func (t *table) checkColumns(values map[string]any) error {
+	for column := range values {
+		if _, ok := t.types[column]; !ok {
+			return fmt.Errorf("undefined column name %s", column)
+		}
+	}
+
+	return nil
+}
+
+// checkSelector returns an error if a selector refers to an unknown column.
+func (t *table) checkSelector(sel selector) error {
+	if sel.kind != selectColumn && sel.kind != selectTTL {
+		return nil
+	}
+
+	if _, ok := t.types[sel.column]; !ok {
+		return fmt.Errorf("undefined column name %s", sel.column)
+	}
+
+	return nil
+}
+
+// liveRow returns the row with the given key unless it does not exist or has expired.
+func (t *table) liveRow(key string, now time.Time) *row {
+	r := t.rows[key]
+	if r == nil {
+		return nil
+	}
+
+	if r.expired(now) {
+		delete(t.rows, key)
+		return nil
+	}
+
+	return r
+}
+
+// write inserts or updates a row. A positive ttl in seconds makes the row expire,
+// otherwise the row no longer expires.
+func (t *table) write(key string, values map[string]any, ttl int64, now time.Time) {
+	r := t.liveRow(key, now)
+	if r == nil {
+		r = &row{values: make(map[string]any, len(values))}
+		t.rows[key] = r
+	}
+
+	for column, v := range values {
+		r.values[column] = v
+	}
+
+	r.expiresAt = time.Time{}
+
+	if ttl > 0 {
+		r.expiresAt = now.Add(time.Duration(ttl) * time.Second)
+	}
+}
+
+// sortedRows returns the live rows ordered by primary key.
+func (t *table) sortedRows(now time.Time) []*row {
+	rows := make([]*row, 0, len(t.rows))
+
+	for key := range t.rows {
+		if r := t.liveRow(key, now); r != nil {
+			rows = append(rows, r)
+		}
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		for _, column := range t.primaryKey {
+			if c := compareValues(rows[i].values[column], rows[j].values[column]); c != 0 {
+				return c < 0
+			}
+		}
+
+		return false
+	})
+
+	return rows
+}
+
+// expired reports whether the row has expired at now.
+func (r *row) expired(now time.Time) bool {
+	return !r.expiresAt.IsZero() && !now.Before(r.expiresAt)
+}
+
+// copyValues returns a copy of the column values.
+func (r *row) copyValues() map[string]any {
+	values := make(map[string]any, len(r.values))
+
+	for column, v := range r.values {
+		values[column] = v
+	}
+
+	return values
+}
+
+// matches reports whether the row satisfies all equality conditions.
+func (r *row) matches(conds []condition) bool {
+	for _, cond := range conds {
+		v := r.values[cond.column]
+
+		if (v == nil) != (cond.value == nil) {
+			return false
+		}
+
+		if v != nil && canonical(v) != canonical(cond.value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// project returns the selected values of the row.
+func (r *row) project(t *table, selectors []selector, now time.Time) []any {
+	var values []any
+
+	for _, sel := range selectors {
+		switch sel.kind {
+		case selectAll:
+			for _, column := range t.columns {
+				values = append(values, r.values[column])
+			}
+
+		case selectTTL:
+			if r.expiresAt.IsZero() || r.values[sel.column] == nil {
+				values = append(values, nil)
+				continue
+			}
+
+			values = append(values, int(math.Ceil(r.expiresAt.Sub(now).Seconds())))
+
+		default:
+			values = append(values, r.values[sel.column])
+		}
+	}
+
+	return values
+}
+
+// selectorKind is the kind of a SELECT selector.
+type selectorKind int
+
+const (
+	selectColumn selectorKind = iota
+	selectAll
+	selectCount
+	selectTTL
+)
+
+// selector is a single item of a SELECT clause.
+type selector struct {
+	kind   selectorKind
+	column string
+}
+
+// selectors reads the selectors of a SELECT clause.
+func (p *parser) selectors() ([]selector, error) {
+	if p.acceptPunct("*") {
+		return []selector{{kind: selectAll}}, nil
+	}
+
+	var selectors []selector
+
+	for {
+		sel, err := p.selector()
+		if err != nil {
+			return nil, err
+		}
+
+		selectors = append(selectors, sel)
+
+		if !p.acceptPunct(",") {
+			break
+		}
+	}
+
+	for _, sel := range selectors {
+		if sel.kind == selectCount && len(selectors) > 1 {
+			return nil, errors.New("COUNT(*) can not be combined with other selectors")
+		}
+	}
+
+	return selectors, nil
+}
+
+// selector reads a column name, COUNT(*) or TTL(column).
+func (p *parser) selector() (selector, error) {
+	name, err := p.ident()
+	if err != nil {
+		return selector{}, err
+	}
+
+	if !p.acceptPunct("(") {
+		return selector{kind: selectColumn, column: name}, nil
+	}
+
+	switch name {
+	case "count":
+		if !p.acceptPunct("*") {
+			if _, err := p.term(); err != nil {
+				return selector{}, err
+			}
+		}
+
+		return selector{kind: selectCount}, p.expectPunct(")")
+
+	case "ttl":
+		column, err := p.ident()
+		if err != nil {
+			return selector{}, err
+		}
+
+		return selector{kind: selectTTL, column: column}, p.expectPunct(")")
+
+	default:
+		return selector{}, fmt.Errorf("unsupported function %s", name)
+	}
+}
+
+// canonical returns a representation of v that is equal for equal values,
+// regardless of the Go integer type holding them.
+func canonical(v any) string {
+	rv := reflect.ValueOf(v)
+
+	switch {
+	case isInteger(rv.Kind()):
+		return "i" + toBig(rv).String()
+	case rv.Kind() == reflect.String:
+		return "s" + rv.String()
+	}
+
+	if t, ok := v.(time.Time); ok {
+		return "t" + strconv.FormatInt(t.UnixNano(), 10)
+	}
+
+	return fmt.Sprintf("%T:%v", v, v)
+}
+
+// compareValues orders two values of a primary key column.
+func compareValues(a, b any) int {
+	ra, rb := reflect.ValueOf(a), reflect.ValueOf(b)
+
+	switch {
+	case isInteger(ra.Kind()) && isInteger(rb.Kind()):
+		return toBig(ra).Cmp(toBig(rb))
+	case ra.Kind() == reflect.String && rb.Kind() == reflect.String:
+		return strings.Compare(ra.String(), rb.String())
+	}
+
+	if ta, ok := a.(time.Time); ok {
+		if tb, ok := b.(time.Time); ok {
+			return ta.Compare(tb)
+		}
+	}
+
+	return strings.Compare(canonical(a), canonical(b))
+}
+
+// toBig converts an integer value to a big.Int.
+func toBig(v reflect.Value) *big.Int {
+	if v.CanInt() {
+		return big.NewInt(v.Int())
+	}
+
+	return new(big.Int).SetUint64(v.Uint())
+}
+
+// toInt64 converts an integer value to int64.
+func toInt64(v any) (int64, bool) {
+	rv := reflect.ValueOf(v)
+
+	switch {
+	case rv.CanInt():
+		return rv.Int(), true
+	case rv.CanUint() && rv.Uint() <= math.MaxInt64:
+		return int64(rv.Uint()), true
+	default:
+		return 0, false
+	}
+}
+
+// isInteger reports whether k is an integer kind.
+func isInteger(k reflect.Kind) bool {
+	return (k >= reflect.Int && k <= reflect.Int64) || (k >= reflect.Uint && k <= reflect.Uintptr)
+}
+
+// isNumber reports whether k is an integer or floating point kind.
+func isNumber(k reflect.Kind) bool {
+	return isInteger(k) || k == reflect.Float32 || k == reflect.Float64
+}
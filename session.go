@@ -0,0 +1,115 @@
+package scyllamigrate
+
+import (
+	"context"
+
+	"github.com/gocql/gocql"
+)
+
+// Session is the database session the Migrator talks to.
+// NewGocqlSession adapts a *gocql.Session, and the scyllamigratetest package
+// provides an in-memory implementation for unit tests.
+type Session interface {
+	// Exec executes a statement that returns no rows.
+	Exec(ctx context.Context, consistency gocql.Consistency, stmt string, values ...any) error
+
+	// Iter executes a query and returns an iterator over the rows it returns.
+	Iter(ctx context.Context, consistency gocql.Consistency, stmt string, values ...any) Iter
+
+	// ExecCAS executes a conditional statement (lightweight transaction) with serial
+	// consistency. If the statement was not applied, existing holds the columns of
+	// the row that prevented it.
+	ExecCAS(ctx context.Context, consistency gocql.Consistency, stmt string, values ...any) (applied bool, existing map[string]any, err error)
+
+	// AwaitSchemaAgreement waits until all nodes agree on the schema version.
+	AwaitSchemaAgreement(ctx context.Context) error
+}
+
+// Iter iterates over the rows returned by a query.
+type Iter interface {
+	// Scan copies the columns of the next row into dest.
+	// Returns false when there are no more rows or an error occurred.
+	Scan(dest ...any) bool
+
+	// Close releases the iterator and returns the error that stopped the iteration, if any.
+	Close() error
+}
+
+// gocqlSession adapts a *gocql.Session to the Session interface.
+type gocqlSession struct {
+	session *gocql.Session
+}
+
+// NewGocqlSession adapts a gocql session to the Session interface.
+func NewGocqlSession(session *gocql.Session) Session {
+	return &gocqlSession{session: session}
+}
+
+// Exec implements Session.
+func (s *gocqlSession) Exec(ctx context.Context, consistency gocql.Consistency, stmt string, values ...any) error {
+	return s.session.Query(stmt, values...).WithContext(ctx).Consistency(consistency).Exec()
+}
+
+// Iter implements Session.
+func (s *gocqlSession) Iter(ctx context.Context, consistency gocql.Consistency, stmt string, values ...any) Iter {
+	return s.session.Query(stmt, values...).WithContext(ctx).Consistency(consistency).Iter()
+}
+
+// ExecCAS implements Session.
+func (s *gocqlSession) ExecCAS(ctx context.Context, consistency gocql.Consistency, stmt string, values ...any) (bool, map[string]any, error) {
+	existing := make(map[string]any)
+
+	applied, err := s.session.Query(stmt, values...).
+		WithContext(ctx).
+		Consistency(consistency).
+		SerialConsistency(gocql.Serial).
+		MapScanCAS(existing)
+
+	return applied, existing, err
+}
+
+// AwaitSchemaAgreement implements Session.
+func (s *gocqlSession) AwaitSchemaAgreement(ctx context.Context) error {
+	return s.session.AwaitSchemaAgreement(ctx)
+}
+
+// gocqlSessionOf returns the gocql session backing session, or nil if it is not
+// backed by one.
+func gocqlSessionOf(session Session) *gocql.Session {
+	if s, ok := session.(*gocqlSession); ok {
+		return s.session
+	}
+
+	return nil
+}
+
+// exec executes a statement with the configured consistency.
+func (m *Migrator) exec(ctx context.Context, stmt string, values ...any) error {
+	return m.session.Exec(ctx, m.consistency, stmt, values...)
+}
+
+// iter executes a query with the configured consistency.
+func (m *Migrator) iter(ctx context.Context, stmt string, values ...any) Iter {
+	return m.session.Iter(ctx, m.consistency, stmt, values...)
+}
+
+// execCAS executes a conditional statement with the configured consistency.
+func (m *Migrator) execCAS(ctx context.Context, stmt string, values ...any) (bool, map[string]any, error) {
+	return m.session.ExecCAS(ctx, m.consistency, stmt, values...)
+}
+
+// scanRow copies the columns of the first row of iter into dest and closes it.
+// Returns gocql.ErrNotFound if there are no rows.
+func scanRow(iter Iter, dest ...any) error {
+	found := iter.Scan(dest...)
+
+	if err := iter.Close(); err != nil {
+		return err
+	}
+
+	if !found {
+		return gocql.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,311 @@
+package scyllamigrate_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/gocql/gocql"
+	td "github.com/maxatome/go-testdeep/td"
+
+	"github.com/heartwilltell/scyllamigrate"
+	"github.com/heartwilltell/scyllamigrate/scyllamigratetest"
+)
+
+// testMigrations returns two migrations creating the users and posts tables.
+func testMigrations() fstest.MapFS {
+	return fstest.MapFS{
+		"000001_create_users.up.cql": {Data: []byte(`
+CREATE TABLE users (id uuid PRIMARY KEY, email text);
+CREATE INDEX users_email_idx ON users (email);
+`)},
+		"000001_create_users.down.cql": {Data: []byte(`
+-- scyllamigrate:allow-destructive
+DROP TABLE users;
+`)},
+		"000002_create_posts.up.cql": {Data: []byte(`
+CREATE TABLE posts (user_id uuid, id timeuuid, title text, PRIMARY KEY ((user_id), id));
+`)},
+		"000002_create_posts.down.cql": {Data: []byte(`
+-- scyllamigrate:allow-destructive
+DROP TABLE posts;
+`)},
+	}
+}
+
+// newTestMigrator creates a Migrator backed by an in-memory session.
+func newTestMigrator(t *testing.T, session *scyllamigratetest.Session, opts ...scyllamigrate.Option) *scyllamigrate.Migrator {
+	t.Helper()
+
+	opts = append([]scyllamigrate.Option{
+		scyllamigrate.WithFS(testMigrations()),
+		scyllamigrate.WithKeyspace("app"),
+	}, opts...)
+
+	m, err := scyllamigrate.NewWithSession(session, opts...)
+	td.Require(t).CmpNoError(err)
+
+	return m
+}
+
+func TestNewWithSession(t *testing.T) {
+	_, err := scyllamigrate.NewWithSession(nil, scyllamigrate.WithFS(testMigrations()), scyllamigrate.WithKeyspace("app"))
+	td.CmpErrorIs(t, err, scyllamigrate.ErrNoSession)
+
+	_, err = scyllamigrate.New(nil, scyllamigrate.WithFS(testMigrations()), scyllamigrate.WithKeyspace("app"))
+	td.CmpErrorIs(t, err, scyllamigrate.ErrNoSession)
+}
+
+func TestMigrator_UpDownWithSession(t *testing.T) {
+	ctx := context.Background()
+	session := scyllamigratetest.NewSession("app")
+	m := newTestMigrator(t, session)
+
+	result, err := m.Up(ctx)
+	td.Require(t).CmpNoError(err)
+	td.Cmp(t, result.Versions(), []uint64{1, 2})
+	td.Cmp(t, session.Tables("app"), td.SuperBagOf("users", "posts", "schema_migrations", "schema_migrations_dirty"))
+
+	status, err := m.Status(ctx)
+	td.Require(t).CmpNoError(err)
+	td.Cmp(t, status.CurrentVersion, uint64(2))
+	td.CmpEmpty(t, status.Pending)
+	td.CmpNil(t, status.Dirty)
+	td.Cmp(t, status.Applied, td.Len(2))
+	td.Cmp(t, status.Applied[0], td.Struct(&scyllamigrate.AppliedMigration{
+		Version:     1,
+		Description: "create_users",
+	}, td.StructFields{"Checksum": td.NotEmpty()}))
+
+	// The lock is released after the run.
+	lock, err := m.LockStatus(ctx)
+	td.CmpNoError(t, err)
+	td.CmpNil(t, lock)
+
+	result, err = m.Up(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, result.Count(), 0)
+
+	result, err = m.Down(ctx)
+	td.Require(t).CmpNoError(err)
+	td.Cmp(t, result.Versions(), []uint64{2})
+	td.Cmp(t, session.Tables("app"), td.Not(td.Contains("posts")))
+
+	version, err := m.Version(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, version, uint64(1))
+
+	result, err = m.DownTo(ctx, 0)
+	td.Require(t).CmpNoError(err)
+	td.Cmp(t, result.Versions(), []uint64{1})
+	td.Cmp(t, session.Rows("app", "schema_migrations"), td.Empty())
+}
+
+func TestMigrator_DirtyWithSession(t *testing.T) {
+	ctx := context.Background()
+	session := scyllamigratetest.NewSession("app")
+	m := newTestMigrator(t, session)
+
+	failure := errors.New("server timeout")
+	session.FailOn("CREATE INDEX users_email_idx", failure)
+
+	result, err := m.Up(ctx)
+	td.CmpErrorIs(t, err, failure)
+	td.Cmp(t, result.Count(), 0)
+
+	var me *scyllamigrate.MigrationError
+	td.Require(t).True(errors.As(err, &me))
+	td.Cmp(t, me.Version, uint64(1))
+	td.Cmp(t, me.Statement, 2)
+
+	status, err := m.Status(ctx)
+	td.Require(t).CmpNoError(err)
+	td.Cmp(t, status.Dirty, td.Struct(&scyllamigrate.DirtyState{
+		Version:   1,
+		Direction: scyllamigrate.Up,
+		Statement: 2,
+	}))
+
+	// The next run resumes at the failed statement instead of creating users again.
+	session.FailOn("CREATE INDEX users_email_idx", nil)
+
+	result, err = m.Up(ctx)
+	td.Require(t).CmpNoError(err)
+	td.Cmp(t, result.Versions(), []uint64{1, 2})
+
+	status, err = m.Status(ctx)
+	td.Require(t).CmpNoError(err)
+	td.CmpNil(t, status.Dirty)
+}
+
+func TestMigrator_LockWithSession(t *testing.T) {
+	ctx := context.Background()
+	session := scyllamigratetest.NewSession("app")
+	m := newTestMigrator(t, session, scyllamigrate.WithLockTimeout(0))
+
+	td.Require(t).CmpNoError(session.Exec(ctx, 0,
+		`CREATE TABLE app.schema_migrations_lock (lock_id text PRIMARY KEY, holder text, host text, pid int, acquired_at timestamp)`))
+	td.Require(t).CmpNoError(session.Exec(ctx, 0,
+		`INSERT INTO app.schema_migrations_lock (lock_id, holder, host, pid) VALUES ('migrations', 'other', 'elsewhere', 42) USING TTL 60`))
+
+	_, err := m.Up(ctx)
+	td.CmpErrorIs(t, err, scyllamigrate.ErrLocked)
+
+	lock, err := m.LockStatus(ctx)
+	td.Require(t).CmpNoError(err)
+	td.Cmp(t, lock.Holder, "other")
+	td.Cmp(t, lock.PID, 42)
+
+	// The lease expires if the holder stops renewing it.
+	session.Advance(61 * time.Second)
+
+	_, err = m.Up(ctx)
+	td.CmpNoError(t, err)
+}
+
+func TestMigrator_GotoOutOfOrderWithSession(t *testing.T) {
+	migrations := func(versions ...string) fstest.MapFS {
+		fsys := fstest.MapFS{}
+
+		for _, name := range versions {
+			fsys[name+".up.cql"] = &fstest.MapFile{Data: []byte("CREATE TABLE " + name[7:] + " (id int PRIMARY KEY);")}
+			fsys[name+".down.cql"] = &fstest.MapFile{Data: []byte("-- scyllamigrate:allow-destructive\nDROP TABLE " + name[7:] + ";")}
+		}
+
+		return fsys
+	}
+
+	for name, policy := range map[string]scyllamigrate.OutOfOrderPolicy{
+		"allow":  scyllamigrate.OutOfOrderAllow,
+		"strict": scyllamigrate.OutOfOrderStrict,
+		"ignore": scyllamigrate.OutOfOrderIgnore,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			session := scyllamigratetest.NewSession("app")
+
+			m := newTestMigrator(t, session, scyllamigrate.WithFS(migrations("000001_a", "000007_c")))
+			_, err := m.Up(ctx)
+			td.Require(t).CmpNoError(err)
+
+			// Version 5 was merged after 1 and 7 were applied. Going to 5 rolls
+			// back 7 first, so 5 is in order afterwards.
+			m = newTestMigrator(t, session,
+				scyllamigrate.WithFS(migrations("000001_a", "000005_b", "000007_c")),
+				scyllamigrate.WithOutOfOrderPolicy(policy),
+			)
+
+			result, err := m.Goto(ctx, 5)
+			td.Require(t).CmpNoError(err)
+			td.Cmp(t, result.Versions(), []uint64{7, 5})
+			td.CmpEmpty(t, result.Skipped)
+
+			version, err := m.Version(ctx)
+			td.CmpNoError(t, err)
+			td.Cmp(t, version, uint64(5))
+			td.Cmp(t, session.Tables("app"), td.All(td.SuperBagOf("a", "b"), td.Not(td.Contains("c"))))
+		})
+	}
+}
+
+func TestMigrator_BaselineDestructiveWithSession(t *testing.T) {
+	ctx := context.Background()
+	session := scyllamigratetest.NewSession("app")
+
+	// Baseline executes no statement, so destructive statements need no confirmation.
+	m := newTestMigrator(t, session, scyllamigrate.WithFS(fstest.MapFS{
+		"000001_a.up.cql": {Data: []byte("CREATE TABLE a (id int PRIMARY KEY, legacy text);")},
+		"000002_b.up.cql": {Data: []byte("ALTER TABLE a DROP legacy;\nDROP TABLE old;")},
+	}))
+
+	baselined, err := m.Baseline(ctx, 2)
+	td.Require(t).CmpNoError(err)
+	td.Cmp(t, baselined, 2)
+
+	version, err := m.Version(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, version, uint64(2))
+	td.Cmp(t, session.Tables("app"), td.Not(td.Contains("a")))
+}
+
+func TestMigrator_GoMigrationWithSession(t *testing.T) {
+	ctx := context.Background()
+	session := scyllamigratetest.NewSession("app")
+
+	backfill := &scyllamigrate.GoMigration{
+		Version:     3,
+		Description: "backfill_users",
+		Checksum:    "v1",
+		UpSession: func(ctx context.Context, s scyllamigrate.Session) error {
+			return s.Exec(ctx, gocql.Quorum, "INSERT INTO users (id, email) VALUES (?, ?)", 1, "a@example.com")
+		},
+	}
+
+	m := newTestMigrator(t, session, scyllamigrate.WithGoMigrations(backfill))
+
+	result, err := m.Up(ctx)
+	td.Require(t).CmpNoError(err)
+	td.Cmp(t, result.Versions(), []uint64{1, 2, 3})
+	td.Cmp(t, session.Rows("app", "users"), []map[string]any{{"id": 1, "email": "a@example.com"}})
+}
+
+func TestMigrator_GoMigrationWithoutGocqlSession(t *testing.T) {
+	// Go migrations using the gocql session cannot run on other Sessions.
+	_, err := scyllamigrate.NewWithSession(scyllamigratetest.NewSession("app"),
+		scyllamigrate.WithFS(testMigrations()),
+		scyllamigrate.WithKeyspace("app"),
+		scyllamigrate.WithGoMigrations(&scyllamigrate.GoMigration{
+			Version:   3,
+			UpSession: func(context.Context, scyllamigrate.Session) error { return nil },
+			Down:      func(context.Context, *gocql.Session) error { return nil },
+		}),
+	)
+	td.CmpErrorIs(t, err, scyllamigrate.ErrNoGocqlSession)
+}
+
+func TestMigrator_AfterMigrationHookError(t *testing.T) {
+	ctx := context.Background()
+	session := scyllamigratetest.NewSession("app")
+	failed := errors.New("audit log unavailable")
+
+	var fail bool
+
+	m := newTestMigrator(t, session, scyllamigrate.WithHooks(&scyllamigrate.Hooks{
+		AfterMigration: func(context.Context, scyllamigrate.MigrationEvent) error {
+			if fail {
+				return failed
+			}
+
+			return nil
+		},
+	}))
+
+	// The migration is applied and recorded before the hook fails, so it is reported.
+	fail = true
+
+	result, err := m.Steps(ctx, 1)
+	td.CmpErrorIs(t, err, failed)
+	td.Cmp(t, result.Versions(), []uint64{1})
+	td.Cmp(t, result.Count(), 1)
+
+	version, err := m.Version(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, version, uint64(1))
+
+	fail = false
+
+	_, err = m.Up(ctx)
+	td.Require(t).CmpNoError(err)
+
+	fail = true
+
+	result, err = m.Down(ctx)
+	td.CmpErrorIs(t, err, failed)
+	td.Cmp(t, result.Versions(), []uint64{2})
+
+	version, err = m.Version(ctx)
+	td.CmpNoError(t, err)
+	td.Cmp(t, version, uint64(1))
+}
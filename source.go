@@ -1,10 +1,13 @@
 package scyllamigrate
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
+	"path"
+	"path/filepath"
 	"sort"
 )
 
@@ -24,8 +27,10 @@ type Source interface {
 }
 
 // FSSource implements Source using fs.FS (supports go:embed).
+// Migrations are found in the root and in nested directories, e.g. "2024/" and "2025/".
 type FSSource struct {
 	fsys       fs.FS
+	location   func(name string) string // path of a file for messages, nil for fs.FS.
 	migrations map[uint64]*MigrationPair
 	versions   []uint64 // sorted.
 }
@@ -33,8 +38,21 @@ type FSSource struct {
 // NewFSSource creates a Source from an fs.FS instance.
 // This supports embedded migrations via go:embed.
 func NewFSSource(fsys fs.FS) (*FSSource, error) {
+	return newFSSource(fsys, nil)
+}
+
+// NewDirSource creates a Source from a filesystem directory path.
+func NewDirSource(path string) (*FSSource, error) {
+	return newFSSource(os.DirFS(path), func(name string) string {
+		return filepath.Join(path, filepath.FromSlash(name))
+	})
+}
+
+// newFSSource creates a Source from fsys whose files are displayed by location.
+func newFSSource(fsys fs.FS, location func(name string) string) (*FSSource, error) {
 	s := &FSSource{
 		fsys:       fsys,
+		location:   location,
 		migrations: make(map[uint64]*MigrationPair),
 	}
 
@@ -45,33 +63,26 @@ func NewFSSource(fsys fs.FS) (*FSSource, error) {
 	return s, nil
 }
 
-// NewDirSource creates a Source from a filesystem directory path.
-func NewDirSource(path string) (*FSSource, error) {
-	return NewFSSource(os.DirFS(path))
-}
-
-// scan reads the fs.FS and indexes all migration files.
+// scan walks the fs.FS and indexes all migration files.
+// The files of a version must be in the same directory and a version must have
+// at most one file per direction, otherwise a *ConflictError is returned.
 func (s *FSSource) scan() error {
-	entries, err := fs.ReadDir(s.fsys, ".")
-	if err != nil {
-		return &SourceError{Op: "scan", Err: err}
-	}
-
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
+	err := fs.WalkDir(s.fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return &SourceError{Op: "scan", Err: err}
 		}
 
-		name := entry.Name()
-		if !IsMigrationFile(name) {
-			continue
+		if entry.IsDir() || !IsMigrationFile(entry.Name()) {
+			return nil
 		}
 
-		m, err := ParseMigration(name)
+		m, err := ParseMigration(entry.Name())
 		if err != nil {
 			return err
 		}
 
+		m.Raw = name
+
 		pair, ok := s.migrations[m.Version]
 		if !ok {
 			pair = &MigrationPair{
@@ -82,10 +93,22 @@ func (s *FSSource) scan() error {
 			s.versions = append(s.versions, m.Version)
 		}
 
+		if other := pair.file(); other != "" && path.Dir(other) != path.Dir(name) {
+			return &ConflictError{Version: m.Version, Files: [2]string{s.filePath(other), s.filePath(name)}}
+		}
+
 		switch m.Direction {
 		case Up:
+			if pair.Up != nil {
+				return &ConflictError{Version: m.Version, Files: [2]string{s.filePath(pair.Up.Raw), s.filePath(name)}}
+			}
+
 			pair.Up = m
 		case Down:
+			if pair.Down != nil {
+				return &ConflictError{Version: m.Version, Files: [2]string{s.filePath(pair.Down.Raw), s.filePath(name)}}
+			}
+
 			pair.Down = m
 		default:
 			return &SourceError{
@@ -94,6 +117,11 @@ func (s *FSSource) scan() error {
 				Err:     fmt.Errorf("unknown migration direction: %s", m.Direction),
 			}
 		}
+
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	// Sort versions in ascending order.
@@ -104,6 +132,17 @@ func (s *FSSource) scan() error {
 	return nil
 }
 
+// filePath returns the path of a file of the source for messages,
+// including the directory of a source created by NewDirSource
+// and the location of a remote source.
+func (s *FSSource) filePath(name string) string {
+	if s.location == nil {
+		return name
+	}
+
+	return s.location(name)
+}
+
 // List returns all available migration pairs sorted by version.
 func (s *FSSource) List() ([]*MigrationPair, error) {
 	pairs := make([]*MigrationPair, 0, len(s.versions))
@@ -171,3 +210,146 @@ func (s *FSSource) Versions() []uint64 {
 
 	return result
 }
+
+// CompositeSource merges the migrations of several sources, e.g. migrations
+// shared by several services and the migrations of one service.
+// Versions must be unique across the sources.
+type CompositeSource struct {
+	sources []Source
+	owners  map[uint64]Source
+	pairs   []*MigrationPair // sorted.
+}
+
+// NewCompositeSource creates a Source merging the migrations of sources.
+// A version defined by more than one source makes it fail with a *ConflictError
+// naming a file of each source. Closing the composite source closes all sources.
+func NewCompositeSource(sources ...Source) (*CompositeSource, error) {
+	c := &CompositeSource{
+		sources: sources,
+		owners:  make(map[uint64]Source),
+	}
+
+	files := make(map[uint64]string)
+
+	for _, source := range sources {
+		if source == nil {
+			return nil, ErrNoSource
+		}
+
+		pairs, err := source.List()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pair := range pairs {
+			pair = qualifyPair(source, pair)
+
+			if _, ok := c.owners[pair.Version]; ok {
+				return nil, &ConflictError{Version: pair.Version, Files: [2]string{files[pair.Version], pair.file()}}
+			}
+
+			c.owners[pair.Version] = source
+			c.pairs = append(c.pairs, pair)
+			files[pair.Version] = pair.file()
+		}
+	}
+
+	sort.Slice(c.pairs, func(i, j int) bool {
+		return c.pairs[i].Version < c.pairs[j].Version
+	})
+
+	return c, nil
+}
+
+// List returns the migration pairs of all sources sorted by version.
+// The file names of sources created by NewDirSource include their directory.
+func (c *CompositeSource) List() ([]*MigrationPair, error) {
+	pairs := make([]*MigrationPair, len(c.pairs))
+	copy(pairs, c.pairs)
+
+	return pairs, nil
+}
+
+// ReadUp returns the content of the up migration for the given version.
+func (c *CompositeSource) ReadUp(version uint64) (io.ReadCloser, error) {
+	source, ok := c.owners[version]
+	if !ok {
+		return nil, &SourceError{Version: version, Op: "read up", Err: ErrVersionNotFound}
+	}
+
+	return source.ReadUp(version)
+}
+
+// ReadDown returns the content of the down migration for the given version.
+func (c *CompositeSource) ReadDown(version uint64) (io.ReadCloser, error) {
+	source, ok := c.owners[version]
+	if !ok {
+		return nil, &SourceError{Version: version, Op: "read down", Err: ErrVersionNotFound}
+	}
+
+	return source.ReadDown(version)
+}
+
+// Files returns the files of the sources implementing FileSource.
+func (c *CompositeSource) Files() ([]string, error) {
+	var files []string
+
+	for _, source := range c.sources {
+		fsrc, ok := source.(FileSource)
+		if !ok {
+			continue
+		}
+
+		names, err := fsrc.Files()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range names {
+			files = append(files, sourceFilePath(source, name))
+		}
+	}
+
+	return files, nil
+}
+
+// Close closes all sources.
+func (c *CompositeSource) Close() error {
+	var errs []error
+
+	for _, source := range c.sources {
+		if err := source.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// sourceFilePath returns the path of a file of source for messages.
+func sourceFilePath(source Source, name string) string {
+	if s, ok := source.(interface{ filePath(name string) string }); ok {
+		return s.filePath(name)
+	}
+
+	return name
+}
+
+// qualifyPair returns a copy of pair whose file names are the paths returned by sourceFilePath.
+func qualifyPair(source Source, pair *MigrationPair) *MigrationPair {
+	qualified := *pair
+
+	if pair.Up != nil {
+		up := *pair.Up
+		up.Raw = sourceFilePath(source, up.Raw)
+		qualified.Up = &up
+	}
+
+	if pair.Down != nil {
+		down := *pair.Down
+		down.Raw = sourceFilePath(source, down.Raw)
+		qualified.Down = &down
+	}
+
+	return &qualified
+}
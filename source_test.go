@@ -1,8 +1,11 @@
 package scyllamigrate
 
 import (
+	"errors"
 	"io"
 	"io/fs"
+	"os"
+	"path/filepath"
 	"testing"
 	"testing/fstest"
 
@@ -381,3 +384,154 @@ func TestFSSource_scanWithDirectories(t *testing.T) {
 
 	td.Cmp(t, len(pairs), 1)
 }
+
+func TestFSSource_scanNested(t *testing.T) {
+	fsys := fstest.MapFS{
+		"000001_init.up.cql":               {Data: []byte("CREATE TABLE init;")},
+		"2024/000002_users.up.cql":         {Data: []byte("CREATE TABLE users;")},
+		"2024/000002_users.down.cql":       {Data: []byte("DROP TABLE users;")},
+		"2025/billing/000003_bills.up.cql": {Data: []byte("CREATE TABLE bills;")},
+		"2025/README.md":                   {Data: []byte("# 2025")},
+	}
+
+	source, err := NewFSSource(fsys)
+	td.Require(t).CmpNoError(err)
+
+	td.Cmp(t, source.Versions(), []uint64{1, 2, 3})
+
+	pair, ok := source.Get(3)
+	td.Require(t).True(ok)
+	td.Cmp(t, pair.Up.Raw, "2025/billing/000003_bills.up.cql")
+	td.Cmp(t, pair.Description, "bills")
+
+	rc, err := source.ReadDown(2)
+	td.Require(t).CmpNoError(err)
+	defer rc.Close()
+
+	content, err := io.ReadAll(rc)
+	td.CmpNoError(t, err)
+	td.Cmp(t, string(content), "DROP TABLE users;")
+}
+
+func TestFSSource_scanConflict(t *testing.T) {
+	fsys := fstest.MapFS{
+		"2024/000001_users.up.cql":   {Data: []byte("CREATE TABLE users;")},
+		"2025/000001_users.down.cql": {Data: []byte("DROP TABLE users;")},
+	}
+
+	_, err := NewFSSource(fsys)
+
+	var ce *ConflictError
+	td.Require(t).True(errors.As(err, &ce))
+	td.Cmp(t, ce.Version, uint64(1))
+	td.Cmp(t, ce.Files, [2]string{"2024/000001_users.up.cql", "2025/000001_users.down.cql"})
+	td.CmpErrorIs(t, err, ErrDuplicateVersion)
+
+	t.Run("directory source", func(t *testing.T) {
+		dir := t.TempDir()
+
+		for name, file := range fsys {
+			path := filepath.Join(dir, filepath.FromSlash(name))
+			td.Require(t).CmpNoError(os.MkdirAll(filepath.Dir(path), 0o755))
+			td.Require(t).CmpNoError(os.WriteFile(path, file.Data, 0o644))
+		}
+
+		_, err := NewDirSource(dir)
+		td.Require(t).True(errors.As(err, &ce))
+		td.Cmp(t, ce.Files, [2]string{
+			filepath.Join(dir, "2024", "000001_users.up.cql"),
+			filepath.Join(dir, "2025", "000001_users.down.cql"),
+		})
+	})
+
+	t.Run("same direction", func(t *testing.T) {
+		_, err := NewFSSource(fstest.MapFS{
+			"000001_users.up.cql": {Data: []byte("CREATE TABLE users;")},
+			"000001_posts.up.cql": {Data: []byte("CREATE TABLE posts;")},
+		})
+		td.Require(t).True(errors.As(err, &ce))
+		td.Cmp(t, ce.Files, [2]string{"000001_posts.up.cql", "000001_users.up.cql"})
+	})
+}
+
+// closeRecorder is a Source that records whether it was closed.
+type closeRecorder struct {
+	Source
+	closed bool
+	err    error
+}
+
+func (s *closeRecorder) Close() error {
+	s.closed = true
+	return s.err
+}
+
+func TestCompositeSource(t *testing.T) {
+	shared, err := NewFSSource(fstest.MapFS{
+		"000001_init.up.cql":   {Data: []byte("CREATE TABLE init;")},
+		"000001_init.down.cql": {Data: []byte("DROP TABLE init;")},
+		"000003_audit.up.cql":  {Data: []byte("CREATE TABLE audit;")},
+	})
+	td.Require(t).CmpNoError(err)
+
+	dir := t.TempDir()
+	td.Require(t).CmpNoError(os.WriteFile(filepath.Join(dir, "000002_orders.up.cql"), []byte("CREATE TABLE orders;"), 0o644))
+
+	service, err := NewDirSource(dir)
+	td.Require(t).CmpNoError(err)
+
+	// The recorder hides that shared implements FileSource.
+	recorder := &closeRecorder{Source: shared}
+
+	source, err := NewCompositeSource(recorder, service)
+	td.Require(t).CmpNoError(err)
+
+	pairs, err := source.List()
+	td.Require(t).CmpNoError(err)
+	td.Cmp(t, pairs, td.Len(3))
+	td.Cmp(t, []uint64{pairs[0].Version, pairs[1].Version, pairs[2].Version}, []uint64{1, 2, 3})
+
+	// Files of directory sources are named with their directory.
+	td.Cmp(t, pairs[0].Up.Raw, "000001_init.up.cql")
+	td.Cmp(t, pairs[1].Up.Raw, filepath.Join(dir, "000002_orders.up.cql"))
+
+	rc, err := source.ReadUp(2)
+	td.Require(t).CmpNoError(err)
+
+	content, err := io.ReadAll(rc)
+	td.CmpNoError(t, err)
+	td.CmpNoError(t, rc.Close())
+	td.Cmp(t, string(content), "CREATE TABLE orders;")
+
+	_, err = source.ReadDown(3)
+	td.CmpErrorIs(t, err, ErrMissingDown)
+
+	_, err = source.ReadUp(4)
+	td.CmpErrorIs(t, err, ErrVersionNotFound)
+
+	files, err := source.Files()
+	td.CmpNoError(t, err)
+	td.Cmp(t, files, []string{filepath.Join(dir, "000002_orders.up.cql")})
+
+	report, err := Validate(source)
+	td.Require(t).CmpNoError(err)
+	td.Cmp(t, report.HasErrors(), false)
+
+	recorder.err = errors.New("close failed")
+	td.CmpErrorIs(t, source.Close(), recorder.err)
+	td.CmpTrue(t, recorder.closed)
+
+	t.Run("conflict", func(t *testing.T) {
+		_, err := NewCompositeSource(shared, service, shared)
+
+		var ce *ConflictError
+		td.Require(t).True(errors.As(err, &ce))
+		td.Cmp(t, ce.Version, uint64(1))
+		td.Cmp(t, ce.Files, [2]string{"000001_init.up.cql", "000001_init.up.cql"})
+	})
+
+	t.Run("nil source", func(t *testing.T) {
+		_, err := NewCompositeSource(shared, nil)
+		td.CmpErrorIs(t, err, ErrNoSource)
+	})
+}
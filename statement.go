@@ -0,0 +1,252 @@
+package scyllamigrate
+
+import (
+	"strings"
+)
+
+// Statement is a single CQL statement parsed from migration content.
+type Statement struct {
+	// Text is the statement text without comments and the trailing semicolon.
+	Text string
+
+	// Line is the 1-based line number where the statement starts.
+	Line int
+}
+
+// SplitStatements splits migration content into individual CQL statements.
+//
+// Statements are separated by semicolons. The splitter understands:
+//   - '...' string literals and "..." quoted identifiers (with doubled quote escapes)
+//   - $$...$$ quoted UDF and UDA bodies
+//   - -- and // line comments, including trailing comments after a statement
+//   - /* ... */ block comments
+//   - BEGIN [UNLOGGED | COUNTER] BATCH ... APPLY BATCH blocks, which are kept as a single statement
+//
+// Comments are removed from the statement text. A *SyntaxError is returned for
+// unterminated literals, comments and batches.
+func SplitStatements(content string) ([]Statement, error) {
+	s := &splitter{src: content, line: 1}
+
+	if err := s.split(); err != nil {
+		return nil, err
+	}
+
+	if s.statements == nil {
+		return []Statement{}, nil
+	}
+
+	return s.statements, nil
+}
+
+// splitter holds the state of SplitStatements.
+type splitter struct {
+	src  string
+	pos  int
+	line int
+
+	statements []Statement
+	current    strings.Builder
+	startLine  int // line of the first token of the current statement, 0 if none yet.
+
+	words     int    // number of words in the current statement.
+	lastWord  string // last upper-cased word of the current statement.
+	inBatch   bool   // the current statement is a BEGIN BATCH block.
+	batchDone bool   // APPLY BATCH has been seen in the current batch.
+	batchLine int    // line where the current batch starts.
+}
+
+func (s *splitter) split() error {
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+
+		switch {
+		case c == '\n':
+			s.current.WriteByte(c)
+			s.line++
+			s.pos++
+
+		case s.hasPrefix("--"), s.hasPrefix("//"):
+			s.skipLineComment()
+
+		case s.hasPrefix("/*"):
+			if err := s.skipBlockComment(); err != nil {
+				return err
+			}
+
+		case c == '\'', c == '"':
+			if err := s.readQuoted(c); err != nil {
+				return err
+			}
+
+		case s.hasPrefix("$$"):
+			if err := s.readDollarQuoted(); err != nil {
+				return err
+			}
+
+		case c == ';':
+			s.pos++
+
+			if s.inBatch && !s.batchDone {
+				s.current.WriteByte(c)
+				continue
+			}
+
+			s.flush()
+
+		case isWordByte(c):
+			s.readWord()
+
+		default:
+			if !isSpaceByte(c) {
+				s.begin()
+			}
+
+			s.current.WriteByte(c)
+			s.pos++
+		}
+	}
+
+	if s.inBatch && !s.batchDone {
+		return &SyntaxError{Line: s.batchLine, Msg: "unterminated batch (missing APPLY BATCH)"}
+	}
+
+	s.flush()
+
+	return nil
+}
+
+// begin records the start line of the current statement on its first token.
+func (s *splitter) begin() {
+	if s.startLine == 0 {
+		s.startLine = s.line
+	}
+}
+
+// flush appends the current statement, if any, and resets the statement state.
+func (s *splitter) flush() {
+	if text := strings.TrimSpace(s.current.String()); text != "" {
+		s.statements = append(s.statements, Statement{Text: text, Line: s.startLine})
+	}
+
+	s.current.Reset()
+	s.startLine = 0
+	s.words = 0
+	s.lastWord = ""
+	s.inBatch = false
+	s.batchDone = false
+	s.batchLine = 0
+}
+
+func (s *splitter) hasPrefix(prefix string) bool {
+	return strings.HasPrefix(s.src[s.pos:], prefix)
+}
+
+// skipLineComment skips a comment up to, but not including, the end of the line.
+func (s *splitter) skipLineComment() {
+	end := strings.IndexByte(s.src[s.pos:], '\n')
+	if end < 0 {
+		s.pos = len(s.src)
+		return
+	}
+
+	s.pos += end
+}
+
+// skipBlockComment skips a /* ... */ comment and replaces it with a single space.
+func (s *splitter) skipBlockComment() error {
+	end := strings.Index(s.src[s.pos+2:], "*/")
+	if end < 0 {
+		return &SyntaxError{Line: s.line, Msg: "unterminated block comment"}
+	}
+
+	comment := s.src[s.pos : s.pos+2+end+2]
+	s.line += strings.Count(comment, "\n")
+	s.pos += len(comment)
+
+	s.current.WriteByte(' ')
+
+	return nil
+}
+
+// readQuoted copies a quoted string literal or identifier. A doubled quote
+// character inside the literal is an escaped quote.
+func (s *splitter) readQuoted(quote byte) error {
+	s.begin()
+
+	startLine := s.line
+
+	for i := s.pos + 1; i < len(s.src); i++ {
+		switch s.src[i] {
+		case '\n':
+			s.line++
+		case quote:
+			if i+1 < len(s.src) && s.src[i+1] == quote {
+				i++
+				continue
+			}
+
+			s.current.WriteString(s.src[s.pos : i+1])
+			s.pos = i + 1
+
+			return nil
+		}
+	}
+
+	if quote == '"' {
+		return &SyntaxError{Line: startLine, Msg: "unterminated quoted identifier"}
+	}
+
+	return &SyntaxError{Line: startLine, Msg: "unterminated string literal"}
+}
+
+// readDollarQuoted copies a $$...$$ quoted string.
+func (s *splitter) readDollarQuoted() error {
+	s.begin()
+
+	end := strings.Index(s.src[s.pos+2:], "$$")
+	if end < 0 {
+		return &SyntaxError{Line: s.line, Msg: "unterminated $$ quoted string"}
+	}
+
+	literal := s.src[s.pos : s.pos+2+end+2]
+	s.line += strings.Count(literal, "\n")
+	s.pos += len(literal)
+
+	s.current.WriteString(literal)
+
+	return nil
+}
+
+// readWord copies an identifier or keyword and tracks BEGIN BATCH ... APPLY BATCH blocks.
+func (s *splitter) readWord() {
+	s.begin()
+
+	end := s.pos
+	for end < len(s.src) && isWordByte(s.src[end]) {
+		end++
+	}
+
+	word := strings.ToUpper(s.src[s.pos:end])
+	s.current.WriteString(s.src[s.pos:end])
+	s.pos = end
+
+	if s.words == 0 && word == "BEGIN" {
+		s.inBatch = true
+		s.batchLine = s.line
+	}
+
+	if s.inBatch && s.lastWord == "APPLY" && word == "BATCH" {
+		s.batchDone = true
+	}
+
+	s.words++
+	s.lastWord = word
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == '\v'
+}
@@ -0,0 +1,226 @@
+package scyllamigrate
+
+import (
+	"errors"
+	"testing"
+
+	td "github.com/maxatome/go-testdeep/td"
+)
+
+// statementTexts returns the text of every statement.
+func statementTexts(statements []Statement) []string {
+	texts := make([]string, 0, len(statements))
+	for _, stmt := range statements {
+		texts = append(texts, stmt.Text)
+	}
+
+	return texts
+}
+
+func TestSplitStatements(t *testing.T) {
+	type tcase struct {
+		content  string
+		expected []string
+	}
+	tests := map[string]tcase{
+		"single statement": {
+			content:  "CREATE TABLE users (id UUID PRIMARY KEY);",
+			expected: []string{"CREATE TABLE users (id UUID PRIMARY KEY)"},
+		},
+		"multiple statements": {
+			content:  "CREATE TABLE users (id UUID PRIMARY KEY);\nCREATE INDEX idx ON users (id);",
+			expected: []string{"CREATE TABLE users (id UUID PRIMARY KEY)", "CREATE INDEX idx ON users (id)"},
+		},
+		"statements with comments": {
+			content:  "-- This is a comment\nCREATE TABLE users;\n-- Another comment\nCREATE INDEX idx;",
+			expected: []string{"CREATE TABLE users", "CREATE INDEX idx"},
+		},
+		"statements with empty lines": {
+			content:  "CREATE TABLE users;\n\nCREATE INDEX idx;\n",
+			expected: []string{"CREATE TABLE users", "CREATE INDEX idx"},
+		},
+		"statement without semicolon": {
+			content:  "CREATE TABLE users",
+			expected: []string{"CREATE TABLE users"},
+		},
+		"multiple statements, last without semicolon": {
+			content:  "CREATE TABLE users;\nCREATE INDEX idx",
+			expected: []string{"CREATE TABLE users", "CREATE INDEX idx"},
+		},
+		"empty content": {
+			content:  "",
+			expected: []string{},
+		},
+		"only comments": {
+			content:  "-- Comment 1\n-- Comment 2",
+			expected: []string{},
+		},
+		"only empty lines": {
+			content:  "\n\n\n",
+			expected: []string{},
+		},
+		"statement with trailing whitespace": {
+			content:  "CREATE TABLE users;  \n",
+			expected: []string{"CREATE TABLE users"},
+		},
+		"multi-line statement": {
+			content:  "CREATE TABLE users (\n    id UUID PRIMARY KEY,\n    name TEXT\n);",
+			expected: []string{"CREATE TABLE users (\n    id UUID PRIMARY KEY,\n    name TEXT\n)"},
+		},
+		"comment at end of line": {
+			content:  "CREATE TABLE users; -- inline comment",
+			expected: []string{"CREATE TABLE users"},
+		},
+		"trailing comment before semicolon": {
+			content:  "CREATE TABLE users -- inline comment\n;",
+			expected: []string{"CREATE TABLE users"},
+		},
+		"statement with semicolon in string": {
+			content:  "INSERT INTO users (name) VALUES ('test;value');",
+			expected: []string{"INSERT INTO users (name) VALUES ('test;value')"},
+		},
+		"escaped quote in string": {
+			content:  "INSERT INTO users (name) VALUES ('it''s; fine');SELECT 1;",
+			expected: []string{"INSERT INTO users (name) VALUES ('it''s; fine')", "SELECT 1"},
+		},
+		"comment markers in string": {
+			content:  "INSERT INTO urls (u) VALUES ('http://example.com/*x*/--y');",
+			expected: []string{"INSERT INTO urls (u) VALUES ('http://example.com/*x*/--y')"},
+		},
+		"quoted identifier": {
+			content:  `CREATE TABLE "Weird;Name" (id int PRIMARY KEY);`,
+			expected: []string{`CREATE TABLE "Weird;Name" (id int PRIMARY KEY)`},
+		},
+		"double slash comment": {
+			content:  "// leading comment\nCREATE TABLE users; // trailing comment\nCREATE INDEX idx;",
+			expected: []string{"CREATE TABLE users", "CREATE INDEX idx"},
+		},
+		"block comment": {
+			content:  "/* header;\n   comment */\nCREATE TABLE users;",
+			expected: []string{"CREATE TABLE users"},
+		},
+		"block comment separates tokens": {
+			content:  "CREATE/* x */TABLE users;",
+			expected: []string{"CREATE TABLE users"},
+		},
+		"dollar quoted function body": {
+			content: "CREATE FUNCTION f(a int) RETURNS NULL ON NULL INPUT RETURNS int LANGUAGE lua AS $$ return a; $$;\n" +
+				"CREATE TABLE users;",
+			expected: []string{
+				"CREATE FUNCTION f(a int) RETURNS NULL ON NULL INPUT RETURNS int LANGUAGE lua AS $$ return a; $$",
+				"CREATE TABLE users",
+			},
+		},
+		"batch": {
+			content: "BEGIN BATCH\n  INSERT INTO a (id) VALUES (1);\n  INSERT INTO b (id) VALUES (2);\nAPPLY BATCH;\nCREATE TABLE c;",
+			expected: []string{
+				"BEGIN BATCH\n  INSERT INTO a (id) VALUES (1);\n  INSERT INTO b (id) VALUES (2);\nAPPLY BATCH",
+				"CREATE TABLE c",
+			},
+		},
+		"unlogged batch lowercase": {
+			content:  "begin unlogged batch insert into a (id) values (1); apply batch",
+			expected: []string{"begin unlogged batch insert into a (id) values (1); apply batch"},
+		},
+		"semicolon only": {
+			content:  ";",
+			expected: []string{},
+		},
+		"multiple semicolons on same line": {
+			content:  "CREATE TABLE a;;CREATE TABLE b;",
+			expected: []string{"CREATE TABLE a", "CREATE TABLE b"},
+		},
+		"whitespace only": {
+			content:  "   \n\t  ",
+			expected: []string{},
+		},
+		"statement with only whitespace": {
+			content:  "   ;",
+			expected: []string{},
+		},
+		"mixed content": {
+			content:  "-- Comment\nCREATE TABLE a;\n\n-- Another\nCREATE TABLE b;\nDROP TABLE c",
+			expected: []string{"CREATE TABLE a", "CREATE TABLE b", "DROP TABLE c"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := SplitStatements(tt.content)
+			td.CmpNoError(t, err)
+			td.Cmp(t, statementTexts(got), tt.expected)
+		})
+	}
+}
+
+func TestSplitStatements_LineNumbers(t *testing.T) {
+	content := `-- Create the users table
+CREATE TABLE users (
+    id UUID PRIMARY KEY
+);
+
+/* The index
+   is optional */
+CREATE INDEX idx ON users (id); INSERT INTO users (id) VALUES (uuid());
+
+INSERT INTO notes (body) VALUES ('multi
+line');
+DROP TABLE x`
+
+	got, err := SplitStatements(content)
+	td.CmpNoError(t, err)
+
+	lines := make([]int, 0, len(got))
+	for _, stmt := range got {
+		lines = append(lines, stmt.Line)
+	}
+
+	td.Cmp(t, lines, []int{2, 8, 8, 10, 12})
+}
+
+func TestSplitStatements_Errors(t *testing.T) {
+	type tcase struct {
+		content string
+		line    int
+		msg     string
+	}
+	tests := map[string]tcase{
+		"unterminated string": {
+			content: "CREATE TABLE a;\nINSERT INTO a (s) VALUES ('oops);",
+			line:    2,
+			msg:     "unterminated string literal",
+		},
+		"unterminated quoted identifier": {
+			content: `CREATE TABLE "oops (id int PRIMARY KEY);`,
+			line:    1,
+			msg:     "unterminated quoted identifier",
+		},
+		"unterminated block comment": {
+			content: "CREATE TABLE a;\n\n/* never closed",
+			line:    3,
+			msg:     "unterminated block comment",
+		},
+		"unterminated dollar quote": {
+			content: "CREATE FUNCTION f() AS $$ return 1;",
+			line:    1,
+			msg:     "unterminated $$ quoted string",
+		},
+		"unterminated batch": {
+			content: "CREATE TABLE a;\nBEGIN BATCH\nINSERT INTO a (id) VALUES (1);",
+			line:    2,
+			msg:     "unterminated batch (missing APPLY BATCH)",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := SplitStatements(tt.content)
+			td.CmpError(t, err)
+
+			var syntaxErr *SyntaxError
+			td.Cmp(t, errors.As(err, &syntaxErr), true)
+			td.Cmp(t, syntaxErr.Line, tt.line)
+			td.Cmp(t, syntaxErr.Msg, tt.msg)
+		})
+	}
+}
@@ -0,0 +1,445 @@
+package scyllamigrate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Severity is the severity of a validation issue.
+type Severity string
+
+const (
+	// SeverityError marks an issue that makes the migrations unusable or ambiguous.
+	SeverityError Severity = "error"
+
+	// SeverityWarning marks an issue that is likely a mistake but does not prevent migrating.
+	SeverityWarning Severity = "warning"
+)
+
+// IssueKind identifies the check that reported a validation issue.
+type IssueKind string
+
+const (
+	// IssueInvalidFilename reports a file that looks like a migration but whose name cannot be parsed.
+	IssueInvalidFilename IssueKind = "invalid_filename"
+
+	// IssueDuplicateVersion reports a version used by several migrations, by files
+	// in different directories or by several files of the same direction.
+	IssueDuplicateVersion IssueKind = "duplicate_version"
+
+	// IssueMissingUp reports a down migration without an up migration.
+	IssueMissingUp IssueKind = "missing_up"
+
+	// IssueMissingDown reports an up migration without a down migration.
+	IssueMissingDown IssueKind = "missing_down"
+
+	// IssueMixedExtensions reports a version whose files use both .cql and .sql.
+	IssueMixedExtensions IssueKind = "mixed_extensions"
+
+	// IssueEmptyMigration reports a migration file without statements.
+	IssueEmptyMigration IssueKind = "empty_migration"
+
+	// IssueSyntaxError reports a migration file that cannot be split into statements.
+	IssueSyntaxError IssueKind = "syntax_error"
+
+	// IssueVersionGap reports missing versions between two sequential versions.
+	IssueVersionGap IssueKind = "version_gap"
+)
+
+// Issue is a problem found by Validate.
+type Issue struct {
+	// Kind identifies the check that reported the issue.
+	Kind IssueKind `json:"kind"`
+
+	// Severity is the severity of the issue.
+	Severity Severity `json:"severity"`
+
+	// Version is the migration version the issue refers to (0 if none).
+	Version uint64 `json:"version,omitempty"`
+
+	// File is the migration file the issue refers to (empty if none).
+	File string `json:"file,omitempty"`
+
+	// Line is the 1-based line in File the issue refers to (0 if none).
+	Line int `json:"line,omitempty"`
+
+	// Message describes the issue.
+	Message string `json:"message"`
+}
+
+// String returns the issue as "file:line: severity: message (kind)".
+func (i Issue) String() string {
+	var b strings.Builder
+
+	switch {
+	case i.File != "" && i.Line > 0:
+		fmt.Fprintf(&b, "%s:%d: ", i.File, i.Line)
+	case i.File != "":
+		fmt.Fprintf(&b, "%s: ", i.File)
+	case i.Version > 0:
+		fmt.Fprintf(&b, "version %d: ", i.Version)
+	}
+
+	fmt.Fprintf(&b, "%s: %s (%s)", i.Severity, i.Message, i.Kind)
+
+	return b.String()
+}
+
+// ValidationReport holds the issues found by Validate.
+type ValidationReport struct {
+	// Issues are sorted by version and file.
+	Issues []Issue `json:"issues"`
+}
+
+// HasErrors reports whether any issue has SeverityError.
+func (r *ValidationReport) HasErrors() bool {
+	return r.count(SeverityError) > 0
+}
+
+// Errors returns the number of issues with SeverityError.
+func (r *ValidationReport) Errors() int { return r.count(SeverityError) }
+
+// Warnings returns the number of issues with SeverityWarning.
+func (r *ValidationReport) Warnings() int { return r.count(SeverityWarning) }
+
+func (r *ValidationReport) count(severity Severity) int {
+	n := 0
+
+	for _, issue := range r.Issues {
+		if issue.Severity == severity {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (r *ValidationReport) add(issue Issue) {
+	r.Issues = append(r.Issues, issue)
+}
+
+// FileSource is implemented by sources backed by migration files.
+// Validate uses it to check the files that List merges or skips,
+// such as duplicate versions and unparsable filenames.
+type FileSource interface {
+	Source
+
+	// Files returns the names of all files of the source.
+	Files() ([]string, error)
+}
+
+// Files returns the paths of all files of the file system, including nested directories.
+func (s *FSSource) Files() ([]string, error) {
+	var files []string
+
+	err := fs.WalkDir(s.fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() {
+			files = append(files, name)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, &SourceError{Op: "files", Err: err}
+	}
+
+	return files, nil
+}
+
+// migrationLikeRegex matches filenames that are probably meant to be migrations,
+// e.g. a version prefix, a direction suffix or a CQL extension.
+var migrationLikeRegex = regexp.MustCompile(`(?i)^\d+_|\.(up|down)(\.[^.]*)?$|\.(cql|sql)$`)
+
+// Validate checks the migrations of source without connecting to a database.
+// It reports:
+//   - files that look like migrations but whose names cannot be parsed
+//   - versions used by migrations with different descriptions or directories or by several files of one direction
+//   - up migrations without a down migration and vice versa
+//   - versions mixing .cql and .sql files
+//   - migration files without statements or with statements that cannot be split
+//   - gaps between sequential versions
+//
+// The filename checks require source to implement FileSource.
+// The returned error is only non-nil if the source cannot be read.
+func Validate(source Source) (*ValidationReport, error) {
+	if source == nil {
+		return nil, ErrNoSource
+	}
+
+	report := &ValidationReport{Issues: []Issue{}}
+
+	if fsrc, ok := source.(FileSource); ok {
+		files, err := fsrc.Files()
+		if err != nil {
+			return nil, err
+		}
+
+		validateFiles(report, files)
+	}
+
+	if err := validateSource(report, source); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// ValidateFS checks the migration files of fsys like Validate without creating
+// a source first, so that the files NewFSSource refuses, such as a version
+// defined twice, are reported as issues instead of failing.
+// If there are such files, the checks of the migrations themselves are skipped.
+func ValidateFS(fsys fs.FS) (*ValidationReport, error) {
+	report := &ValidationReport{Issues: []Issue{}}
+
+	files, err := (&FSSource{fsys: fsys}).Files()
+	if err != nil {
+		return nil, err
+	}
+
+	validateFiles(report, files)
+
+	source, err := NewFSSource(fsys)
+	if err != nil {
+		// The files the source cannot be created from are reported by validateFiles.
+		if report.HasErrors() {
+			sortIssues(report)
+			return report, nil
+		}
+
+		return nil, err
+	}
+
+	if err := validateSource(report, source); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// validateSource reports missing, empty and unparsable migrations and version gaps,
+// then sorts the issues of report.
+func validateSource(report *ValidationReport, source Source) error {
+	pairs, err := source.List()
+	if err != nil {
+		return err
+	}
+
+	for _, pair := range pairs {
+		if pair.Up == nil {
+			report.add(Issue{
+				Kind:     IssueMissingUp,
+				Severity: SeverityError,
+				Version:  pair.Version,
+				File:     pair.Down.Raw,
+				Message:  "down migration has no up migration",
+			})
+		} else if err := validateContent(report, pair.Version, pair.Up.Raw, source.ReadUp); err != nil {
+			return err
+		}
+
+		if pair.Down == nil {
+			report.add(Issue{
+				Kind:     IssueMissingDown,
+				Severity: SeverityWarning,
+				Version:  pair.Version,
+				File:     pair.Up.Raw,
+				Message:  "up migration has no down migration",
+			})
+		} else if err := validateContent(report, pair.Version, pair.Down.Raw, source.ReadDown); err != nil {
+			return err
+		}
+	}
+
+	validateGaps(report, pairs)
+	sortIssues(report)
+
+	return nil
+}
+
+// sortIssues sorts the issues of report by version and file.
+func sortIssues(report *ValidationReport) {
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		a, b := report.Issues[i], report.Issues[j]
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+
+		return a.File < b.File
+	})
+}
+
+// validateFiles reports unparsable filenames, duplicate versions and mixed extensions.
+func validateFiles(report *ValidationReport, files []string) {
+	type versionFiles struct {
+		descriptions map[string]bool
+		dirs         map[string]bool
+		directions   map[Direction][]string
+		extensions   map[string]bool
+		files        []string
+	}
+
+	byVersion := make(map[uint64]*versionFiles)
+
+	var versions []uint64
+
+	for _, name := range files {
+		base := filepath.Base(name)
+
+		if !IsMigrationFile(base) {
+			if migrationLikeRegex.MatchString(base) {
+				report.add(Issue{
+					Kind:     IssueInvalidFilename,
+					Severity: SeverityError,
+					File:     name,
+					Message:  "filename does not match {version}_{description}.{up|down}.{cql|sql}",
+				})
+			}
+
+			continue
+		}
+
+		m, err := ParseMigration(base)
+		if err != nil {
+			report.add(Issue{
+				Kind:     IssueInvalidFilename,
+				Severity: SeverityError,
+				File:     name,
+				Message:  err.Error(),
+			})
+
+			continue
+		}
+
+		vf, ok := byVersion[m.Version]
+		if !ok {
+			vf = &versionFiles{
+				descriptions: make(map[string]bool),
+				dirs:         make(map[string]bool),
+				directions:   make(map[Direction][]string),
+				extensions:   make(map[string]bool),
+			}
+			byVersion[m.Version] = vf
+			versions = append(versions, m.Version)
+		}
+
+		vf.descriptions[m.Description] = true
+		vf.dirs[path.Dir(name)] = true
+		vf.directions[m.Direction] = append(vf.directions[m.Direction], name)
+		vf.extensions[path.Ext(name)] = true
+		vf.files = append(vf.files, name)
+	}
+
+	for _, version := range versions {
+		vf := byVersion[version]
+		sort.Strings(vf.files)
+
+		if len(vf.descriptions) > 1 || len(vf.dirs) > 1 || len(vf.directions[Up]) > 1 || len(vf.directions[Down]) > 1 {
+			report.add(Issue{
+				Kind:     IssueDuplicateVersion,
+				Severity: SeverityError,
+				Version:  version,
+				Message:  "version is used by several migrations: " + strings.Join(vf.files, ", "),
+			})
+
+			continue
+		}
+
+		if len(vf.extensions) > 1 {
+			report.add(Issue{
+				Kind:     IssueMixedExtensions,
+				Severity: SeverityWarning,
+				Version:  version,
+				Message:  "version mixes .cql and .sql files: " + strings.Join(vf.files, ", "),
+			})
+		}
+	}
+}
+
+// validateContent reports a migration file that is empty or cannot be split into statements.
+func validateContent(
+	report *ValidationReport,
+	version uint64,
+	file string,
+	read func(version uint64) (io.ReadCloser, error),
+) error {
+	rc, err := read(version)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		return &SourceError{Version: version, Op: "validate", Err: err}
+	}
+
+	statements, err := SplitStatements(string(content))
+	if err != nil {
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			return err
+		}
+
+		report.add(Issue{
+			Kind:     IssueSyntaxError,
+			Severity: SeverityError,
+			Version:  version,
+			File:     file,
+			Line:     syntaxErr.Line,
+			Message:  syntaxErr.Msg,
+		})
+
+		return nil
+	}
+
+	if len(statements) == 0 {
+		report.add(Issue{
+			Kind:     IssueEmptyMigration,
+			Severity: SeverityWarning,
+			Version:  version,
+			File:     file,
+			Message:  "migration has no statements",
+		})
+	}
+
+	return nil
+}
+
+// validateGaps reports missing versions between consecutive sequential versions.
+// Timestamp-based versions are not expected to be contiguous and are ignored.
+func validateGaps(report *ValidationReport, pairs []*MigrationPair) {
+	var previous uint64
+
+	for _, pair := range pairs {
+		if IsTimestampVersion(pair.Version) {
+			continue
+		}
+
+		if previous > 0 && pair.Version > previous+1 {
+			missing := fmt.Sprintf("version %d is missing", previous+1)
+			if pair.Version-previous > 2 {
+				missing = fmt.Sprintf("versions %d-%d are missing", previous+1, pair.Version-1)
+			}
+
+			report.add(Issue{
+				Kind:     IssueVersionGap,
+				Severity: SeverityWarning,
+				Version:  pair.Version,
+				Message:  fmt.Sprintf("%s before version %d", missing, pair.Version),
+			})
+		}
+
+		previous = pair.Version
+	}
+}
@@ -0,0 +1,278 @@
+package scyllamigrate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	td "github.com/maxatome/go-testdeep/td"
+)
+
+func TestValidate(t *testing.T) {
+	type tcase struct {
+		files    map[string]string
+		expected []Issue
+	}
+
+	tests := map[string]tcase{
+		"valid migrations": {
+			files: map[string]string{
+				"000001_create_users.up.cql":   "CREATE TABLE users (id int PRIMARY KEY);",
+				"000001_create_users.down.cql": "DROP TABLE users;",
+				"000002_add_index.up.cql":      "CREATE INDEX idx ON users (id);",
+				"000002_add_index.down.cql":    "DROP INDEX idx;",
+				"README.md":                    "# Migrations",
+			},
+			expected: []Issue{},
+		},
+		"invalid filenames": {
+			files: map[string]string{
+				"000001_create_users.up.cql":   "CREATE TABLE users (id int PRIMARY KEY);",
+				"000001_create_users.down.cql": "DROP TABLE users;",
+				"000002_add_index.cql":         "CREATE INDEX idx ON users (id);",
+				"add_posts.up.cql":             "CREATE TABLE posts (id int PRIMARY KEY);",
+			},
+			expected: []Issue{
+				{
+					Kind:     IssueInvalidFilename,
+					Severity: SeverityError,
+					File:     "000002_add_index.cql",
+					Message:  "filename does not match {version}_{description}.{up|down}.{cql|sql}",
+				},
+				{
+					Kind:     IssueInvalidFilename,
+					Severity: SeverityError,
+					File:     "add_posts.up.cql",
+					Message:  "filename does not match {version}_{description}.{up|down}.{cql|sql}",
+				},
+			},
+		},
+		"duplicate version": {
+			files: map[string]string{
+				"000001_create_users.up.cql":   "CREATE TABLE users (id int PRIMARY KEY);",
+				"000001_create_users.down.cql": "DROP TABLE users;",
+				"000001_create_posts.up.cql":   "CREATE TABLE posts (id int PRIMARY KEY);",
+			},
+			expected: []Issue{
+				{
+					Kind:     IssueDuplicateVersion,
+					Severity: SeverityError,
+					Version:  1,
+					Message: "version is used by several migrations: " +
+						"000001_create_posts.up.cql, 000001_create_users.down.cql, 000001_create_users.up.cql",
+				},
+			},
+		},
+		"nested directories": {
+			files: map[string]string{
+				"2024/000001_create_users.up.cql":   "CREATE TABLE users (id int PRIMARY KEY);",
+				"2024/000001_create_users.down.cql": "DROP TABLE users;",
+				"2024/000001_create_posts.up.cql":   "CREATE TABLE posts (id int PRIMARY KEY);",
+				"2025/add_index.up.cql":             "CREATE INDEX idx ON users (id);",
+			},
+			expected: []Issue{
+				{
+					Kind:     IssueInvalidFilename,
+					Severity: SeverityError,
+					File:     "2025/add_index.up.cql",
+					Message:  "filename does not match {version}_{description}.{up|down}.{cql|sql}",
+				},
+				{
+					Kind:     IssueDuplicateVersion,
+					Severity: SeverityError,
+					Version:  1,
+					Message: "version is used by several migrations: " +
+						"2024/000001_create_posts.up.cql, 2024/000001_create_users.down.cql, 2024/000001_create_users.up.cql",
+				},
+			},
+		},
+		"conflicting directories": {
+			files: map[string]string{
+				"2024/000001_create_users.up.cql":   "CREATE TABLE users (id int PRIMARY KEY);",
+				"2025/000001_create_users.down.cql": "DROP TABLE users;",
+			},
+			expected: []Issue{
+				{
+					Kind:     IssueDuplicateVersion,
+					Severity: SeverityError,
+					Version:  1,
+					Message: "version is used by several migrations: " +
+						"2024/000001_create_users.up.cql, 2025/000001_create_users.down.cql",
+				},
+			},
+		},
+		"missing up and down": {
+			files: map[string]string{
+				"000001_create_users.up.cql": "CREATE TABLE users (id int PRIMARY KEY);",
+				"000002_add_index.down.cql":  "DROP INDEX idx;",
+			},
+			expected: []Issue{
+				{
+					Kind:     IssueMissingDown,
+					Severity: SeverityWarning,
+					Version:  1,
+					File:     "000001_create_users.up.cql",
+					Message:  "up migration has no down migration",
+				},
+				{
+					Kind:     IssueMissingUp,
+					Severity: SeverityError,
+					Version:  2,
+					File:     "000002_add_index.down.cql",
+					Message:  "down migration has no up migration",
+				},
+			},
+		},
+		"mixed extensions": {
+			files: map[string]string{
+				"000001_create_users.up.cql":   "CREATE TABLE users (id int PRIMARY KEY);",
+				"000001_create_users.down.sql": "DROP TABLE users;",
+			},
+			expected: []Issue{
+				{
+					Kind:     IssueMixedExtensions,
+					Severity: SeverityWarning,
+					Version:  1,
+					Message:  "version mixes .cql and .sql files: 000001_create_users.down.sql, 000001_create_users.up.cql",
+				},
+			},
+		},
+		"empty migration and syntax error": {
+			files: map[string]string{
+				"000001_create_users.up.cql":   "-- nothing yet\n",
+				"000001_create_users.down.cql": "DROP TABLE users;\nINSERT INTO users (id) VALUES ('unterminated);",
+			},
+			expected: []Issue{
+				{
+					Kind:     IssueSyntaxError,
+					Severity: SeverityError,
+					Version:  1,
+					File:     "000001_create_users.down.cql",
+					Line:     2,
+					Message:  "unterminated string literal",
+				},
+				{
+					Kind:     IssueEmptyMigration,
+					Severity: SeverityWarning,
+					Version:  1,
+					File:     "000001_create_users.up.cql",
+					Message:  "migration has no statements",
+				},
+			},
+		},
+		"version gaps": {
+			files: map[string]string{
+				"000001_a.up.cql":           "SELECT now() FROM system.local;",
+				"000001_a.down.cql":         "SELECT now() FROM system.local;",
+				"000003_b.up.cql":           "SELECT now() FROM system.local;",
+				"000003_b.down.cql":         "SELECT now() FROM system.local;",
+				"000007_c.up.cql":           "SELECT now() FROM system.local;",
+				"000007_c.down.cql":         "SELECT now() FROM system.local;",
+				"20240115103000_d.up.cql":   "SELECT now() FROM system.local;",
+				"20240115103000_d.down.cql": "SELECT now() FROM system.local;",
+				"20250115103000_e.up.cql":   "SELECT now() FROM system.local;",
+				"20250115103000_e.down.cql": "SELECT now() FROM system.local;",
+			},
+			expected: []Issue{
+				{
+					Kind:     IssueVersionGap,
+					Severity: SeverityWarning,
+					Version:  3,
+					Message:  "version 2 is missing before version 3",
+				},
+				{
+					Kind:     IssueVersionGap,
+					Severity: SeverityWarning,
+					Version:  7,
+					Message:  "versions 4-6 are missing before version 7",
+				},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			fsys := fstest.MapFS{}
+			for filename, content := range tc.files {
+				fsys[filename] = &fstest.MapFile{Data: []byte(content)}
+			}
+
+			report, err := ValidateFS(fsys)
+			td.CmpNoError(t, err)
+			td.Cmp(t, report.Issues, tc.expected)
+
+			// A source that can be created reports the same issues.
+			source, err := NewFSSource(fsys)
+			if err != nil {
+				td.CmpErrorIs(t, err, ErrDuplicateVersion)
+				return
+			}
+
+			report, err = Validate(source)
+			td.CmpNoError(t, err)
+			td.Cmp(t, report.Issues, tc.expected)
+		})
+	}
+}
+
+func TestValidateFS_missingDirectory(t *testing.T) {
+	_, err := ValidateFS(os.DirFS(filepath.Join(t.TempDir(), "missing")))
+	td.Cmp(t, err, td.ErrorIs(td.Contains("source error for version 0 (files)")))
+}
+
+func TestValidate_noSource(t *testing.T) {
+	_, err := Validate(nil)
+	td.CmpErrorIs(t, err, ErrNoSource)
+}
+
+func TestValidationReport(t *testing.T) {
+	report := &ValidationReport{Issues: []Issue{
+		{Kind: IssueMissingDown, Severity: SeverityWarning},
+		{Kind: IssueMissingUp, Severity: SeverityError},
+		{Kind: IssueVersionGap, Severity: SeverityWarning},
+	}}
+
+	td.Cmp(t, report.HasErrors(), true)
+	td.Cmp(t, report.Errors(), 1)
+	td.Cmp(t, report.Warnings(), 2)
+
+	td.Cmp(t, (&ValidationReport{}).HasErrors(), false)
+}
+
+func TestIssue_String(t *testing.T) {
+	type tcase struct {
+		issue    Issue
+		expected string
+	}
+
+	tests := map[string]tcase{
+		"file and line": {
+			issue: Issue{
+				Kind: IssueSyntaxError, Severity: SeverityError, Version: 1,
+				File: "000001_a.up.cql", Line: 3, Message: "unterminated string literal",
+			},
+			expected: "000001_a.up.cql:3: error: unterminated string literal (syntax_error)",
+		},
+		"file": {
+			issue: Issue{
+				Kind: IssueMissingDown, Severity: SeverityWarning, Version: 1,
+				File: "000001_a.up.cql", Message: "up migration has no down migration",
+			},
+			expected: "000001_a.up.cql: warning: up migration has no down migration (missing_down)",
+		},
+		"version": {
+			issue: Issue{
+				Kind: IssueVersionGap, Severity: SeverityWarning, Version: 3,
+				Message: "version 2 is missing before version 3",
+			},
+			expected: "version 3: warning: version 2 is missing before version 3 (version_gap)",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			td.Cmp(t, tc.issue.String(), tc.expected)
+		})
+	}
+}